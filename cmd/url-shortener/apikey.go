@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/lib/account"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/storage"
+)
+
+const apiKeyUsage = "usage: url-shortener apikey create -name NAME -scopes SCOPE[,SCOPE...] [-user USER] | list | revoke ID"
+
+// runAPIKey implements the "apikey" subcommand.
+func runAPIKey(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(apiKeyUsage)
+	}
+
+	store, err := setupStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if err := checkMigrated(store); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+		name := fs.String("name", "", "key name, shown in logs and listings")
+		scopes := fs.String("scopes", "", "comma separated scopes: "+strings.Join(apikey.Scopes, ", "))
+		user := fs.String("user", "", "user the key acts for, its links only")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *name == "" {
+			return errors.New(apiKeyUsage)
+		}
+
+		return createAPIKey(store, *name, strings.Split(*scopes, ","), *user)
+	case "list":
+		return listAPIKeys(store)
+	case "revoke":
+		if len(args) != 2 {
+			return errors.New(apiKeyUsage)
+		}
+
+		id, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid id %q", args[1])
+		}
+
+		if err := store.RevokeAPIKey(id, time.Now().UTC()); err != nil {
+			return err
+		}
+		fmt.Printf("revoked key %d\n", id)
+	default:
+		return errors.New(apiKeyUsage)
+	}
+
+	return nil
+}
+
+func createAPIKey(store storage.Store, name string, scopes []string, userName string) error {
+	if err := apikey.ValidateScopes(scopes); err != nil {
+		return err
+	}
+
+	var userID int64
+	if userName != "" {
+		user, err := store.GetUserByName(userName)
+		if err != nil {
+			return err
+		}
+		if err := account.CheckKeyScopes(user.Role, scopes); err != nil {
+			return err
+		}
+		userID = user.ID
+	}
+
+	secret, prefix, hash, err := apikey.Generate()
+	if err != nil {
+		return err
+	}
+
+	id, err := store.SaveAPIKey(storage.APIKey{
+		Name:      name,
+		Prefix:    prefix,
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC(),
+		UserID:    userID,
+	}, hash)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("created key %d, it is shown only once:\n%s\n", id, secret)
+
+	return nil
+}
+
+func listAPIKeys(store storage.Store) error {
+	keys, err := store.ListAPIKeys()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tPREFIX\tSCOPES\tUSER ID\tCREATED AT\tLAST USED AT\tREVOKED AT")
+	for _, k := range keys {
+		userID := "-"
+		if k.UserID != 0 {
+			userID = strconv.FormatInt(k.UserID, 10)
+		}
+
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			k.ID, k.Name, k.Prefix, strings.Join(k.Scopes, ","), userID,
+			k.CreatedAt.Format(time.RFC3339), formatTime(k.LastUsedAt), formatTime(k.RevokedAt))
+	}
+
+	return tw.Flush()
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+
+	return t.Format(time.RFC3339)
+}
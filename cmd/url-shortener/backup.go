@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/backup"
+	"url-shortener/internal/config"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/migrate"
+	"url-shortener/internal/storage/sqlite"
+)
+
+const (
+	backupUsage  = "usage: url-shortener backup"
+	restoreUsage = "usage: url-shortener restore SNAPSHOT (stop the server first)"
+)
+
+// backupable is implemented by storage backends that can snapshot themselves.
+type backupable interface {
+	Backup(ctx context.Context, path string) error
+}
+
+// setupBackups returns the backup manager for the configured directory, nil
+// if backups are disabled or the storage cannot be snapshotted.
+func setupBackups(log *slog.Logger, cfg *config.Config, store storage.Store) (*backup.Manager, error) {
+	if cfg.Backup.Dir == "" {
+		return nil, nil
+	}
+
+	db, ok := store.(backupable)
+	if !ok {
+		return nil, fmt.Errorf("storage driver %q does not support backups", cfg.Storage.Driver)
+	}
+
+	return backup.New(log, db, cfg.Backup.Dir, cfg.Backup.Interval, cfg.Backup.Keep)
+}
+
+// runBackup implements the "backup" subcommand, it takes one snapshot
+// into the configured directory.
+func runBackup(log *slog.Logger, cfg *config.Config, args []string) error {
+	if len(args) != 0 {
+		return errors.New(backupUsage)
+	}
+	if cfg.Backup.Dir == "" {
+		return errors.New("backup.dir is not set")
+	}
+
+	store, err := setupStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	// Scheduled snapshots are left to the server.
+	cfg.Backup.Interval = 0
+
+	m, err := setupBackups(log, cfg, store)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	s, err := m.Snapshot(context.Background())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("wrote %s (%d bytes)\n", s.Path, s.Size)
+
+	return nil
+}
+
+// runRestore implements the "restore" subcommand. The snapshot is copied
+// next to the database, checked and migrated there, and only then swapped
+// in. The replaced database is kept under a .before-restore name.
+func runRestore(cfg *config.Config, args []string) error {
+	if len(args) != 1 {
+		return errors.New(restoreUsage)
+	}
+	if cfg.Storage.Driver != storageSQLite {
+		return fmt.Errorf("storage driver %q does not support restore", cfg.Storage.Driver)
+	}
+
+	snapshot := args[0]
+	if _, err := os.Stat(snapshot); err != nil {
+		return err
+	}
+
+	// A hot journal belongs to the current database, it would be rolled
+	// back into the restored one.
+	if _, err := os.Stat(cfg.StoragePath + "-journal"); err == nil {
+		return fmt.Errorf("%s-journal exists, stop the server before restoring", cfg.StoragePath)
+	}
+
+	staged := cfg.StoragePath + ".restore"
+	if err := copyFile(snapshot, staged); err != nil {
+		return err
+	}
+
+	applied, err := prepareRestore(staged, cfg.Alias.MaxLength)
+	if err != nil {
+		_ = os.Remove(staged)
+		return fmt.Errorf("snapshot %s: %w", snapshot, err)
+	}
+	for _, migration := range applied {
+		fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+	}
+
+	if _, err := os.Stat(cfg.StoragePath); err == nil {
+		previous := cfg.StoragePath + ".before-restore-" + time.Now().UTC().Format("20060102T150405Z")
+		if err := os.Rename(cfg.StoragePath, previous); err != nil {
+			_ = os.Remove(staged)
+			return err
+		}
+		fmt.Printf("moved current database to %s\n", previous)
+	}
+
+	if err := os.Rename(staged, cfg.StoragePath); err != nil {
+		return err
+	}
+
+	fmt.Printf("restored %s from %s\n", cfg.StoragePath, snapshot)
+
+	return nil
+}
+
+// prepareRestore checks the staged snapshot and brings its schema up to
+// date, so the server starts on it right away.
+func prepareRestore(path string, aliasMaxLength int) ([]migrate.Migration, error) {
+	s, err := sqlite.New(path, aliasMaxLength, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	if err := s.Verify(); err != nil {
+		return nil, err
+	}
+
+	m, err := s.Migrator()
+	if err != nil {
+		return nil, err
+	}
+
+	return m.Up()
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		_ = out.Close()
+		return err
+	}
+
+	return out.Close()
+}
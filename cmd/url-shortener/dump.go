@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/lib/linkdump"
+	"url-shortener/internal/storage"
+)
+
+const (
+	exportUsage = "usage: url-shortener export [-format jsonl|csv] [-o FILE]"
+	importUsage = "usage: url-shortener import [-format jsonl|csv] [-on-conflict skip|overwrite|fail] [-dry-run] [FILE]"
+)
+
+// runExport implements the "export" subcommand. The dump is written to
+// stdout unless -o is given, progress goes to stderr.
+func runExport(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	formatName := fs.String("format", "", "dump format: jsonl or csv, by default taken from the file extension")
+	output := fs.String("o", "", "output file, stdout if empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errors.New(exportUsage)
+	}
+
+	format, err := dumpFormat(*formatName, *output)
+	if err != nil {
+		return err
+	}
+
+	store, err := setupStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if err := checkMigrated(store); err != nil {
+		return err
+	}
+
+	if *output == "" {
+		return exportLinks(os.Stdout, format, store)
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+
+	if err := exportLinks(f, format, store); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func exportLinks(w io.Writer, format linkdump.Format, store storage.Store) error {
+	n, err := linkdump.Export(w, format, store, func(done int) {
+		fmt.Fprintf(os.Stderr, "exported %d links\n", done)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "export finished: %d links\n", n)
+
+	return nil
+}
+
+// runImport implements the "import" subcommand. The dump is read from the
+// given file or from stdin.
+func runImport(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	formatName := fs.String("format", "", "dump format: jsonl or csv, by default taken from the file extension")
+	onConflict := fs.String("on-conflict", string(storage.ConflictFail), "what to do with taken aliases: skip, overwrite or fail")
+	dryRun := fs.Bool("dry-run", false, "report what would be imported without saving anything")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return errors.New(importUsage)
+	}
+
+	input := fs.Arg(0)
+
+	format, err := dumpFormat(*formatName, input)
+	if err != nil {
+		return err
+	}
+
+	policy, err := linkdump.ParseConflictPolicy(*onConflict)
+	if err != nil {
+		return err
+	}
+
+	store, err := setupStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if err := checkMigrated(store); err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	stats, err := linkdump.Import(r, store, linkdump.Options{
+		Format:     format,
+		OnConflict: policy,
+		DryRun:     *dryRun,
+		Progress: func(done int) {
+			fmt.Fprintf(os.Stderr, "read %d links\n", done)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	prefix := "imported"
+	if *dryRun {
+		prefix = "dry run, would have imported"
+	}
+	fmt.Printf("%s: %d created, %d overwritten, %d skipped\n", prefix, stats.Created, stats.Overwritten, stats.Skipped)
+
+	return nil
+}
+
+// dumpFormat returns the format named name, or the one matching the
+// extension of path if name is empty. JSONL is the default.
+func dumpFormat(name, path string) (linkdump.Format, error) {
+	if name == "" {
+		name = strings.TrimPrefix(filepath.Ext(path), ".")
+		if name != string(linkdump.FormatCSV) {
+			name = string(linkdump.FormatJSONL)
+		}
+	}
+
+	return linkdump.ParseFormat(name)
+}
@@ -2,9 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 
@@ -16,13 +20,44 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"golang.org/x/exp/slog"
 
+	"url-shortener/internal/analytics"
+	"url-shortener/internal/cache"
 	"url-shortener/internal/config"
+	keycreate "url-shortener/internal/http-server/handlers/apikey/create"
+	keylist "url-shortener/internal/http-server/handlers/apikey/list"
+	"url-shortener/internal/http-server/handlers/apikey/revoke"
+	backupcreate "url-shortener/internal/http-server/handlers/backup/create"
+	"url-shortener/internal/http-server/handlers/dump/export"
+	"url-shortener/internal/http-server/handlers/dump/load"
+	"url-shortener/internal/http-server/handlers/health"
+	"url-shortener/internal/http-server/handlers/preview"
+	"url-shortener/internal/http-server/handlers/qr"
 	"url-shortener/internal/http-server/handlers/redirect"
+	"url-shortener/internal/http-server/handlers/url/batch"
+	"url-shortener/internal/http-server/handlers/url/get"
+	"url-shortener/internal/http-server/handlers/url/list"
+	"url-shortener/internal/http-server/handlers/url/remove"
 	"url-shortener/internal/http-server/handlers/url/save"
+	"url-shortener/internal/http-server/handlers/url/stats"
+	"url-shortener/internal/http-server/handlers/url/update"
+	usercreate "url-shortener/internal/http-server/handlers/user/create"
+	userlist "url-shortener/internal/http-server/handlers/user/list"
+	"url-shortener/internal/http-server/middleware/auth"
 	mwLogger "url-shortener/internal/http-server/middleware/logger"
+	"url-shortener/internal/http-server/middleware/ratelimit"
+	"url-shortener/internal/lib/apikey"
+	customalias "url-shortener/internal/lib/custom_alias"
+	generatingalias "url-shortener/internal/lib/generating_alias"
+	"url-shortener/internal/lib/linkpass"
 	"url-shortener/internal/lib/logger/handlers/slogpretty"
 	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/shorturl"
+	"url-shortener/internal/metrics"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+	"url-shortener/internal/storage/postgres"
 	"url-shortener/internal/storage/sqlite"
+	"url-shortener/internal/sweeper"
 )
 
 const (
@@ -31,6 +66,12 @@ const (
 	envProd  = "prod"
 )
 
+const (
+	storageSQLite   = "sqlite"
+	storagePostgres = "postgres"
+	storageMemory   = "memory"
+)
+
 // @title URL Shortener API
 // @version 1.0
 // @description API для сокращения URL.
@@ -38,47 +79,161 @@ const (
 // @contact.name API Support
 // @host localhost:8082
 // @BasePath /
+// @securityDefinitions.basic BasicAuth
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
 func main() {
 	cfg := config.MustLoad()
 	log := setupLogger(cfg.Env)
 
+	if len(os.Args) > 1 {
+		var err error
+
+		switch os.Args[1] {
+		case "migrate":
+			err = runMigrate(cfg, os.Args[2:])
+		case "apikey":
+			err = runAPIKey(cfg, os.Args[2:])
+		case "user":
+			err = runUser(cfg, os.Args[2:])
+		case "export":
+			err = runExport(cfg, os.Args[2:])
+		case "import":
+			err = runImport(cfg, os.Args[2:])
+		case "backup":
+			err = runBackup(log, cfg, os.Args[2:])
+		case "restore":
+			err = runRestore(cfg, os.Args[2:])
+		default:
+			err = fmt.Errorf("unknown command %q", os.Args[1])
+		}
+
+		if err != nil {
+			log.Error("command failed", slog.String("command", os.Args[1]), sl.Err(err))
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	if !redirect.IsValidCode(cfg.Redirect.DefaultCode) {
+		log.Error("invalid redirect default code", slog.Int("code", cfg.Redirect.DefaultCode))
+		os.Exit(1)
+	}
+
+	shortURLs, err := shorturl.New(cfg.HTTPServer.PublicURL)
+	if err != nil {
+		log.Error("invalid public url", slog.String("public_url", cfg.HTTPServer.PublicURL), sl.Err(err))
+		os.Exit(1)
+	}
+
+	passwordCookies, err := linkpass.New(cfg.Redirect.Password.CookieSecret, cfg.Redirect.Password.CookieTTL)
+	if err != nil {
+		log.Error("invalid link password settings", sl.Err(err))
+		os.Exit(1)
+	}
+
 	log.Info("starting url-shortener", slog.String("env", cfg.Env), slog.String("version", "123"))
 	log.Debug("debug messages are enabled")
 
-	storage, err := sqlite.New(cfg.StoragePath)
+	storage, err := setupStorage(cfg)
+	if err != nil {
+		log.Error("failed to init storage", sl.Err(err))
+		os.Exit(1)
+	}
+	defer storage.Close()
+
+	// Set up before the storage is wrapped below, migrationsCheck needs the
+	// bare backend to reach the migrator.
+	migrated, err := migrationsCheck(storage)
 	if err != nil {
 		log.Error("failed to init storage", sl.Err(err))
 		os.Exit(1)
 	}
 
-	err = sqlite.SaveGeneratingAlias(cfg.StoragePath)
+	aliasCapacity := generatingalias.Capacity(cfg.Alias.MaxLength)
+
+	readiness := health.NewReadiness(
+		health.Check{Name: "storage", Func: storage.Ping},
+		health.Check{Name: "migrations", Func: migrated},
+		health.Check{Name: "alias_space", Func: aliasSpaceLeft(storage, aliasCapacity)},
+	)
+
+	backups, err := setupBackups(log, cfg, storage)
 	if err != nil {
-		log.Error("failed to init storage { alias_value }", sl.Err(err2))
+		log.Error("failed to init backups", sl.Err(err))
 		os.Exit(1)
 	}
+	if backups != nil {
+		defer backups.Close()
+	}
 
-	// deletedURL, err := sqlite.DeleteURL()
-	// log.Info("{ deletedURL } was successfully deleted")
-	// if err != nil {'
-	// 	log.Error("failed while deleting URL")
-	// }
+	appMetrics := metrics.New(storage, aliasCapacity)
+	storage = metrics.InstrumentStore(storage, appMetrics)
 
-	// updatedURL, err := sqlite.UpdateURL(, )
-	// log.Info("{ updatedURL } was successfully updated")
-	// if err != nil {
-	// 	log.Error("failed while updating URL")
-	// }
+	var urlGetter redirect.URLGetter = storage
+	if cfg.Cache.Size > 0 {
+		linkCache := cache.New(storage, cfg.Cache.Size, cfg.Cache.TTL, cfg.Cache.NegativeTTL)
+		storage = cache.WithInvalidation(storage, linkCache)
+		urlGetter = linkCache
+		appMetrics.RegisterCache(linkCache)
+	}
+
+	// Deferred after storage.Close, so buffered clicks are flushed before the storage is closed.
+	clickRecorder := analytics.New(
+		log,
+		storage,
+		cfg.Analytics.IPSalt,
+		cfg.Analytics.BufferSize,
+		cfg.Analytics.FlushInterval,
+	)
+	defer clickRecorder.Close()
+
+	if cfg.Expiration.SweepInterval > 0 {
+		expiredSweeper := sweeper.New(log, storage, cfg.Expiration.SweepInterval, cfg.Expiration.Retention)
+		defer expiredSweeper.Close()
+	}
+
+	aliasValidator := customalias.New(
+		cfg.Alias.Custom.MinLength,
+		cfg.Alias.Custom.MaxLength,
+		cfg.Alias.Custom.Reserved,
+	)
 
 	router := chi.NewRouter()
 	router.Use(middleware.RequestID)
+	if cfg.HTTPServer.TrustProxy {
+		// Runs first, so logs, rate limits and click analytics see the client IP.
+		router.Use(middleware.RealIP)
+	}
 	router.Use(middleware.Logger)
-	router.Use(mwLogger.New(log))
+	router.Use(mwLogger.New(log, appMetrics))
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.URLFormat)
 
 	// Добавляем маршрут Swagger
 	router.Get("/swagger/*", httpSwagger.WrapHandler)
 
+	router.Get("/healthz", health.NewLive())
+	router.Get("/readyz", health.NewReady(log, readiness))
+
+	var adminSrv *http.Server
+	if cfg.HTTPServer.AdminAddress != "" {
+		adminRouter := chi.NewRouter()
+		adminRouter.Handle("/metrics", appMetrics.Handler())
+
+		adminSrv = &http.Server{
+			Addr:         cfg.HTTPServer.AdminAddress,
+			Handler:      adminRouter,
+			ReadTimeout:  cfg.HTTPServer.Timeout,
+			WriteTimeout: cfg.HTTPServer.Timeout,
+			IdleTimeout:  cfg.HTTPServer.IdleTimeout,
+		}
+	} else {
+		router.Handle("/metrics", appMetrics.Handler())
+	}
+
 	srv := &http.Server{
 		Addr:         cfg.Address,
 		Handler:      router,
@@ -87,39 +242,207 @@ func main() {
 		IdleTimeout:  cfg.HTTPServer.IdleTimeout,
 	}
 
+	authenticate := auth.New(log, storage, map[string]string{
+		cfg.HTTPServer.User: cfg.HTTPServer.Password,
+	})
+
+	// Limits are kept per instance, a shared Store would make them global.
+	rateLimits := ratelimit.NewMemoryStore()
+	saveLimiter := ratelimit.NewLimiter(log, rateLimits, "save", ratelimit.Limit(cfg.RateLimit.Save), ratelimit.ClientKey)
+	redirectLimiter := ratelimit.New(log, rateLimits, "redirect", ratelimit.Limit(cfg.RateLimit.Redirect), ratelimit.ClientKey)
+
 	router.Route("/url", func(r chi.Router) {
-		r.Use(middleware.BasicAuth("url-shortener", map[string]string{
-			cfg.HTTPServer.User: cfg.HTTPServer.Password,
-		}))
+		r.Use(authenticate)
+
+		// Limited after authentication, so clients are told apart by credential.
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequireScope(apikey.ScopeCreate))
+
+			aliasAllowed := customAliasAllowed(cfg.Alias.Custom.Users)
+			r.With(saveLimiter.Middleware).Post("/", save.New(log, storage, aliasValidator, aliasAllowed, shortURLs.URL))
+			// A batch is charged one save per entry once it is decoded.
+			r.Post("/batch", batch.New(log, storage, aliasValidator, aliasAllowed, saveLimiter.Charge, cfg.Batch.MaxSize, cfg.Batch.MaxBodySize))
+		})
 
-		r.Post("/", save.New(log, storage, srv))
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequireScope(apikey.ScopeRead))
+
+			r.Get("/", list.New(log, storage))
+			r.Get("/{alias}", get.New(log, storage))
+			r.Get("/{alias}/stats", stats.New(log, storage))
+		})
+
+		r.With(auth.RequireScope(apikey.ScopeUpdate)).Patch("/{alias}", update.New(log, storage))
+		r.With(auth.RequireScope(apikey.ScopeDelete)).Delete("/{alias}", remove.New(log, storage))
+	})
+
+	router.Route("/admin/keys", func(r chi.Router) {
+		r.Use(authenticate)
+		r.Use(auth.RequireScope(apikey.ScopeAdmin))
+
+		r.Post("/", keycreate.New(log, storage, storage))
+		r.Get("/", keylist.New(log, storage))
+		r.Delete("/{id}", revoke.New(log, storage))
 	})
 
-	router.Get("/{alias}", redirect.New(log, storage))
+	router.Route("/admin/users", func(r chi.Router) {
+		r.Use(authenticate)
+		r.Use(auth.RequireScope(apikey.ScopeAdmin))
+
+		r.Post("/", usercreate.New(log, storage))
+		r.Get("/", userlist.New(log, storage))
+	})
+
+	router.Route("/admin", func(r chi.Router) {
+		r.Use(authenticate)
+		r.Use(auth.RequireScope(apikey.ScopeAdmin))
+
+		r.Get("/export", export.New(log, storage))
+		r.Post("/import", load.New(log, storage))
+
+		if backups != nil {
+			r.Post("/backups", backupcreate.New(log, backups))
+		}
+	})
+
+	router.With(redirectLimiter).Get("/{alias}/qr", qr.New(log, urlGetter, shortURLs.URL))
+
+	previewHandler := preview.New(log, urlGetter, storage, shortURLs.URL)
+	router.With(redirectLimiter).Get("/{alias}+", previewHandler)
+	router.With(redirectLimiter).Get("/{alias}/preview", previewHandler)
+
+	redirectHandler := redirect.New(
+		log,
+		urlGetter,
+		storage,
+		appMetrics.CountRedirects(clickRecorder),
+		cfg.Redirect.DefaultCode,
+		cfg.Redirect.PermanentMaxAge,
+		redirect.Passwords{
+			Signer:   passwordCookies,
+			Attempts: rateLimits,
+			Limit: ratelimit.Limit{
+				Requests: cfg.Redirect.Password.Attempts,
+				Period:   cfg.Redirect.Password.AttemptsPeriod,
+			},
+		},
+	)
+	router.With(redirectLimiter).Get("/{alias}", redirectHandler)
+	router.With(redirectLimiter).Post("/{alias}", redirectHandler)
 
 	log.Info("starting server", slog.String("address", cfg.Address))
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
-	go func() {
-		if err := srv.ListenAndServe(); err != nil {
-			log.Error("failed to start server")
+	serverErr := make(chan error, 2)
+
+	if err := serve(srv, serverErr); err != nil {
+		log.Error("failed to start server", sl.Err(err))
+		os.Exit(1)
+	}
+
+	if adminSrv != nil {
+		log.Info("starting admin server", slog.String("address", adminSrv.Addr))
+
+		if err := serve(adminSrv, serverErr); err != nil {
+			log.Error("failed to start admin server", sl.Err(err))
+			os.Exit(1)
 		}
-	}()
+	}
 
 	log.Info("server started")
-	<-done
+
+	select {
+	case <-done:
+	case err := <-serverErr:
+		log.Error("server failed", sl.Err(err))
+	}
+
 	log.Info("stopping server")
 
+	// Fail readiness first, so load balancers stop sending traffic before
+	// the listener closes.
+	readiness.Drain()
+	if cfg.HTTPServer.ShutdownDelay > 0 {
+		log.Info("draining traffic", slog.Duration("delay", cfg.HTTPServer.ShutdownDelay))
+		time.Sleep(cfg.HTTPServer.ShutdownDelay)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	stopped := true
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Error("failed to stop server", sl.Err(err))
-		return
+		stopped = false
 	}
 
-	log.Info("server stopped")
+	if adminSrv != nil {
+		if err := adminSrv.Shutdown(ctx); err != nil {
+			log.Error("failed to stop admin server", sl.Err(err))
+			stopped = false
+		}
+	}
+
+	if stopped {
+		log.Info("server stopped")
+	}
+}
+
+// serve binds the address of srv and serves in the background, so a failing
+// listener is reported before the server is announced as started. Errors
+// while serving are sent to errs.
+func serve(srv *http.Server, errs chan<- error) error {
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+			errs <- err
+		}
+	}()
+
+	return nil
+}
+
+// aliasSpaceLeft fails once every alias that can be generated is used up.
+func aliasSpaceLeft(store storage.Store, capacity int64) func() error {
+	return func() error {
+		used, err := store.AliasCounter()
+		if err != nil {
+			return err
+		}
+		if used >= capacity {
+			return storage.ErrAliasSpaceExhausted
+		}
+
+		return nil
+	}
+}
+
+func setupStorage(cfg *config.Config) (storage.Store, error) {
+	switch cfg.Storage.Driver {
+	case storageSQLite:
+		return sqlite.New(cfg.StoragePath, cfg.Alias.MaxLength, cfg.Alias.Custom.Reserved)
+	case storagePostgres:
+		return postgres.New(cfg.Storage.DSN, cfg.Alias.MaxLength, cfg.Alias.Custom.Reserved)
+	case storageMemory:
+		return memory.New(cfg.Alias.MaxLength, cfg.Alias.Custom.Reserved), nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Storage.Driver)
+	}
+}
+
+// customAliasAllowed lets the listed basic auth users and API keys choose
+// their own aliases.
+func customAliasAllowed(users []string) save.AliasPermission {
+	return func(r *http.Request) bool {
+		p, ok := auth.FromContext(r.Context())
+
+		return ok && slices.Contains(users, p.Name)
+	}
 }
 
 func setupLogger(env string) *slog.Logger {
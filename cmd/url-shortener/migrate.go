@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/migrate"
+)
+
+// migratable is implemented by storage backends with a versioned schema.
+type migratable interface {
+	Migrator() (*migrate.Migrator, error)
+}
+
+const migrateUsage = "usage: url-shortener migrate up|down|status"
+
+// runMigrate implements the "migrate" subcommand.
+func runMigrate(cfg *config.Config, args []string) error {
+	if len(args) != 1 {
+		return errors.New(migrateUsage)
+	}
+
+	store, err := setupStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	s, ok := store.(migratable)
+	if !ok {
+		return fmt.Errorf("storage driver %q has no schema to migrate", cfg.Storage.Driver)
+	}
+
+	m, err := s.Migrator()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := m.Up()
+		for _, migration := range applied {
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("schema is up to date")
+		}
+	case "down":
+		migration, err := m.Down()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("reverted %04d_%s\n", migration.Version, migration.Name)
+	case "status":
+		statuses, err := m.Status()
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+		for _, st := range statuses {
+			appliedAt := "pending"
+			if st.Applied {
+				appliedAt = st.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%04d\t%s\t%s\n", st.Version, st.Name, appliedAt)
+		}
+		return tw.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	return nil
+}
+
+// migrationsCheck refuses a database whose schema is behind the binary and
+// returns the readiness check of the schema. The check reuses the migrator
+// and only reads schema_version, so probes run no DDL.
+func migrationsCheck(store storage.Store) (func() error, error) {
+	s, ok := store.(migratable)
+	if !ok {
+		return func() error { return nil }, nil
+	}
+
+	m, err := s.Migrator()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.Check(); err != nil {
+		return nil, fmt.Errorf("%w, run `url-shortener migrate up`", err)
+	}
+
+	return m.CheckLatest, nil
+}
+
+// checkMigrated refuses to work on a database whose schema is behind the binary.
+func checkMigrated(store storage.Store) error {
+	_, err := migrationsCheck(store)
+
+	return err
+}
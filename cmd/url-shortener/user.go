@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/lib/account"
+	"url-shortener/internal/storage"
+)
+
+const userUsage = "usage: url-shortener user create -name NAME [-role user|admin] < password | list"
+
+// runUser implements the "user" subcommand. The password of a new user is
+// read from the first line of stdin, so it does not end up in shell history.
+func runUser(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(userUsage)
+	}
+
+	store, err := setupStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if err := checkMigrated(store); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("user create", flag.ContinueOnError)
+		name := fs.String("name", "", "login name")
+		role := fs.String("role", account.RoleUser, "role: "+strings.Join(account.Roles, ", "))
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *name == "" {
+			return errors.New(userUsage)
+		}
+		if err := account.ValidateRole(*role); err != nil {
+			return err
+		}
+
+		password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && password == "" {
+			return fmt.Errorf("read password from stdin: %w", err)
+		}
+
+		return createUser(store, *name, *role, strings.TrimRight(password, "\r\n"))
+	case "list":
+		return listUsers(store)
+	default:
+		return errors.New(userUsage)
+	}
+}
+
+func createUser(store storage.Store, name, role, password string) error {
+	hash, err := account.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	id, err := store.SaveUser(storage.User{
+		Name:         name,
+		Role:         role,
+		PasswordHash: hash,
+		CreatedAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("created user %d\n", id)
+
+	return nil
+}
+
+func listUsers(store storage.Store) error {
+	users, err := store.ListUsers()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tROLE\tCREATED AT")
+	for _, u := range users {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", u.ID, u.Name, u.Role, u.CreatedAt.Format(time.RFC3339))
+	}
+
+	return tw.Flush()
+}
@@ -18,24 +18,205 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/": {
+        "/admin/backups": {
             "post": {
-                "description": "Принимает длинный URL и создает для него короткую версию",
+                "security": [
+                    {
+                        "BasicAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Записывает снимок базы данных в каталог резервных копий и удаляет лишние старые снимки",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Take a backup",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_backup_create.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_backup_create.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/export": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Выгружает все ссылки и счетчик алиасов в JSONL или CSV",
+                "produces": [
+                    "application/x-ndjson",
+                    "text/csv"
+                ],
+                "summary": "Export links",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "jsonl (по умолчанию) или csv",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Dump",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/import": {
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Загружает ссылки из выгрузки /admin/export. Формат берется из параметра\nformat или из Content-Type (text/csv), по умолчанию JSONL.",
+                "consumes": [
+                    "application/x-ndjson",
+                    "text/csv"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Import links",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "jsonl или csv",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "skip, overwrite или fail (по умолчанию)",
+                        "name": "on_conflict",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Только посчитать, ничего не сохраняя",
+                        "name": "dry_run",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_dump_load.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_dump_load.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_dump_load.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_dump_load.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/keys": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает все API-ключи, включая отозванные, без самих секретов",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List API keys",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_apikey_list.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_apikey_list.Response"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Выпускает новый API-ключ, сам ключ возвращается только один раз",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Создать сокращенный URL",
+                "summary": "Create API key",
                 "parameters": [
                     {
-                        "description": "URL для сокращения",
+                        "description": "Key name and scopes",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/save.Request"
+                            "$ref": "#/definitions/internal_http-server_handlers_apikey_create.Request"
                         }
                     }
                 ],
@@ -43,85 +224,1720 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/redirect.Response"
+                            "$ref": "#/definitions/internal_http-server_handlers_apikey_create.Response"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/redirect.Response"
+                            "$ref": "#/definitions/internal_http-server_handlers_apikey_create.Response"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/redirect.Response"
+                            "$ref": "#/definitions/internal_http-server_handlers_apikey_create.Response"
                         }
                     }
                 }
             }
         },
-        "/{alias}": {
-            "get": {
-                "description": "Перенаправляет пользователя на оригинальный URL по его короткому идентификатору",
-                "summary": "Redirect to original URL",
+        "/admin/keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отзывает API-ключ, после этого он больше не принимается",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Revoke API key",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Short URL alias",
-                        "name": "alias",
+                        "type": "integer",
+                        "description": "API key id",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successfully redirected"
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
                     },
-                    "302": {
-                        "description": "Moved Temporarily"
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/redirect.Response"
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/redirect.Response"
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "redirect.Response": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "type": "string"
-                },
-                "status": {
-                    "type": "string"
+        },
+        "/admin/users": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает всех пользователей без хешей паролей",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List users",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_user_list.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_user_list.Response"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Создает пользователя, роль по умолчанию - user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Create user",
+                "parameters": [
+                    {
+                        "description": "Name, password and role",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_user_create.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_user_create.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_user_create.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_user_create.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_user_create.Response"
+                        }
+                    }
                 }
             }
         },
-        "save.Request": {
-            "type": "object",
-            "required": [
-                "url"
-            ],
-            "properties": {
-                "alias": {
-                    "type": "string"
-                },
-                "url": {
-                    "type": "string"
+        "/healthz": {
+            "get": {
+                "description": "Отвечает 200, пока процесс жив",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_health.Response"
+                        }
+                    }
                 }
             }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Проверяет хранилище, миграции и свободные алиасы",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_health.Response"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_health.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/url": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает страницу ссылок вызывающего пользователя в порядке создания, администратору - все ссылки",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List short URLs",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size (1-100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of links to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_list.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_list.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_list.Response"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Принимает длинный URL и создает для него короткую версию",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Создать сокращенный URL",
+                "parameters": [
+                    {
+                        "description": "URL для сокращения",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_save.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_save.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_save.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_save.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_save.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_save.Response"
+                        }
+                    },
+                    "507": {
+                        "description": "Insufficient Storage",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_save.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/url/batch": {
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Принимает JSON массив или NDJSON (application/x-ndjson) с запросами\nкак у POST /url. Массив сохраняется в одной транзакции. NDJSON\nсохраняется частями по 100 записей, результаты каждой части\nотправляются, пока читается остальной запрос. Если ошибка\nслучилась после начала ответа, она приходит последней строкой,\nсохраненные до нее части остаются. Результаты и ошибки\nвозвращаются для каждой записи в порядке запроса.",
+                "consumes": [
+                    "application/json",
+                    "application/x-ndjson"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/x-ndjson"
+                ],
+                "summary": "Создать несколько сокращенных URL",
+                "parameters": [
+                    {
+                        "description": "URL для сокращения",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/url-shortener_internal_http-server_handlers_url_save.Request"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_batch.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_batch.Response"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_batch.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded, every entry costs one request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_batch.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_batch.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/url/{alias}": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает сохраненную ссылку по ее короткому идентификатору",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get short URL metadata",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL alias",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_get.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_get.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_get.Response"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет короткую ссылку",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL alias",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Меняет URL, адреса для платформ и варианты, на которые перенаправляет короткая ссылка\nЗакрепить варианты (sticky_variants) можно только у ссылки с вариантами",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Change short URL destination",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL alias",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Новый URL",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_update.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_update.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_update.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_update.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_update.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/url/{alias}/stats": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает общее число переходов, уникальных посетителей и переходы по дням",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get short URL click statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL alias",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "maximum": 365,
+                        "type": "integer",
+                        "default": 30,
+                        "description": "Number of days in the daily breakdown",
+                        "name": "days",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_stats.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_stats.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_stats.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_stats.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/{alias}": {
+            "get": {
+                "description": "Перенаправляет пользователя на оригинальный URL по его короткому идентификатору.\nДля ссылок с паролем показывает форму ввода пароля.",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "text/html",
+                    "application/json"
+                ],
+                "summary": "Redirect to original URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL alias",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password of a protected link, only posted",
+                        "name": "password",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Password form of a protected link"
+                    },
+                    "301": {
+                        "description": "Moved Permanently"
+                    },
+                    "302": {
+                        "description": "Moved Temporarily"
+                    },
+                    "303": {
+                        "description": "Password accepted"
+                    },
+                    "307": {
+                        "description": "Temporary Redirect"
+                    },
+                    "308": {
+                        "description": "Permanent Redirect"
+                    },
+                    "401": {
+                        "description": "Wrong password, the form is shown again"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_redirect.Response"
+                        }
+                    },
+                    "405": {
+                        "description": "Link is not password protected",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_redirect.Response"
+                        }
+                    },
+                    "410": {
+                        "description": "Link expired or used up",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_redirect.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_redirect.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_redirect.Response"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Перенаправляет пользователя на оригинальный URL по его короткому идентификатору.\nДля ссылок с паролем показывает форму ввода пароля.",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "text/html",
+                    "application/json"
+                ],
+                "summary": "Redirect to original URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL alias",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password of a protected link, only posted",
+                        "name": "password",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Password form of a protected link"
+                    },
+                    "301": {
+                        "description": "Moved Permanently"
+                    },
+                    "302": {
+                        "description": "Moved Temporarily"
+                    },
+                    "303": {
+                        "description": "Password accepted"
+                    },
+                    "307": {
+                        "description": "Temporary Redirect"
+                    },
+                    "308": {
+                        "description": "Permanent Redirect"
+                    },
+                    "401": {
+                        "description": "Wrong password, the form is shown again"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_redirect.Response"
+                        }
+                    },
+                    "405": {
+                        "description": "Link is not password protected",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_redirect.Response"
+                        }
+                    },
+                    "410": {
+                        "description": "Link expired or used up",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_redirect.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_redirect.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_redirect.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/{alias}+": {
+            "get": {
+                "description": "Показывает адрес назначения, домен, дату создания и число переходов вместо перенаправления",
+                "produces": [
+                    "text/html",
+                    "application/json"
+                ],
+                "summary": "Preview a short link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL alias",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_preview.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_preview.Response"
+                        }
+                    },
+                    "410": {
+                        "description": "Link expired or used up",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_preview.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_preview.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_preview.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/{alias}/preview": {
+            "get": {
+                "description": "Показывает адрес назначения, домен, дату создания и число переходов вместо перенаправления",
+                "produces": [
+                    "text/html",
+                    "application/json"
+                ],
+                "summary": "Preview a short link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL alias",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_preview.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_preview.Response"
+                        }
+                    },
+                    "410": {
+                        "description": "Link expired or used up",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_preview.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_preview.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_preview.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/{alias}/qr": {
+            "get": {
+                "description": "Возвращает QR-код полного короткого URL в формате PNG или SVG",
+                "produces": [
+                    "image/png",
+                    "image/svg+xml"
+                ],
+                "summary": "QR code of a short link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL alias",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "png (default) or svg",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Width and height in pixels, 64 to 2048, 256 by default",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Error correction level: L, M (default), Q or H",
+                        "name": "ecc",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Quiet zone in modules, 0 to 16, 4 by default",
+                        "name": "margin",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
+                    "410": {
+                        "description": "Link expired or used up",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "internal_http-server_handlers_apikey_create.Request": {
+            "type": "object",
+            "required": [
+                "name",
+                "scopes"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "user": {
+                    "description": "User ties the key to a user, it then only manages that user's links.",
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_apikey_create.Response": {
+            "type": "object",
+            "properties": {
+                "api_key": {
+                    "$ref": "#/definitions/url-shortener_internal_storage.APIKey"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "key": {
+                    "description": "Key is the secret, it is only shown once.",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_apikey_list.Response": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/url-shortener_internal_storage.APIKey"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_backup_create.Response": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "snapshot": {
+                    "$ref": "#/definitions/url-shortener_internal_backup.Snapshot"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_dump_load.Response": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "integer"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "overwritten": {
+                    "type": "integer"
+                },
+                "skipped": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_health.Response": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "description": "Checks maps every readiness check to \"ok\" or the reason it failed.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_preview.Preview": {
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "clicks": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "domain": {
+                    "type": "string"
+                },
+                "protected": {
+                    "type": "boolean"
+                },
+                "short_url": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_preview.Response": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "preview": {
+                    "$ref": "#/definitions/internal_http-server_handlers_preview.Preview"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_redirect.Response": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_url_batch.Response": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_http-server_handlers_url_batch.Result"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_url_batch.Result": {
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_url_get.Response": {
+            "type": "object",
+            "properties": {
+                "clicks_left": {
+                    "description": "ClicksLeft is how many redirects a link with max_clicks still serves.",
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "link": {
+                    "$ref": "#/definitions/url-shortener_internal_storage.Link"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_url_list.Response": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "links": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/url-shortener_internal_storage.Link"
+                    }
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_http-server_handlers_url_save.Request": {
+            "type": "object",
+            "required": [
+                "url"
+            ],
+            "properties": {
+                "active_from": {
+                    "description": "ActiveFrom and ExpiresAt limit when the link redirects, both are optional.",
+                    "type": "string"
+                },
+                "alias": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "max_clicks": {
+                    "description": "MaxClicks stops the link after that many redirects, 1 makes a one-time link.",
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "password": {
+                    "description": "Password protects the link, visitors have to enter it before they are\nredirected. It must be 8 to 72 bytes long, only its hash is stored.",
+                    "type": "string"
+                },
+                "qr": {
+                    "description": "QR asks for a QR code of the short link in the response.",
+                    "type": "boolean"
+                },
+                "redirect_code": {
+                    "description": "RedirectCode overrides the server default redirect status.",
+                    "type": "integer",
+                    "enum": [
+                        301,
+                        302,
+                        307,
+                        308
+                    ]
+                },
+                "sticky_variants": {
+                    "description": "StickyVariants keeps a visitor on the variant they were sent to first,\nit requires Variants.",
+                    "type": "boolean"
+                },
+                "targets": {
+                    "description": "Targets send clients on some platforms elsewhere than URL.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_save.Targets"
+                        }
+                    ]
+                },
+                "url": {
+                    "type": "string"
+                },
+                "variants": {
+                    "description": "Variants make the link rotate between destinations by weight, URL is\nthen only shown in previews.",
+                    "type": "array",
+                    "maxItems": 20,
+                    "items": {
+                        "$ref": "#/definitions/internal_http-server_handlers_url_save.Variant"
+                    }
+                }
+            }
+        },
+        "internal_http-server_handlers_url_save.Response": {
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "qr": {
+                    "description": "QR is a PNG data URI, only set when the request asked for it.",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_url_save.Targets": {
+            "type": "object",
+            "properties": {
+                "android": {
+                    "type": "string"
+                },
+                "desktop": {
+                    "type": "string"
+                },
+                "ios": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_url_save.Variant": {
+            "type": "object",
+            "required": [
+                "url",
+                "weight"
+            ],
+            "properties": {
+                "url": {
+                    "type": "string"
+                },
+                "weight": {
+                    "description": "Weight is the share of redirects relative to the other variants.",
+                    "type": "integer",
+                    "maximum": 1000,
+                    "minimum": 1
+                }
+            }
+        },
+        "internal_http-server_handlers_url_stats.Response": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "stats": {
+                    "$ref": "#/definitions/url-shortener_internal_storage.ClickStats"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_url_update.Request": {
+            "type": "object",
+            "properties": {
+                "sticky_variants": {
+                    "type": "boolean"
+                },
+                "targets": {
+                    "$ref": "#/definitions/url-shortener_internal_http-server_handlers_url_save.Targets"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "variants": {
+                    "type": "array",
+                    "maxItems": 20,
+                    "items": {
+                        "$ref": "#/definitions/url-shortener_internal_http-server_handlers_url_save.Variant"
+                    }
+                }
+            }
+        },
+        "internal_http-server_handlers_url_update.Response": {
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "sticky_variants": {
+                    "type": "boolean"
+                },
+                "targets": {
+                    "$ref": "#/definitions/url-shortener_internal_storage.Targets"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/url-shortener_internal_storage.Variant"
+                    }
+                }
+            }
+        },
+        "internal_http-server_handlers_user_create.Request": {
+            "type": "object",
+            "required": [
+                "name",
+                "password"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_user_create.Response": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "user": {
+                    "$ref": "#/definitions/url-shortener_internal_storage.User"
+                }
+            }
+        },
+        "internal_http-server_handlers_user_list.Response": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/url-shortener_internal_storage.User"
+                    }
+                }
+            }
+        },
+        "url-shortener_internal_backup.Snapshot": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "url-shortener_internal_http-server_handlers_url_save.Request": {
+            "type": "object",
+            "required": [
+                "url"
+            ],
+            "properties": {
+                "active_from": {
+                    "description": "ActiveFrom and ExpiresAt limit when the link redirects, both are optional.",
+                    "type": "string"
+                },
+                "alias": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "max_clicks": {
+                    "description": "MaxClicks stops the link after that many redirects, 1 makes a one-time link.",
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "password": {
+                    "description": "Password protects the link, visitors have to enter it before they are\nredirected. It must be 8 to 72 bytes long, only its hash is stored.",
+                    "type": "string"
+                },
+                "qr": {
+                    "description": "QR asks for a QR code of the short link in the response.",
+                    "type": "boolean"
+                },
+                "redirect_code": {
+                    "description": "RedirectCode overrides the server default redirect status.",
+                    "type": "integer",
+                    "enum": [
+                        301,
+                        302,
+                        307,
+                        308
+                    ]
+                },
+                "sticky_variants": {
+                    "description": "StickyVariants keeps a visitor on the variant they were sent to first,\nit requires Variants.",
+                    "type": "boolean"
+                },
+                "targets": {
+                    "description": "Targets send clients on some platforms elsewhere than URL.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/url-shortener_internal_http-server_handlers_url_save.Targets"
+                        }
+                    ]
+                },
+                "url": {
+                    "type": "string"
+                },
+                "variants": {
+                    "description": "Variants make the link rotate between destinations by weight, URL is\nthen only shown in previews.",
+                    "type": "array",
+                    "maxItems": 20,
+                    "items": {
+                        "$ref": "#/definitions/url-shortener_internal_http-server_handlers_url_save.Variant"
+                    }
+                }
+            }
+        },
+        "url-shortener_internal_http-server_handlers_url_save.Targets": {
+            "type": "object",
+            "properties": {
+                "android": {
+                    "type": "string"
+                },
+                "desktop": {
+                    "type": "string"
+                },
+                "ios": {
+                    "type": "string"
+                }
+            }
+        },
+        "url-shortener_internal_http-server_handlers_url_save.Variant": {
+            "type": "object",
+            "required": [
+                "url",
+                "weight"
+            ],
+            "properties": {
+                "url": {
+                    "type": "string"
+                },
+                "weight": {
+                    "description": "Weight is the share of redirects relative to the other variants.",
+                    "type": "integer",
+                    "maximum": 1000,
+                    "minimum": 1
+                }
+            }
+        },
+        "url-shortener_internal_lib_api_response.Response": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "url-shortener_internal_storage.APIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "user_id": {
+                    "description": "UserID is the user the key acts for, zero means the key is not tied to a user.",
+                    "type": "integer"
+                }
+            }
+        },
+        "url-shortener_internal_storage.ClickStats": {
+            "type": "object",
+            "properties": {
+                "daily": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/url-shortener_internal_storage.DailyClicks"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "unique_visitors": {
+                    "type": "integer"
+                },
+                "variants": {
+                    "description": "Variants are the variants of a rotating link with their click counts.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/url-shortener_internal_storage.Variant"
+                    }
+                }
+            }
+        },
+        "url-shortener_internal_storage.DailyClicks": {
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "type": "integer"
+                },
+                "date": {
+                    "type": "string"
+                }
+            }
+        },
+        "url-shortener_internal_storage.Link": {
+            "type": "object",
+            "properties": {
+                "active_from": {
+                    "description": "ActiveFrom is when the link starts redirecting, nil means right away.",
+                    "type": "string"
+                },
+                "alias": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "ExpiresAt is when the link stops redirecting, nil means never.",
+                    "type": "string"
+                },
+                "max_clicks": {
+                    "description": "MaxClicks is the number of redirects the link serves, 0 means no limit.",
+                    "type": "integer"
+                },
+                "owner_id": {
+                    "description": "OwnerID is the user who created the link, zero means no owner.",
+                    "type": "integer"
+                },
+                "redirect_code": {
+                    "description": "RedirectCode is the HTTP status used to redirect, 0 means the server default.",
+                    "type": "integer"
+                },
+                "sticky_variants": {
+                    "description": "StickyVariants keeps a visitor on the variant they were sent to first.",
+                    "type": "boolean"
+                },
+                "targets": {
+                    "description": "Targets are alternate destinations per platform, nil means none.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/url-shortener_internal_storage.Targets"
+                        }
+                    ]
+                },
+                "url": {
+                    "type": "string"
+                },
+                "used_clicks": {
+                    "description": "UsedClicks counts the redirects served of a link with MaxClicks.",
+                    "type": "integer"
+                },
+                "variants": {
+                    "description": "Variants are the destinations the link rotates between by weight,\nnone means it always redirects to its URL.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/url-shortener_internal_storage.Variant"
+                    }
+                }
+            }
+        },
+        "url-shortener_internal_storage.Targets": {
+            "type": "object",
+            "properties": {
+                "android": {
+                    "type": "string"
+                },
+                "desktop": {
+                    "type": "string"
+                },
+                "ios": {
+                    "type": "string"
+                }
+            }
+        },
+        "url-shortener_internal_storage.User": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                }
+            }
+        },
+        "url-shortener_internal_storage.Variant": {
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "description": "Clicks counts the recorded redirects to the variant.",
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "weight": {
+                    "description": "Weight is the share of redirects the variant gets relative to the\nweights of the others.",
+                    "type": "integer"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "BasicAuth": {
+            "type": "basic"
+        },
+        "BearerAuth": {
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
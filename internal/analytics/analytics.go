@@ -0,0 +1,149 @@
+package analytics
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+// maxBatchSize caps how many clicks are written in one SaveClicks call.
+const maxBatchSize = 100
+
+// DefaultFlushInterval is used when New is given a non-positive interval.
+const DefaultFlushInterval = time.Second
+
+// ClickSaver is an interface for storing click batches.
+type ClickSaver interface {
+	SaveClicks(clicks []storage.Click) error
+}
+
+// Recorder collects clicks in a buffer and writes them to storage from a
+// background goroutine, so redirects never wait for the database.
+// When the buffer is full new clicks are dropped.
+type Recorder struct {
+	log           *slog.Logger
+	saver         ClickSaver
+	ipSalt        string
+	flushInterval time.Duration
+
+	clicks    chan storage.Click
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// New starts a recorder. Client IPs are hashed together with ipSalt.
+// A non-positive flushInterval means DefaultFlushInterval.
+func New(log *slog.Logger, saver ClickSaver, ipSalt string, bufferSize int, flushInterval time.Duration) *Recorder {
+	if flushInterval <= 0 {
+		flushInterval = DefaultFlushInterval
+	}
+
+	r := &Recorder{
+		log:           log.With(slog.String("op", "analytics.Recorder")),
+		saver:         saver,
+		ipSalt:        ipSalt,
+		flushInterval: flushInterval,
+		clicks:        make(chan storage.Click, bufferSize),
+		done:          make(chan struct{}),
+	}
+
+	r.wg.Add(1)
+	go r.run()
+
+	return r
+}
+
+// RecordClick queues a click on alias made by req, which was sent to variant.
+func (r *Recorder) RecordClick(req *http.Request, alias string, variant int) {
+	click := storage.Click{
+		Alias:     alias,
+		ClickedAt: time.Now().UTC(),
+		Referrer:  req.Referer(),
+		UserAgent: req.UserAgent(),
+		IPHash:    r.hashIP(req.RemoteAddr),
+		RequestID: middleware.GetReqID(req.Context()),
+		Variant:   variant,
+	}
+
+	select {
+	case r.clicks <- click:
+	default:
+		r.log.Warn("click buffer is full, dropping click", slog.String("alias", alias))
+	}
+}
+
+// Close stops accepting clicks, flushes the buffered ones and waits for the
+// background goroutine to finish.
+func (r *Recorder) Close() {
+	r.closeOnce.Do(func() {
+		close(r.done)
+		r.wg.Wait()
+	})
+}
+
+func (r *Recorder) run() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]storage.Click, 0, maxBatchSize)
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := r.saver.SaveClicks(batch); err != nil {
+			r.log.Error("failed to save clicks", sl.Err(err), slog.Int("count", len(batch)))
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case click := <-r.clicks:
+			batch = append(batch, click)
+			if len(batch) == maxBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-r.done:
+			for {
+				select {
+				case click := <-r.clicks:
+					batch = append(batch, click)
+					if len(batch) == maxBatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// hashIP hashes the host part of remoteAddr so visitors can be counted
+// without storing their addresses.
+func (r *Recorder) hashIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	sum := sha256.Sum256([]byte(r.ipSalt + host))
+
+	return hex.EncodeToString(sum[:])
+}
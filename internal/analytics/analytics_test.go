@@ -0,0 +1,85 @@
+package analytics_test
+
+import (
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/analytics"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+type saverStub struct {
+	mu     sync.Mutex
+	clicks []storage.Click
+}
+
+func (s *saverStub) SaveClicks(clicks []storage.Click) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.clicks = append(s.clicks, clicks...)
+
+	return nil
+}
+
+func TestRecorder_FlushesOnClose(t *testing.T) {
+	saver := &saverStub{}
+	rec := analytics.New(slogdiscard.NewDiscardLogger(), saver, "salt", 16, time.Hour)
+
+	for _, addr := range []string{"10.0.0.1:1234", "10.0.0.1:5678", "10.0.0.2:1234"} {
+		req := httptest.NewRequest("GET", "/abc", nil)
+		req.RemoteAddr = addr
+		req.Header.Set("Referer", "https://example.com")
+		req.Header.Set("User-Agent", "test-agent")
+
+		rec.RecordClick(req, "abc", 2)
+	}
+
+	rec.Close()
+
+	require.Len(t, saver.clicks, 3)
+
+	click := saver.clicks[0]
+	require.Equal(t, "abc", click.Alias)
+	require.Equal(t, "https://example.com", click.Referrer)
+	require.Equal(t, "test-agent", click.UserAgent)
+	require.Equal(t, 2, click.Variant)
+	require.NotContains(t, click.IPHash, "10.0.0.1")
+	require.Equal(t, click.IPHash, saver.clicks[1].IPHash, "same host must hash equally")
+	require.NotEqual(t, click.IPHash, saver.clicks[2].IPHash)
+}
+
+func TestRecorder_FlushesOnInterval(t *testing.T) {
+	saver := &saverStub{}
+	rec := analytics.New(slogdiscard.NewDiscardLogger(), saver, "salt", 16, 10*time.Millisecond)
+	defer rec.Close()
+
+	rec.RecordClick(httptest.NewRequest("GET", "/abc", nil), "abc", 0)
+
+	require.Eventually(t, func() bool {
+		saver.mu.Lock()
+		defer saver.mu.Unlock()
+
+		return len(saver.clicks) == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestRecorder_ZeroFlushInterval(t *testing.T) {
+	saver := &saverStub{}
+	rec := analytics.New(slogdiscard.NewDiscardLogger(), saver, "salt", 16, 0)
+	defer rec.Close()
+
+	rec.RecordClick(httptest.NewRequest("GET", "/abc", nil), "abc", 0)
+
+	require.Eventually(t, func() bool {
+		saver.mu.Lock()
+		defer saver.mu.Unlock()
+
+		return len(saver.clicks) == 1
+	}, 3*analytics.DefaultFlushInterval, 10*time.Millisecond)
+}
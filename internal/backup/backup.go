@@ -0,0 +1,217 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/lib/logger/sl"
+)
+
+const (
+	filePrefix = "storage-"
+	fileSuffix = ".db"
+	// timeLayout sorts lexically in time order and is safe in file names.
+	timeLayout = "20060102T150405.000Z"
+)
+
+// Snapshotter writes a consistent copy of the database to path.
+type Snapshotter interface {
+	Backup(ctx context.Context, path string) error
+}
+
+// Snapshot is one backup file.
+type Snapshot struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	Size      int64     `json:"size"`
+}
+
+// Manager writes timestamped snapshots to a directory and deletes the
+// oldest ones beyond the retention count.
+type Manager struct {
+	log  *slog.Logger
+	db   Snapshotter
+	dir  string
+	keep int
+
+	// mu makes snapshots run one at a time.
+	mu sync.Mutex
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// New creates dir if needed and returns a Manager keeping the newest keep
+// snapshots, zero keeps all. With a positive interval a snapshot is also
+// taken every interval until Close.
+func New(log *slog.Logger, db Snapshotter, dir string, interval time.Duration, keep int) (*Manager, error) {
+	const op = "backup.New"
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{
+		log:    log.With(slog.String("op", "backup.Manager")),
+		db:     db,
+		dir:    dir,
+		keep:   keep,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	if interval > 0 {
+		m.wg.Add(1)
+		go m.run(interval)
+	}
+
+	return m, nil
+}
+
+// Close stops scheduled snapshots, cancelling one that is running.
+func (m *Manager) Close() {
+	m.closeOnce.Do(func() {
+		m.cancel()
+		m.wg.Wait()
+	})
+}
+
+// Snapshot writes a new snapshot and prunes old ones. The file only gets
+// its final name once it is complete, so List never returns a partial one.
+func (m *Manager) Snapshot(ctx context.Context) (Snapshot, error) {
+	const op = "backup.Snapshot"
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	createdAt := time.Now().UTC()
+	name := filePrefix + createdAt.Format(timeLayout) + fileSuffix
+	path := filepath.Join(m.dir, name)
+	partial := path + ".partial"
+
+	if err := m.db.Backup(ctx, partial); err != nil {
+		_ = os.Remove(partial)
+		return Snapshot{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := os.Rename(partial, path); err != nil {
+		_ = os.Remove(partial)
+		return Snapshot{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := m.prune(); err != nil {
+		// The snapshot itself is fine, old ones are retried next time.
+		m.log.Error("failed to delete old snapshots", sl.Err(err))
+	}
+
+	return Snapshot{Name: name, Path: path, CreatedAt: createdAt, Size: info.Size()}, nil
+}
+
+// prune deletes the snapshots beyond the newest keep.
+func (m *Manager) prune() error {
+	if m.keep <= 0 {
+		return nil
+	}
+
+	snapshots, err := List(m.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range snapshots[min(m.keep, len(snapshots)):] {
+		if err := os.Remove(s.Path); err != nil {
+			return err
+		}
+		m.log.Info("deleted old snapshot", slog.String("name", s.Name))
+	}
+
+	return nil
+}
+
+func (m *Manager) run(interval time.Duration) {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			start := time.Now()
+			s, err := m.Snapshot(m.ctx)
+			if err != nil {
+				m.log.Error("scheduled snapshot failed", sl.Err(err))
+				continue
+			}
+			m.log.Info("snapshot written",
+				slog.String("name", s.Name),
+				slog.Int64("size", s.Size),
+				slog.Duration("took", time.Since(start)),
+			)
+		case <-m.ctx.Done():
+			return
+		}
+	}
+}
+
+// List returns the snapshots in dir, newest first.
+func List(dir string) ([]Snapshot, error) {
+	const op = "backup.List"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var snapshots []Snapshot
+	for _, entry := range entries {
+		name := entry.Name()
+		stamp, ok := strings.CutPrefix(name, filePrefix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		stamp, ok = strings.CutSuffix(stamp, fileSuffix)
+		if !ok {
+			continue
+		}
+
+		createdAt, err := time.Parse(timeLayout, stamp)
+		if err != nil {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		snapshots = append(snapshots, Snapshot{
+			Name:      name,
+			Path:      filepath.Join(dir, name),
+			CreatedAt: createdAt,
+			Size:      info.Size(),
+		})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+
+	return snapshots, nil
+}
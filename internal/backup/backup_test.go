@@ -0,0 +1,114 @@
+package backup_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/backup"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+type snapshotterStub struct {
+	mu    sync.Mutex
+	calls int
+	err   error
+}
+
+func (s *snapshotterStub) Backup(_ context.Context, path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	if s.err != nil {
+		// A failed backup may leave a partial file behind.
+		_ = os.WriteFile(path, []byte("partial"), 0o600)
+		return s.err
+	}
+
+	return os.WriteFile(path, []byte("snapshot"), 0o600)
+}
+
+func TestManager_Snapshot(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "backups")
+	db := &snapshotterStub{}
+
+	m, err := backup.New(slogdiscard.NewDiscardLogger(), db, dir, 0, 2)
+	require.NoError(t, err)
+	t.Cleanup(m.Close)
+
+	var names []string
+	for i := 0; i < 3; i++ {
+		s, err := m.Snapshot(context.Background())
+		require.NoError(t, err)
+		require.EqualValues(t, len("snapshot"), s.Size)
+		require.FileExists(t, s.Path)
+		names = append(names, s.Name)
+
+		// Timestamps have millisecond precision.
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	// Files that are not snapshots are left alone.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o600))
+
+	snapshots, err := backup.List(dir)
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	require.Equal(t, names[2], snapshots[0].Name)
+	require.Equal(t, names[1], snapshots[1].Name)
+	require.FileExists(t, filepath.Join(dir, "notes.txt"))
+}
+
+func TestManager_Snapshot_Error(t *testing.T) {
+	dir := t.TempDir()
+	db := &snapshotterStub{err: errors.New("disk full")}
+
+	m, err := backup.New(slogdiscard.NewDiscardLogger(), db, dir, 0, 2)
+	require.NoError(t, err)
+	t.Cleanup(m.Close)
+
+	_, err = m.Snapshot(context.Background())
+	require.ErrorIs(t, err, db.err)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries, "partial snapshot is removed")
+}
+
+func TestManager_Schedule(t *testing.T) {
+	dir := t.TempDir()
+	db := &snapshotterStub{}
+
+	m, err := backup.New(slogdiscard.NewDiscardLogger(), db, dir, 10*time.Millisecond, 1)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		db.mu.Lock()
+		defer db.mu.Unlock()
+
+		return db.calls >= 2
+	}, time.Second, 5*time.Millisecond)
+
+	m.Close()
+
+	snapshots, err := backup.List(dir)
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+
+	db.mu.Lock()
+	calls := db.calls
+	db.mu.Unlock()
+
+	time.Sleep(30 * time.Millisecond)
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	require.Equal(t, calls, db.calls, "no snapshots after Close")
+}
@@ -0,0 +1,84 @@
+package cache_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/http-server/handlers/redirect"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/sqlite"
+)
+
+type noopRecorder struct{}
+
+func (noopRecorder) RecordClick(*http.Request, string, int) {}
+
+// BenchmarkRedirect compares redirect throughput against SQLite with and
+// without the cache: go test -bench Redirect ./internal/cache/
+func BenchmarkRedirect(b *testing.B) {
+	store, err := sqlite.New(filepath.Join(b.TempDir(), "storage.db"), 0, nil)
+	require.NoError(b, err)
+	b.Cleanup(func() { _ = store.Close() })
+
+	m, err := store.Migrator()
+	require.NoError(b, err)
+	_, err = m.Up()
+	require.NoError(b, err)
+
+	const links = 1000
+
+	aliases := make([]string, links)
+	for i := range aliases {
+		aliases[i], _, err = store.SaveGeneratedURL(fmt.Sprintf("https://example.com/%d", i), storage.LinkOptions{})
+		require.NoError(b, err)
+	}
+
+	getters := []struct {
+		name   string
+		getter redirect.URLGetter
+	}{
+		{name: "uncached", getter: store},
+		{name: "cached", getter: cache.New(store, links, time.Minute, time.Minute)},
+	}
+
+	for _, g := range getters {
+		b.Run(g.name, func(b *testing.B) {
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(
+				slogdiscard.NewDiscardLogger(),
+				g.getter,
+				nil,
+				noopRecorder{},
+				http.StatusFound,
+				time.Hour,
+				redirect.Passwords{},
+			))
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					req := httptest.NewRequest(http.MethodGet, "/"+aliases[i%links], nil)
+					rr := httptest.NewRecorder()
+					r.ServeHTTP(rr, req)
+
+					if rr.Code != http.StatusFound {
+						b.Fatalf("unexpected status %d", rr.Code)
+					}
+					i++
+				}
+			})
+		})
+	}
+}
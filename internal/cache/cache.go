@@ -0,0 +1,196 @@
+package cache
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"url-shortener/internal/storage"
+)
+
+// LinkGetter is an interface for getting a link by alias.
+type LinkGetter interface {
+	GetLink(alias string) (storage.Link, error)
+}
+
+// Stats are the cache counters since it was created.
+type Stats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// Cache is a read-through cache in front of a LinkGetter. It keeps at most
+// size links, each for ttl, and remembers unknown aliases for negativeTTL.
+// Concurrent misses for one alias result in a single lookup.
+type Cache struct {
+	getter      LinkGetter
+	size        int
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// lru holds *entry values, the most recently used at the front.
+	lru *list.List
+
+	// stale marks in-flight lookups invalidated before they finished, so they
+	// do not put an outdated link back into the cache.
+	stale map[string]bool
+	group singleflight.Group
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+type entry struct {
+	alias     string
+	link      storage.Link
+	notFound  bool
+	expiresAt time.Time
+}
+
+// New creates a cache in front of getter.
+func New(getter LinkGetter, size int, ttl, negativeTTL time.Duration) *Cache {
+	return &Cache{
+		getter:      getter,
+		size:        size,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+		stale:       make(map[string]bool),
+	}
+}
+
+// GetLink returns the cached link or loads it from the underlying getter.
+// Only storage.ErrURLNotFound is cached, other errors are returned as is.
+func (c *Cache) GetLink(alias string) (storage.Link, error) {
+	if link, notFound, ok := c.lookup(alias); ok {
+		c.hits.Add(1)
+		if notFound {
+			return storage.Link{}, storage.ErrURLNotFound
+		}
+
+		return link, nil
+	}
+
+	c.misses.Add(1)
+
+	v, err, _ := c.group.Do(alias, func() (any, error) {
+		c.mu.Lock()
+		c.stale[alias] = false
+		c.mu.Unlock()
+
+		link, err := c.getter.GetLink(alias)
+		switch {
+		case err == nil:
+			c.store(alias, link, false)
+		case errors.Is(err, storage.ErrURLNotFound):
+			c.store(alias, storage.Link{}, true)
+		default:
+			c.mu.Lock()
+			delete(c.stale, alias)
+			c.mu.Unlock()
+		}
+
+		return link, err
+	})
+
+	return v.(storage.Link), err
+}
+
+// Invalidate drops the cached state of alias. It must be called after the
+// link is saved, updated or deleted.
+func (c *Cache) Invalidate(alias string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.stale[alias]; ok {
+		c.stale[alias] = true
+	}
+	if el, ok := c.entries[alias]; ok {
+		c.remove(el)
+	}
+}
+
+// Stats returns the hit and miss counters.
+func (c *Cache) Stats() Stats {
+	return Stats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+	}
+}
+
+// Len returns the number of cached aliases, including expired ones not yet evicted.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+func (c *Cache) lookup(alias string) (storage.Link, bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[alias]
+	if !ok {
+		return storage.Link{}, false, false
+	}
+
+	e := el.Value.(*entry)
+	if time.Now().After(e.expiresAt) {
+		c.remove(el)
+		return storage.Link{}, false, false
+	}
+
+	c.lru.MoveToFront(el)
+
+	return e.link, e.notFound, true
+}
+
+// store caches the result of a finished lookup.
+func (c *Cache) store(alias string, link storage.Link, notFound bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stale := c.stale[alias]
+	delete(c.stale, alias)
+
+	ttl := c.ttl
+	if notFound {
+		ttl = c.negativeTTL
+	}
+	if stale || ttl <= 0 || c.size <= 0 {
+		return
+	}
+
+	e := &entry{
+		alias:     alias,
+		link:      link,
+		notFound:  notFound,
+		expiresAt: time.Now().Add(ttl),
+	}
+
+	if el, ok := c.entries[alias]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+
+	c.entries[alias] = c.lru.PushFront(e)
+
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove must be called with c.mu held.
+func (c *Cache) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.entries, el.Value.(*entry).alias)
+}
@@ -0,0 +1,190 @@
+package cache_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/cache"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+)
+
+type getterStub struct {
+	calls   atomic.Int64
+	delay   time.Duration
+	links   map[string]string
+	release chan struct{}
+}
+
+func (g *getterStub) GetLink(alias string) (storage.Link, error) {
+	g.calls.Add(1)
+	if g.release != nil {
+		<-g.release
+	}
+	time.Sleep(g.delay)
+
+	if alias == "broken" {
+		return storage.Link{}, errors.New("unexpected error")
+	}
+
+	url, ok := g.links[alias]
+	if !ok {
+		return storage.Link{}, storage.ErrURLNotFound
+	}
+
+	return storage.Link{Alias: alias, URL: url}, nil
+}
+
+func TestCache_GetLink(t *testing.T) {
+	getter := &getterStub{links: map[string]string{"abc": "https://google.com"}}
+	c := cache.New(getter, 10, time.Minute, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		link, err := c.GetLink("abc")
+		require.NoError(t, err)
+		require.Equal(t, "https://google.com", link.URL)
+
+		_, err = c.GetLink("missing")
+		require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+		_, err = c.GetLink("broken")
+		require.Error(t, err)
+	}
+
+	// Errors other than not found are never cached.
+	require.EqualValues(t, 2+3, getter.calls.Load())
+	require.Equal(t, cache.Stats{Hits: 4, Misses: 5}, c.Stats())
+}
+
+func TestCache_TTL(t *testing.T) {
+	getter := &getterStub{links: map[string]string{"abc": "https://google.com"}}
+	c := cache.New(getter, 10, 20*time.Millisecond, 0)
+
+	_, _ = c.GetLink("abc")
+	_, _ = c.GetLink("abc")
+	require.EqualValues(t, 1, getter.calls.Load())
+
+	time.Sleep(30 * time.Millisecond)
+
+	_, _ = c.GetLink("abc")
+	require.EqualValues(t, 2, getter.calls.Load())
+
+	// A zero negative TTL disables negative caching.
+	_, _ = c.GetLink("missing")
+	_, _ = c.GetLink("missing")
+	require.EqualValues(t, 4, getter.calls.Load())
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	getter := &getterStub{links: map[string]string{"a": "1", "b": "2", "c": "3"}}
+	c := cache.New(getter, 2, time.Minute, time.Minute)
+
+	_, _ = c.GetLink("a")
+	_, _ = c.GetLink("b")
+	_, _ = c.GetLink("a")
+	_, _ = c.GetLink("c") // evicts b
+	require.Equal(t, 2, c.Len())
+	require.EqualValues(t, 3, getter.calls.Load())
+
+	_, _ = c.GetLink("a")
+	require.EqualValues(t, 3, getter.calls.Load())
+
+	_, _ = c.GetLink("b")
+	require.EqualValues(t, 4, getter.calls.Load())
+}
+
+func TestCache_CollapsesConcurrentMisses(t *testing.T) {
+	getter := &getterStub{
+		links:   map[string]string{"abc": "https://google.com"},
+		release: make(chan struct{}),
+		// Gives callers that already counted a miss time to join the lookup.
+		delay: 20 * time.Millisecond,
+	}
+	c := cache.New(getter, 10, time.Minute, time.Minute)
+
+	const n = 50
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			link, err := c.GetLink("abc")
+			require.NoError(t, err)
+			require.Equal(t, "https://google.com", link.URL)
+		}()
+	}
+
+	require.Eventually(t, func() bool { return c.Stats().Misses+c.Stats().Hits == n }, time.Second, time.Millisecond)
+	close(getter.release)
+	wg.Wait()
+
+	require.EqualValues(t, 1, getter.calls.Load())
+}
+
+func TestCache_InvalidateDuringLookup(t *testing.T) {
+	getter := &getterStub{
+		links:   map[string]string{"abc": "https://google.com"},
+		release: make(chan struct{}),
+	}
+	c := cache.New(getter, 10, time.Minute, time.Minute)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = c.GetLink("abc")
+	}()
+
+	require.Eventually(t, func() bool { return getter.calls.Load() == 1 }, time.Second, time.Millisecond)
+	c.Invalidate("abc")
+	close(getter.release)
+	<-done
+
+	require.Zero(t, c.Len(), "a lookup started before invalidation must not be cached")
+}
+
+func TestStore_InvalidatesOnWrite(t *testing.T) {
+	store := memory.New(0, nil)
+	c := cache.New(store, 10, time.Minute, time.Minute)
+	s := cache.WithInvalidation(store, c)
+
+	_, err := c.GetLink("abc")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	_, err = s.SaveURL("https://google.com", "abc", storage.LinkOptions{})
+	require.NoError(t, err)
+
+	link, err := c.GetLink("abc")
+	require.NoError(t, err)
+	require.Equal(t, "https://google.com", link.URL)
+
+	require.NoError(t, s.UpdateURL("abc", "https://go.dev", storage.AnyOwner))
+
+	link, err = c.GetLink("abc")
+	require.NoError(t, err)
+	require.Equal(t, "https://go.dev", link.URL)
+
+	require.NoError(t, s.DeleteURL("abc", storage.AnyOwner))
+
+	_, err = c.GetLink("abc")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	alias, _, err := s.SaveGeneratedURL("https://google.com", storage.LinkOptions{})
+	require.NoError(t, err)
+	_, err = c.GetLink(alias)
+	require.NoError(t, err)
+
+	_, err = c.GetLink("batch")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	_, err = s.SaveURLBatch([]storage.NewLink{{URL: "https://go.dev", Alias: "batch"}})
+	require.NoError(t, err)
+	_, err = c.GetLink("batch")
+	require.NoError(t, err)
+}
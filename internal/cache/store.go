@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"url-shortener/internal/storage"
+)
+
+// Store forwards to a storage.Store and invalidates the cached link after
+// every write, so redirects see saved, updated and deleted links at once.
+//
+// Links removed by DeleteExpiredURLs are not invalidated: they are already
+// expired, so cached copies answer the same until their TTL runs out.
+type Store struct {
+	storage.Store
+	cache *Cache
+}
+
+// WithInvalidation wraps store so that writes invalidate cache.
+func WithInvalidation(store storage.Store, cache *Cache) *Store {
+	return &Store{Store: store, cache: cache}
+}
+
+func (s *Store) SaveURL(urlToSave string, alias string, opts storage.LinkOptions) (int64, error) {
+	id, err := s.Store.SaveURL(urlToSave, alias, opts)
+	s.cache.Invalidate(alias)
+
+	return id, err
+}
+
+func (s *Store) SaveGeneratedURL(urlToSave string, opts storage.LinkOptions) (string, int64, error) {
+	alias, id, err := s.Store.SaveGeneratedURL(urlToSave, opts)
+	if err == nil {
+		s.cache.Invalidate(alias)
+	}
+
+	return alias, id, err
+}
+
+func (s *Store) SaveURLBatch(links []storage.NewLink) ([]storage.BatchResult, error) {
+	results, err := s.Store.SaveURLBatch(links)
+	for _, res := range results {
+		if res.Err == nil {
+			s.cache.Invalidate(res.Alias)
+		}
+	}
+
+	return results, err
+}
+
+func (s *Store) ImportLinks(links []storage.Link, onConflict storage.ConflictPolicy) (storage.ImportStats, error) {
+	stats, err := s.Store.ImportLinks(links, onConflict)
+	for _, link := range links {
+		s.cache.Invalidate(link.Alias)
+	}
+
+	return stats, err
+}
+
+func (s *Store) UpdateURL(alias string, newURL string, ownerID int64) error {
+	err := s.Store.UpdateURL(alias, newURL, ownerID)
+	s.cache.Invalidate(alias)
+
+	return err
+}
+
+func (s *Store) UpdateLink(alias string, update storage.LinkUpdate, ownerID int64) (storage.Link, error) {
+	link, err := s.Store.UpdateLink(alias, update, ownerID)
+	s.cache.Invalidate(alias)
+
+	return link, err
+}
+
+func (s *Store) UseClick(alias string) (int64, error) {
+	left, err := s.Store.UseClick(alias)
+	s.cache.Invalidate(alias)
+
+	return left, err
+}
+
+func (s *Store) DeleteURL(alias string, ownerID int64) error {
+	err := s.Store.DeleteURL(alias, ownerID)
+	s.cache.Invalidate(alias)
+
+	return err
+}
@@ -9,9 +9,25 @@ import (
 )
 
 type Config struct {
-	Env         string `yaml:"env" env-default:"local"`
-	StoragePath string `yaml:"storage_path" env-required:"true"`
+	Env         string  `yaml:"env" env-default:"local"`
+	StoragePath string  `yaml:"storage_path" env-default:"./storage.db"`
+	Storage     Storage `yaml:"storage"`
 	HTTPServer  `yaml:"http_server"`
+	Alias       Alias      `yaml:"alias"`
+	Analytics   Analytics  `yaml:"analytics"`
+	Expiration  Expiration `yaml:"expiration"`
+	Redirect    Redirect   `yaml:"redirect"`
+	Cache       Cache      `yaml:"cache"`
+	RateLimit   RateLimit  `yaml:"rate_limit"`
+	Batch       Batch      `yaml:"batch"`
+	Backup      Backup     `yaml:"backup"`
+}
+
+type Storage struct {
+	// Driver selects the storage backend: "sqlite", "postgres" or "memory".
+	Driver string `yaml:"driver" env-default:"sqlite"`
+	// DSN is the connection string for the postgres driver.
+	DSN string `yaml:"dsn" env:"STORAGE_DSN"`
 }
 
 type HTTPServer struct {
@@ -20,6 +36,108 @@ type HTTPServer struct {
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
 	User        string        `yaml:"user" env-required:"true"`
 	Password    string        `yaml:"password" env-required:"true" env:"HTTP_SERVER_PASSWORD"`
+	// AdminAddress serves /metrics on a separate listener, empty serves it on Address.
+	AdminAddress string `yaml:"admin_address" env:"HTTP_SERVER_ADMIN_ADDRESS"`
+	// ShutdownDelay is how long /readyz fails before the server stops accepting requests.
+	ShutdownDelay time.Duration `yaml:"shutdown_delay" env-default:"0s"`
+	// PublicURL is the base of short URLs, e.g. "https://sho.rt". Empty takes
+	// the scheme and host of each request, set it behind a proxy.
+	PublicURL string `yaml:"public_url" env:"HTTP_SERVER_PUBLIC_URL"`
+	// TrustProxy takes the client IP from the X-Forwarded-For and X-Real-IP
+	// headers. Enable it only behind a proxy that sets them, otherwise
+	// clients can pick their own IP.
+	TrustProxy bool `yaml:"trust_proxy" env:"HTTP_SERVER_TRUST_PROXY" env-default:"false"`
+}
+
+type Alias struct {
+	// MaxLength limits generated aliases, 0 means no limit.
+	MaxLength int         `yaml:"max_length" env-default:"0"`
+	Custom    CustomAlias `yaml:"custom"`
+}
+
+type CustomAlias struct {
+	// Users lists the basic auth users and API key names allowed to choose
+	// their own aliases.
+	Users     []string `yaml:"users"`
+	MinLength int      `yaml:"min_length" env-default:"3"`
+	MaxLength int      `yaml:"max_length" env-default:"32"`
+	// Reserved extends customalias.DefaultReserved.
+	Reserved []string `yaml:"reserved"`
+}
+
+type Analytics struct {
+	// IPSalt is mixed into client IP hashes so they cannot be reversed by brute force.
+	IPSalt string `yaml:"ip_salt" env:"ANALYTICS_IP_SALT"`
+	// BufferSize is the number of clicks queued for writing, further clicks are dropped.
+	BufferSize    int           `yaml:"buffer_size" env-default:"1024"`
+	FlushInterval time.Duration `yaml:"flush_interval" env-default:"1s"`
+}
+
+type Expiration struct {
+	// SweepInterval is how often expired links are purged, 0 disables purging.
+	SweepInterval time.Duration `yaml:"sweep_interval" env-default:"1h"`
+	// Retention keeps expired links answering 410 Gone for a while before they are purged.
+	Retention time.Duration `yaml:"retention" env-default:"24h"`
+}
+
+type Redirect struct {
+	// DefaultCode is used for links saved without a redirect code: 301, 302, 307 or 308.
+	DefaultCode int `yaml:"default_code" env-default:"302"`
+	// PermanentMaxAge is how long clients may cache 301 and 308 redirects.
+	PermanentMaxAge time.Duration `yaml:"permanent_max_age" env-default:"24h"`
+	Password        LinkPassword  `yaml:"password"`
+}
+
+type LinkPassword struct {
+	// CookieSecret signs the cookies of visitors who entered a link password.
+	// Empty uses a random secret, so the cookies are lost on restart and
+	// several instances do not accept each other's cookies.
+	CookieSecret string `yaml:"cookie_secret" env:"LINK_PASSWORD_COOKIE_SECRET"`
+	// CookieTTL is how long a visitor is not asked for the password again.
+	CookieTTL time.Duration `yaml:"cookie_ttl" env-default:"1h"`
+	// Attempts limits password attempts per link, 0 disables the limit.
+	Attempts       int           `yaml:"attempts" env-default:"5"`
+	AttemptsPeriod time.Duration `yaml:"attempts_period" env-default:"1m"`
+}
+
+type Cache struct {
+	// Size is the number of links cached for redirects, 0 disables the cache.
+	Size int `yaml:"size" env-default:"10000"`
+	// TTL also bounds how long changes made through other instances take to show up.
+	TTL time.Duration `yaml:"ttl" env-default:"1m"`
+	// NegativeTTL is how long unknown aliases are remembered, 0 disables it.
+	NegativeTTL time.Duration `yaml:"negative_ttl" env-default:"10s"`
+}
+
+type RateLimit struct {
+	// Save limits link creation per API key, user or client IP.
+	Save Limit `yaml:"save"`
+	// Redirect limits redirects per client IP.
+	Redirect Limit `yaml:"redirect"`
+}
+
+// Limit allows Requests per Period on average with bursts of up to Burst
+// requests, 0 requests disables the limit and 0 burst means Requests.
+type Limit struct {
+	Requests int           `yaml:"requests"`
+	Period   time.Duration `yaml:"period" env-default:"1m"`
+	Burst    int           `yaml:"burst"`
+}
+
+type Batch struct {
+	// MaxSize is the number of links accepted by one POST /url/batch request.
+	MaxSize int `yaml:"max_size" env-default:"1000"`
+	// MaxBodySize is the number of bytes accepted by one POST /url/batch request.
+	MaxBodySize int64 `yaml:"max_body_size" env-default:"8388608"`
+}
+
+type Backup struct {
+	// Dir is where SQLite snapshots are written, empty disables backups.
+	Dir string `yaml:"dir"`
+	// Interval is how often a snapshot is taken, 0 only takes them on request.
+	Interval time.Duration `yaml:"interval" env-default:"0s"`
+	// Keep is the number of snapshots kept, older ones are deleted. 0 keeps all.
+	Keep int `yaml:"keep" env-default:"7"`
 }
 
 func MustLoad() *Config {
@@ -0,0 +1,158 @@
+package create
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/lib/account"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+type Request struct {
+	Name   string   `json:"name" validate:"required"`
+	Scopes []string `json:"scopes" validate:"required"`
+	// User ties the key to a user, it then only manages that user's links.
+	User string `json:"user,omitempty"`
+}
+
+type Response struct {
+	resp.Response
+	// Key is the secret, it is only shown once.
+	Key    string          `json:"key,omitempty"`
+	APIKey *storage.APIKey `json:"api_key,omitempty"`
+}
+
+// KeySaver is an interface for saving API keys.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=KeySaver
+type KeySaver interface {
+	SaveAPIKey(key storage.APIKey, hash string) (int64, error)
+}
+
+// UserGetter is an interface for looking up the user a key is issued to.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=UserGetter
+type UserGetter interface {
+	GetUserByName(name string) (storage.User, error)
+}
+
+// @Summary      Create API key
+// @Description  Выпускает новый API-ключ, сам ключ возвращается только один раз
+// @Accept       json
+// @Produce      json
+// @Security     BasicAuth
+// @Security     BearerAuth
+// @Param        request body Request true "Key name and scopes"
+// @Success      200 {object} Response
+// @Failure      400 {object} Response
+// @Failure      500 {object} Response
+// @Router       /admin/keys [post]
+func New(log *slog.Logger, keySaver KeySaver, userGetter UserGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.apikey.create.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("empty request"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Info("invalid request", sl.Err(err))
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+			return
+		}
+
+		if err := apikey.ValidateScopes(req.Scopes); err != nil {
+			log.Info("invalid scopes", sl.Err(err))
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(fmt.Sprintf("invalid scopes: %s", err)))
+			return
+		}
+
+		var userID int64
+		if req.User != "" {
+			user, err := userGetter.GetUserByName(req.User)
+			if errors.Is(err, storage.ErrUserNotFound) {
+				log.Info("user not found", slog.String("user", req.User))
+				w.WriteHeader(http.StatusBadRequest)
+				render.JSON(w, r, resp.Error("user not found"))
+				return
+			}
+			if err != nil {
+				log.Error("failed to get user", sl.Err(err))
+				w.WriteHeader(http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error("internal error"))
+				return
+			}
+
+			if err := account.CheckKeyScopes(user.Role, req.Scopes); err != nil {
+				log.Info("scopes exceed the user role", slog.String("user", req.User))
+				w.WriteHeader(http.StatusBadRequest)
+				render.JSON(w, r, resp.Error(err.Error()))
+				return
+			}
+
+			userID = user.ID
+		}
+
+		secret, prefix, hash, err := apikey.Generate()
+		if err != nil {
+			log.Error("failed to generate api key", sl.Err(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("internal error"))
+			return
+		}
+
+		key := storage.APIKey{
+			Name:      req.Name,
+			Prefix:    prefix,
+			Scopes:    req.Scopes,
+			CreatedAt: time.Now().UTC(),
+			UserID:    userID,
+		}
+
+		key.ID, err = keySaver.SaveAPIKey(key, hash)
+		if err != nil {
+			log.Error("failed to save api key", sl.Err(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("failed to save api key"))
+			return
+		}
+
+		log.Info("api key created", slog.Int64("id", key.ID), slog.String("name", key.Name))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Key:      secret,
+			APIKey:   &key,
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package create_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/apikey/create"
+	"url-shortener/internal/http-server/handlers/apikey/create/mocks"
+	"url-shortener/internal/lib/account"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestCreateHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		body      string
+		respError string
+		respCode  int
+		mockError error
+		noMock    bool
+		userID    int64
+	}{
+		{
+			name:     "Success",
+			body:     `{"name": "billing", "scopes": ["create", "read"]}`,
+			respCode: http.StatusOK,
+		},
+		{
+			name:     "For user",
+			body:     `{"name": "ci", "scopes": ["read"], "user": "alice"}`,
+			respCode: http.StatusOK,
+			userID:   3,
+		},
+		{
+			name:      "Admin scope for user",
+			body:      `{"name": "ci", "scopes": ["admin"], "user": "alice"}`,
+			respError: "admin scope requires an admin user",
+			respCode:  http.StatusBadRequest,
+			noMock:    true,
+		},
+		{
+			name:      "Unknown user",
+			body:      `{"name": "ci", "scopes": ["read"], "user": "bob"}`,
+			respError: "user not found",
+			respCode:  http.StatusBadRequest,
+			noMock:    true,
+		},
+		{
+			name:      "Empty name",
+			body:      `{"scopes": ["read"]}`,
+			respError: "field Name is a required field",
+			respCode:  http.StatusBadRequest,
+			noMock:    true,
+		},
+		{
+			name:      "Unknown scope",
+			body:      `{"name": "billing", "scopes": ["write"]}`,
+			respError: `invalid scopes: unknown scope: "write"`,
+			respCode:  http.StatusBadRequest,
+			noMock:    true,
+		},
+		{
+			name:      "Empty body",
+			respError: "empty request",
+			respCode:  http.StatusBadRequest,
+			noMock:    true,
+		},
+		{
+			name:      "SaveAPIKey Error",
+			body:      `{"name": "billing", "scopes": ["admin"]}`,
+			respError: "failed to save api key",
+			respCode:  http.StatusInternalServerError,
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			keySaverMock := mocks.NewKeySaver(t)
+			userGetterMock := mocks.NewUserGetter(t)
+
+			userGetterMock.On("GetUserByName", "alice").
+				Return(storage.User{ID: 3, Name: "alice", Role: account.RoleUser}, nil).
+				Maybe()
+			userGetterMock.On("GetUserByName", "bob").
+				Return(storage.User{}, storage.ErrUserNotFound).
+				Maybe()
+
+			if !tc.noMock {
+				keySaverMock.On("SaveAPIKey", mock.MatchedBy(func(key storage.APIKey) bool {
+					return key.UserID == tc.userID
+				}), mock.AnythingOfType("string")).
+					Return(int64(7), tc.mockError).
+					Once()
+			}
+
+			handler := create.New(slogdiscard.NewDiscardLogger(), keySaverMock, userGetterMock)
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/keys", bytes.NewReader([]byte(tc.body)))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var body create.Response
+
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+
+			require.Equal(t, tc.respError, body.Error)
+
+			if tc.respError == "" {
+				require.True(t, strings.HasPrefix(body.Key, body.APIKey.Prefix))
+				require.Equal(t, int64(7), body.APIKey.ID)
+
+				saved := keySaverMock.Calls[0].Arguments.String(1)
+				require.Equal(t, apikey.Hash(body.Key), saved)
+			}
+		})
+	}
+}
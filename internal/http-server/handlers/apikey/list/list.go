@@ -0,0 +1,57 @@
+package list
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+type Response struct {
+	resp.Response
+	Keys []storage.APIKey `json:"keys,omitempty"`
+}
+
+// KeyLister is an interface for listing API keys.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=KeyLister
+type KeyLister interface {
+	ListAPIKeys() ([]storage.APIKey, error)
+}
+
+// @Summary      List API keys
+// @Description  Возвращает все API-ключи, включая отозванные, без самих секретов
+// @Produce      json
+// @Security     BasicAuth
+// @Security     BearerAuth
+// @Success      200 {object} Response
+// @Failure      500 {object} Response
+// @Router       /admin/keys [get]
+func New(log *slog.Logger, keyLister KeyLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.apikey.list.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		keys, err := keyLister.ListAPIKeys()
+		if err != nil {
+			log.Error("failed to list api keys", sl.Err(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("internal error"))
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Keys:     keys,
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package list_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/apikey/list"
+	"url-shortener/internal/http-server/handlers/apikey/list/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestListHandler(t *testing.T) {
+	keys := []storage.APIKey{
+		{ID: 1, Name: "billing", Prefix: "usk_12345678", Scopes: []string{"read"}, CreatedAt: time.Now().UTC()},
+	}
+
+	cases := []struct {
+		name      string
+		respError string
+		respCode  int
+		mockKeys  []storage.APIKey
+		mockError error
+	}{
+		{
+			name:     "Success",
+			respCode: http.StatusOK,
+			mockKeys: keys,
+		},
+		{
+			name:      "ListAPIKeys Error",
+			respError: "internal error",
+			respCode:  http.StatusInternalServerError,
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			keyListerMock := mocks.NewKeyLister(t)
+			keyListerMock.On("ListAPIKeys").
+				Return(tc.mockKeys, tc.mockError).
+				Once()
+
+			handler := list.New(slogdiscard.NewDiscardLogger(), keyListerMock)
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/keys", nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var body list.Response
+
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+
+			require.Equal(t, tc.respError, body.Error)
+			require.Len(t, body.Keys, len(tc.mockKeys))
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package revoke
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+// KeyRevoker is an interface for revoking API keys.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=KeyRevoker
+type KeyRevoker interface {
+	RevokeAPIKey(id int64, revokedAt time.Time) error
+}
+
+// @Summary      Revoke API key
+// @Description  Отзывает API-ключ, после этого он больше не принимается
+// @Produce      json
+// @Security     BasicAuth
+// @Security     BearerAuth
+// @Param        id path int true "API key id"
+// @Success      200 {object} resp.Response
+// @Failure      400 {object} resp.Response
+// @Failure      404 {object} resp.Response
+// @Failure      500 {object} resp.Response
+// @Router       /admin/keys/{id} [delete]
+func New(log *slog.Logger, keyRevoker KeyRevoker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.apikey.revoke.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.Info("invalid api key id", sl.Err(err))
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("invalid id"))
+			return
+		}
+
+		err = keyRevoker.RevokeAPIKey(id, time.Now().UTC())
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			log.Info("api key not found", slog.Int64("id", id))
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, resp.Error("not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to revoke api key", sl.Err(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("failed to revoke api key"))
+			return
+		}
+
+		log.Info("api key revoked", slog.Int64("id", id))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
@@ -0,0 +1,88 @@
+package revoke_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/apikey/revoke"
+	"url-shortener/internal/http-server/handlers/apikey/revoke/mocks"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestRevokeHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		id        string
+		respError string
+		respCode  int
+		mockError error
+		noMock    bool
+	}{
+		{
+			name:     "Success",
+			id:       "3",
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Invalid id",
+			id:        "abc",
+			respError: "invalid id",
+			respCode:  http.StatusBadRequest,
+			noMock:    true,
+		},
+		{
+			name:      "Not found",
+			id:        "3",
+			respError: "not found",
+			respCode:  http.StatusNotFound,
+			mockError: storage.ErrAPIKeyNotFound,
+		},
+		{
+			name:      "RevokeAPIKey Error",
+			id:        "3",
+			respError: "failed to revoke api key",
+			respCode:  http.StatusInternalServerError,
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			keyRevokerMock := mocks.NewKeyRevoker(t)
+
+			if !tc.noMock {
+				keyRevokerMock.On("RevokeAPIKey", int64(3), mock.AnythingOfType("time.Time")).
+					Return(tc.mockError).
+					Once()
+			}
+
+			r := chi.NewRouter()
+			r.Delete("/admin/keys/{id}", revoke.New(slogdiscard.NewDiscardLogger(), keyRevokerMock))
+
+			req := httptest.NewRequest(http.MethodDelete, "/admin/keys/"+tc.id, nil)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var body resp.Response
+
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+
+			require.Equal(t, tc.respError, body.Error)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package create
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/backup"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+)
+
+type Response struct {
+	resp.Response
+	Snapshot *backup.Snapshot `json:"snapshot,omitempty"`
+}
+
+// SnapshotTaker is an interface for taking database snapshots.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=SnapshotTaker
+type SnapshotTaker interface {
+	Snapshot(ctx context.Context) (backup.Snapshot, error)
+}
+
+// @Summary      Take a backup
+// @Description  Записывает снимок базы данных в каталог резервных копий и удаляет лишние старые снимки
+// @Produce      json
+// @Security     BasicAuth
+// @Security     BearerAuth
+// @Success      200 {object} Response
+// @Failure      500 {object} Response
+// @Router       /admin/backups [post]
+func New(log *slog.Logger, taker SnapshotTaker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.backup.create.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		// A large database takes longer than the server write timeout allows.
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+		snapshot, err := taker.Snapshot(r.Context())
+		if err != nil {
+			log.Error("failed to take snapshot", sl.Err(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("failed to take snapshot"))
+			return
+		}
+
+		log.Info("snapshot written", slog.String("name", snapshot.Name), slog.Int64("size", snapshot.Size))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Snapshot: &snapshot,
+		})
+	}
+}
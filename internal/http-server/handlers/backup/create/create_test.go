@@ -0,0 +1,78 @@
+package create_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/backup"
+	"url-shortener/internal/http-server/handlers/backup/create"
+	"url-shortener/internal/http-server/handlers/backup/create/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestCreateHandler(t *testing.T) {
+	snapshot := backup.Snapshot{
+		Name:      "storage-20240301T000000.000Z.db",
+		Path:      "/backups/storage-20240301T000000.000Z.db",
+		CreatedAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		Size:      4096,
+	}
+
+	cases := []struct {
+		name         string
+		respError    string
+		respCode     int
+		mockSnapshot backup.Snapshot
+		mockError    error
+		wantSnapshot *backup.Snapshot
+	}{
+		{
+			name:         "Success",
+			respCode:     http.StatusOK,
+			mockSnapshot: snapshot,
+			// The path on the server is not exposed.
+			wantSnapshot: &backup.Snapshot{Name: snapshot.Name, CreatedAt: snapshot.CreatedAt, Size: snapshot.Size},
+		},
+		{
+			name:      "Snapshot Error",
+			respError: "failed to take snapshot",
+			respCode:  http.StatusInternalServerError,
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			takerMock := mocks.NewSnapshotTaker(t)
+			takerMock.On("Snapshot", mock.Anything).
+				Return(tc.mockSnapshot, tc.mockError).
+				Once()
+
+			handler := create.New(slogdiscard.NewDiscardLogger(), takerMock)
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/backups", nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var body create.Response
+
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+
+			require.Equal(t, tc.respError, body.Error)
+			require.Equal(t, tc.wantSnapshot, body.Snapshot)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/linkdump"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+// LinkExporter is an interface for reading all links and the alias counter.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=LinkExporter
+type LinkExporter interface {
+	AliasCounter() (int64, error)
+	ListURLs(limit, offset int, ownerID int64) ([]storage.Link, error)
+}
+
+// @Summary      Export links
+// @Description  Выгружает все ссылки и счетчик алиасов в JSONL или CSV
+// @Produce      application/x-ndjson
+// @Produce      text/csv
+// @Security     BasicAuth
+// @Security     BearerAuth
+// @Param        format query string false "jsonl (по умолчанию) или csv"
+// @Success      200 {string} string "Dump"
+// @Failure      400 {object} resp.Response
+// @Failure      500 {object} resp.Response
+// @Router       /admin/export [get]
+func New(log *slog.Logger, exporter LinkExporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.dump.export.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		formatName := r.URL.Query().Get("format")
+		if formatName == "" {
+			formatName = string(linkdump.FormatJSONL)
+		}
+
+		format, err := linkdump.ParseFormat(formatName)
+		if err != nil {
+			log.Info("invalid format", slog.String("format", formatName))
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("format must be jsonl or csv"))
+			return
+		}
+
+		// A large dump takes longer than the server write timeout allows.
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="links.%s"`, format))
+
+		out := &startedWriter{w: w}
+		n, err := linkdump.Export(out, format, exporter, func(done int) {
+			log.Debug("export progress", slog.Int("links", done))
+		})
+		if err != nil && !out.started {
+			log.Error("failed to export links", sl.Err(err))
+			w.Header().Del("Content-Disposition")
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("internal error"))
+			return
+		}
+		if err != nil {
+			// The status is sent already, the client sees a truncated dump.
+			log.Error("export aborted", slog.Int("links", n), sl.Err(err))
+			return
+		}
+
+		log.Info("links exported", slog.Int("links", n))
+	}
+}
+
+// startedWriter records whether anything was written to the response.
+type startedWriter struct {
+	w       io.Writer
+	started bool
+}
+
+func (s *startedWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		s.started = true
+	}
+
+	return s.w.Write(p)
+}
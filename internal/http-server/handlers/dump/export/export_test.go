@@ -0,0 +1,109 @@
+package export_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/dump/export"
+	"url-shortener/internal/http-server/handlers/dump/export/mocks"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestExportHandler(t *testing.T) {
+	links := []storage.Link{
+		{Alias: "abc", URL: "https://google.com", CreatedAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	cases := []struct {
+		name         string
+		query        string
+		respCode     int
+		respError    string
+		contentType  string
+		wantBody     string
+		mockCounter  bool
+		counterError error
+	}{
+		{
+			name:        "JSONL",
+			respCode:    http.StatusOK,
+			contentType: "application/x-ndjson",
+			wantBody: `{"version":1,"alias_counter":7}
+{"alias":"abc","url":"https://google.com","created_at":"2024-03-01T00:00:00Z"}
+`,
+			mockCounter: true,
+		},
+		{
+			name:        "CSV",
+			query:       "?format=csv",
+			respCode:    http.StatusOK,
+			contentType: "text/csv",
+			wantBody: `# version=1 alias_counter=7
+alias,url,created_at,active_from,expires_at,redirect_code,owner_id,password_hash,max_clicks,used_clicks,target_ios,target_android,target_desktop,sticky_variants,variants
+abc,https://google.com,2024-03-01T00:00:00Z,,,,,,,,,,,,
+`,
+			mockCounter: true,
+		},
+		{
+			name:      "Unknown format",
+			query:     "?format=xml",
+			respCode:  http.StatusBadRequest,
+			respError: "format must be jsonl or csv",
+		},
+		{
+			name:         "Storage error",
+			respCode:     http.StatusInternalServerError,
+			respError:    "internal error",
+			mockCounter:  true,
+			counterError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			exporterMock := mocks.NewLinkExporter(t)
+			if tc.mockCounter {
+				exporterMock.On("AliasCounter").
+					Return(int64(7), tc.counterError).
+					Once()
+			}
+			if tc.mockCounter && tc.counterError == nil {
+				exporterMock.On("ListURLs", 500, 0, storage.AnyOwner).
+					Return(links, nil).
+					Once()
+			}
+
+			handler := export.New(slogdiscard.NewDiscardLogger(), exporterMock)
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/export"+tc.query, nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			if tc.respError != "" {
+				var body resp.Response
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+				require.Equal(t, tc.respError, body.Error)
+				return
+			}
+
+			require.Equal(t, tc.contentType, rr.Header().Get("Content-Type"))
+			require.True(t, strings.HasPrefix(rr.Header().Get("Content-Disposition"), "attachment"))
+			require.Equal(t, tc.wantBody, rr.Body.String())
+		})
+	}
+}
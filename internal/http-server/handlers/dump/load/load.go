@@ -0,0 +1,145 @@
+package load
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/linkdump"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+type Response struct {
+	resp.Response
+	storage.ImportStats
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// LinkImporter is an interface for saving the links of a dump.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=LinkImporter
+type LinkImporter interface {
+	GetLink(alias string) (storage.Link, error)
+	ImportLinks(links []storage.Link, onConflict storage.ConflictPolicy) (storage.ImportStats, error)
+	RaiseAliasCounter(counter int64) error
+}
+
+// @Summary      Import links
+// @Description  Загружает ссылки из выгрузки /admin/export. Формат берется из параметра
+// @Description  format или из Content-Type (text/csv), по умолчанию JSONL.
+// @Accept       application/x-ndjson
+// @Accept       text/csv
+// @Produce      json
+// @Security     BasicAuth
+// @Security     BearerAuth
+// @Param        format      query string false "jsonl или csv"
+// @Param        on_conflict query string false "skip, overwrite или fail (по умолчанию)"
+// @Param        dry_run     query bool   false "Только посчитать, ничего не сохраняя"
+// @Success      200 {object} Response
+// @Failure      400 {object} Response
+// @Failure      409 {object} Response
+// @Failure      500 {object} Response
+// @Router       /admin/import [post]
+func New(log *slog.Logger, importer LinkImporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.dump.load.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		query := r.URL.Query()
+
+		formatName := query.Get("format")
+		if formatName == "" {
+			formatName = string(linkdump.FormatJSONL)
+			if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "text/csv" {
+				formatName = string(linkdump.FormatCSV)
+			}
+		}
+
+		format, err := linkdump.ParseFormat(formatName)
+		if err != nil {
+			log.Info("invalid format", slog.String("format", formatName))
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("format must be jsonl or csv"))
+			return
+		}
+
+		policyName := query.Get("on_conflict")
+		if policyName == "" {
+			policyName = string(storage.ConflictFail)
+		}
+
+		policy, err := linkdump.ParseConflictPolicy(policyName)
+		if err != nil {
+			log.Info("invalid conflict policy", slog.String("on_conflict", policyName))
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("on_conflict must be skip, overwrite or fail"))
+			return
+		}
+
+		dryRun := false
+		if v := query.Get("dry_run"); v != "" {
+			dryRun, err = strconv.ParseBool(v)
+			if err != nil {
+				log.Info("invalid dry_run", slog.String("dry_run", v))
+				w.WriteHeader(http.StatusBadRequest)
+				render.JSON(w, r, resp.Error("dry_run must be true or false"))
+				return
+			}
+		}
+
+		// A large dump takes longer to upload than the server read timeout allows.
+		_ = http.NewResponseController(w).SetReadDeadline(time.Time{})
+
+		stats, err := linkdump.Import(r.Body, importer, linkdump.Options{
+			Format:     format,
+			OnConflict: policy,
+			DryRun:     dryRun,
+			Progress: func(done int) {
+				log.Info("import progress", slog.Int("links", done))
+			},
+		})
+		if errors.Is(err, linkdump.ErrInvalidDump) {
+			log.Info("invalid dump", sl.Err(err))
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("invalid dump"))
+			return
+		}
+		if errors.Is(err, storage.ErrURLExists) {
+			log.Info("alias already exists", sl.Err(err))
+			w.WriteHeader(http.StatusConflict)
+			render.JSON(w, r, resp.Error("dump has aliases that already exist, nothing was imported"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to import links", sl.Err(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("internal error"))
+			return
+		}
+
+		log.Info("links imported",
+			slog.Int("created", stats.Created),
+			slog.Int("overwritten", stats.Overwritten),
+			slog.Int("skipped", stats.Skipped),
+			slog.Bool("dry_run", dryRun),
+		)
+
+		render.JSON(w, r, Response{
+			Response:    resp.OK(),
+			ImportStats: stats,
+			DryRun:      dryRun,
+		})
+	}
+}
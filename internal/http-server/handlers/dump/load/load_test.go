@@ -0,0 +1,165 @@
+package load_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/dump/load"
+	"url-shortener/internal/http-server/handlers/dump/load/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+const (
+	jsonlDump = `{"version": 1, "alias_counter": 7}
+{"alias": "abc", "url": "https://google.com"}
+`
+	csvDump = `# version=1 alias_counter=7
+alias,url,created_at,active_from,expires_at,redirect_code,owner_id
+abc,https://google.com,,,,,
+`
+)
+
+func TestLoadHandler(t *testing.T) {
+	cases := []struct {
+		name        string
+		query       string
+		contentType string
+		body        string
+		respCode    int
+		respError   string
+		// policy is the conflict policy passed to ImportLinks, empty means
+		// ImportLinks is not called.
+		policy      storage.ConflictPolicy
+		mockStats   storage.ImportStats
+		mockError   error
+		dryRun      bool
+		wantStats   storage.ImportStats
+		wantCounter bool
+	}{
+		{
+			name:        "Default policy",
+			body:        jsonlDump,
+			respCode:    http.StatusOK,
+			policy:      storage.ConflictFail,
+			mockStats:   storage.ImportStats{Created: 1},
+			wantStats:   storage.ImportStats{Created: 1},
+			wantCounter: true,
+		},
+		{
+			name:        "CSV by content type",
+			contentType: "text/csv; charset=utf-8",
+			query:       "?on_conflict=overwrite",
+			body:        csvDump,
+			respCode:    http.StatusOK,
+			policy:      storage.ConflictOverwrite,
+			mockStats:   storage.ImportStats{Overwritten: 1},
+			wantStats:   storage.ImportStats{Overwritten: 1},
+			wantCounter: true,
+		},
+		{
+			name:      "Dry run",
+			query:     "?dry_run=true&on_conflict=skip",
+			body:      jsonlDump,
+			respCode:  http.StatusOK,
+			dryRun:    true,
+			wantStats: storage.ImportStats{Created: 1},
+		},
+		{
+			name:      "Conflict",
+			body:      jsonlDump,
+			respCode:  http.StatusConflict,
+			respError: "dump has aliases that already exist, nothing was imported",
+			policy:    storage.ConflictFail,
+			mockError: fmt.Errorf("alias %q: %w", "abc", storage.ErrURLExists),
+		},
+		{
+			name:      "Invalid dump",
+			body:      `{"alias": "abc"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "invalid dump",
+		},
+		{
+			name:      "Unknown format",
+			query:     "?format=xml",
+			body:      jsonlDump,
+			respCode:  http.StatusBadRequest,
+			respError: "format must be jsonl or csv",
+		},
+		{
+			name:      "Unknown policy",
+			query:     "?on_conflict=merge",
+			body:      jsonlDump,
+			respCode:  http.StatusBadRequest,
+			respError: "on_conflict must be skip, overwrite or fail",
+		},
+		{
+			name:      "Invalid dry run",
+			query:     "?dry_run=maybe",
+			body:      jsonlDump,
+			respCode:  http.StatusBadRequest,
+			respError: "dry_run must be true or false",
+		},
+		{
+			name:      "Storage error",
+			body:      jsonlDump,
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+			policy:    storage.ConflictFail,
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			importerMock := mocks.NewLinkImporter(t)
+			if tc.policy != "" {
+				importerMock.On("ImportLinks", mock.MatchedBy(func(links []storage.Link) bool {
+					return len(links) == 1 && links[0].Alias == "abc" && links[0].URL == "https://google.com"
+				}), tc.policy).
+					Return(tc.mockStats, tc.mockError).
+					Once()
+			}
+			if tc.dryRun {
+				importerMock.On("GetLink", "abc").
+					Return(storage.Link{}, storage.ErrURLNotFound).
+					Once()
+			}
+			if tc.wantCounter {
+				importerMock.On("RaiseAliasCounter", int64(7)).
+					Return(nil).
+					Once()
+			}
+
+			handler := load.New(slogdiscard.NewDiscardLogger(), importerMock)
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/import"+tc.query, strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp load.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.wantStats, resp.ImportStats)
+			require.Equal(t, tc.dryRun, resp.DryRun)
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package health
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+)
+
+const checkOK = "ok"
+
+type Response struct {
+	resp.Response
+	// Checks maps every readiness check to "ok" or the reason it failed.
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Check is one readiness condition, Func returns nil when it holds.
+type Check struct {
+	Name string
+	Func func() error
+}
+
+// Readiness reports whether the service should receive traffic. It fails as
+// soon as Drain is called, so load balancers stop routing to an instance
+// that is shutting down.
+type Readiness struct {
+	checks   []Check
+	draining atomic.Bool
+}
+
+func NewReadiness(checks ...Check) *Readiness {
+	return &Readiness{checks: checks}
+}
+
+// Drain makes every following readiness probe fail.
+func (rd *Readiness) Drain() {
+	rd.draining.Store(true)
+}
+
+// @Summary      Liveness probe
+// @Description  Отвечает 200, пока процесс жив
+// @Produce      json
+// @Success      200 {object} Response
+// @Router       /healthz [get]
+func NewLive() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, Response{Response: resp.OK()})
+	}
+}
+
+// Check runs all checks. It returns their results and whether the service is ready.
+func (rd *Readiness) Check() (map[string]string, bool) {
+	results := make(map[string]string, len(rd.checks))
+	ready := !rd.draining.Load()
+
+	for _, c := range rd.checks {
+		if err := c.Func(); err != nil {
+			results[c.Name] = err.Error()
+			ready = false
+			continue
+		}
+		results[c.Name] = checkOK
+	}
+
+	return results, ready
+}
+
+// Draining reports whether Drain was called.
+func (rd *Readiness) Draining() bool {
+	return rd.draining.Load()
+}
+
+// @Summary      Readiness probe
+// @Description  Проверяет хранилище, миграции и свободные алиасы
+// @Produce      json
+// @Success      200 {object} Response
+// @Failure      503 {object} Response
+// @Router       /readyz [get]
+func NewReady(log *slog.Logger, readiness *Readiness) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.health.NewReady"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		if readiness.Draining() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			render.JSON(w, r, Response{Response: resp.Error("shutting down")})
+			return
+		}
+
+		checks, ready := readiness.Check()
+		if !ready {
+			log.Warn("service is not ready", slog.Any("checks", checks))
+			w.WriteHeader(http.StatusServiceUnavailable)
+			render.JSON(w, r, Response{Response: resp.Error("not ready"), Checks: checks})
+			return
+		}
+
+		render.JSON(w, r, Response{Response: resp.OK(), Checks: checks})
+	}
+}
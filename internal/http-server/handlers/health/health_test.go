@@ -0,0 +1,80 @@
+package health_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/health"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestLiveHandler(t *testing.T) {
+	rr := httptest.NewRecorder()
+	health.NewLive().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestReadyHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		checkErr  error
+		drain     bool
+		respError string
+		respCode  int
+		checks    map[string]string
+	}{
+		{
+			name:     "Ready",
+			respCode: http.StatusOK,
+			checks:   map[string]string{"storage": "ok", "alias_space": "ok"},
+		},
+		{
+			name:      "Check fails",
+			checkErr:  errors.New("alias space exhausted"),
+			respError: "not ready",
+			respCode:  http.StatusServiceUnavailable,
+			checks:    map[string]string{"storage": "ok", "alias_space": "alias space exhausted"},
+		},
+		{
+			name:      "Draining",
+			drain:     true,
+			respError: "shutting down",
+			respCode:  http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			readiness := health.NewReadiness(
+				health.Check{Name: "storage", Func: func() error { return nil }},
+				health.Check{Name: "alias_space", Func: func() error { return tc.checkErr }},
+			)
+			if tc.drain {
+				readiness.Drain()
+			}
+
+			rr := httptest.NewRecorder()
+			health.NewReady(slogdiscard.NewDiscardLogger(), readiness).
+				ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp health.Response
+
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.checks, resp.Checks)
+		})
+	}
+}
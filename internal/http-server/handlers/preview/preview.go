@@ -0,0 +1,220 @@
+package preview
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var templates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+// pageMessages explain errors on the HTML page, JSON clients get the
+// usual error messages.
+var pageMessages = map[string]string{
+	"not found":      "This link does not exist.",
+	"link expired":   "This link has expired.",
+	"link used up":   "This link has been used up.",
+	"internal error": "This link cannot be shown right now, try again later.",
+}
+
+// contentSecurityPolicy allows the inline style of the templates and nothing else.
+const contentSecurityPolicy = "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'"
+
+// Preview is what a client learns about a link before following it. The
+// destination of a password protected link is left out.
+type Preview struct {
+	Alias     string    `json:"alias"`
+	ShortURL  string    `json:"short_url"`
+	URL       string    `json:"url,omitempty"`
+	Domain    string    `json:"domain,omitempty"`
+	Protected bool      `json:"protected,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Clicks    int       `json:"clicks"`
+}
+
+type Response struct {
+	resp.Response
+	Preview *Preview `json:"preview,omitempty"`
+}
+
+// LinkGetter is an interface for getting a link by alias.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=LinkGetter
+type LinkGetter interface {
+	GetLink(alias string) (storage.Link, error)
+}
+
+// ClickCounter is an interface for counting the clicks on an alias.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=ClickCounter
+type ClickCounter interface {
+	CountClicks(alias string) (int, error)
+}
+
+// ShortURL returns the full short URL of alias as requested through r.
+type ShortURL func(r *http.Request, alias string) string
+
+// New shows where the link saved under the alias leads instead of
+// redirecting. Browsers get an HTML page whose button follows the short
+// link, so the click is counted. Clients asking for application/json, or
+// requesting the preview with a .json suffix, get the same data as JSON.
+//
+// @Summary      Preview a short link
+// @Description  Показывает адрес назначения, домен, дату создания и число переходов вместо перенаправления
+// @Produce      html
+// @Produce      json
+// @Param        alias path string true "Short URL alias"
+// @Success      200 {object} Response
+// @Failure      404 {object} Response
+// @Failure      410 {object} Response "Link expired or used up"
+// @Failure      429 {object} Response "Rate limit exceeded"
+// @Failure      500 {object} Response
+// @Router       /{alias}/preview [get]
+// @Router       /{alias}+ [get]
+func New(log *slog.Logger, linkGetter LinkGetter, clickCounter ClickCounter, shortURL ShortURL) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.preview.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		asJSON := wantsJSON(r)
+		// Click counts change all the time.
+		w.Header().Set("Cache-Control", "no-store")
+
+		alias := chi.URLParam(r, "alias")
+
+		link, err := linkGetter.GetLink(alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			renderError(w, r, log, asJSON, http.StatusNotFound, "not found")
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url", sl.Err(err))
+			renderError(w, r, log, asJSON, http.StatusInternalServerError, "internal error")
+			return
+		}
+
+		now := time.Now()
+
+		// A link that is not active yet looks the same as an unknown one,
+		// as it does for redirects.
+		if link.Pending(now) {
+			log.Info("url is not active yet", slog.String("alias", alias))
+			renderError(w, r, log, asJSON, http.StatusNotFound, "not found")
+			return
+		}
+		if link.Expired(now) {
+			log.Info("url expired", slog.String("alias", alias))
+			renderError(w, r, log, asJSON, http.StatusGone, "link expired")
+			return
+		}
+		if link.UsedUp() {
+			log.Info("url used up", slog.String("alias", alias))
+			renderError(w, r, log, asJSON, http.StatusGone, "link used up")
+			return
+		}
+
+		clicks, err := clickCounter.CountClicks(alias)
+		if err != nil {
+			log.Error("failed to count clicks", sl.Err(err))
+			renderError(w, r, log, asJSON, http.StatusInternalServerError, "internal error")
+			return
+		}
+
+		preview := Preview{
+			Alias:     link.Alias,
+			ShortURL:  shortURL(r, link.Alias),
+			CreatedAt: link.CreatedAt,
+			Clicks:    clicks,
+		}
+		if link.Protected() {
+			preview.Protected = true
+		} else {
+			preview.URL = link.URL
+			preview.Domain = domain(link.URL)
+		}
+
+		if asJSON {
+			render.JSON(w, r, Response{
+				Response: resp.OK(),
+				Preview:  &preview,
+			})
+			return
+		}
+
+		renderHTML(w, r, log, http.StatusOK, "preview.html", preview)
+	}
+}
+
+// wantsJSON reports whether the client prefers JSON over an HTML page.
+func wantsJSON(r *http.Request) bool {
+	if format, _ := r.Context().Value(middleware.URLFormatCtxKey).(string); format == "json" {
+		return true
+	}
+
+	return render.GetAcceptedContentType(r) == render.ContentTypeJSON
+}
+
+// domain returns the host of rawURL without the port, or rawURL itself if
+// it cannot be parsed.
+func domain(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return rawURL
+	}
+
+	return u.Hostname()
+}
+
+func renderError(w http.ResponseWriter, r *http.Request, log *slog.Logger, asJSON bool, code int, msg string) {
+	if asJSON {
+		w.WriteHeader(code)
+		render.JSON(w, r, resp.Error(msg))
+		return
+	}
+
+	renderHTML(w, r, log, code, "error.html", struct {
+		Title   string
+		Message string
+	}{
+		Title:   http.StatusText(code),
+		Message: pageMessages[msg],
+	})
+}
+
+// renderHTML executes the template into a buffer first, so a failing
+// template still leads to a clean 500.
+func renderHTML(w http.ResponseWriter, r *http.Request, log *slog.Logger, code int, name string, data any) {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		log.Error("failed to render template", slog.String("template", name), sl.Err(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, resp.Error("internal error"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", contentSecurityPolicy)
+	w.WriteHeader(code)
+	_, _ = buf.WriteTo(w)
+}
@@ -0,0 +1,210 @@
+package preview_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/preview"
+	"url-shortener/internal/http-server/handlers/preview/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func shortURL(_ *http.Request, alias string) string {
+	return "https://sho.rt/" + alias
+}
+
+func TestPreviewHandler(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	link := storage.Link{Alias: "abc", URL: "https://www.example.com:8443/docs?q=1", CreatedAt: createdAt}
+	protected := link
+	protected.PasswordHash = "$2a$10$hash"
+
+	cases := []struct {
+		name      string
+		path      string
+		accept    string
+		respCode  int
+		respError string
+		// wantHTML lists strings the page must contain, nil means JSON.
+		wantHTML   []string
+		mockLink   storage.Link
+		mockError  error
+		mockCount  bool
+		countError error
+	}{
+		{
+			name:      "HTML",
+			path:      "/abc+",
+			accept:    "text/html,application/xhtml+xml,*/*;q=0.8",
+			respCode:  http.StatusOK,
+			wantHTML:  []string{"<h1>www.example.com</h1>", "https://www.example.com:8443/docs?q=1", "1 March 2024", "<dd>42</dd>", `href="https://sho.rt/abc"`},
+			mockLink:  link,
+			mockCount: true,
+		},
+		{
+			name:      "JSON by Accept",
+			path:      "/abc/preview",
+			accept:    "application/json",
+			respCode:  http.StatusOK,
+			mockLink:  link,
+			mockCount: true,
+		},
+		{
+			name:      "JSON by suffix",
+			path:      "/abc/preview.json",
+			respCode:  http.StatusOK,
+			mockLink:  link,
+			mockCount: true,
+		},
+		{
+			name:      "HTML is escaped",
+			path:      "/abc+",
+			respCode:  http.StatusOK,
+			wantHTML:  []string{"https://example.com/?q=&lt;script&gt;"},
+			mockLink:  storage.Link{Alias: "abc", URL: "https://example.com/?q=<script>", CreatedAt: createdAt},
+			mockCount: true,
+		},
+		{
+			name:      "Protected page",
+			path:      "/abc+",
+			respCode:  http.StatusOK,
+			wantHTML:  []string{"<h1>Password protected</h1>", `href="https://sho.rt/abc"`},
+			mockLink:  protected,
+			mockCount: true,
+		},
+		{
+			name:      "Protected JSON",
+			path:      "/abc/preview.json",
+			respCode:  http.StatusOK,
+			mockLink:  protected,
+			mockCount: true,
+		},
+		{
+			name:      "Not found page",
+			path:      "/abc+",
+			respCode:  http.StatusNotFound,
+			wantHTML:  []string{"This link does not exist."},
+			mockError: storage.ErrURLNotFound,
+		},
+		{
+			name:      "Not found JSON",
+			path:      "/abc/preview",
+			accept:    "application/json",
+			respCode:  http.StatusNotFound,
+			respError: "not found",
+			mockError: storage.ErrURLNotFound,
+		},
+		{
+			name:      "Not active yet",
+			path:      "/abc/preview",
+			accept:    "application/json",
+			respCode:  http.StatusNotFound,
+			respError: "not found",
+			mockLink:  storage.Link{Alias: "abc", LinkOptions: storage.LinkOptions{ActiveFrom: &future}},
+		},
+		{
+			name:     "Expired",
+			path:     "/abc+",
+			respCode: http.StatusGone,
+			wantHTML: []string{"This link has expired."},
+			mockLink: storage.Link{Alias: "abc", LinkOptions: storage.LinkOptions{ExpiresAt: &past}},
+		},
+		{
+			name:     "Used up",
+			path:     "/abc+",
+			respCode: http.StatusGone,
+			wantHTML: []string{"This link has been used up."},
+			mockLink: storage.Link{Alias: "abc", LinkOptions: storage.LinkOptions{MaxClicks: 1}, UsedClicks: 1},
+		},
+		{
+			name:       "Count error",
+			path:       "/abc/preview",
+			accept:     "application/json",
+			respCode:   http.StatusInternalServerError,
+			respError:  "internal error",
+			mockLink:   link,
+			mockCount:  true,
+			countError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			linkGetterMock := mocks.NewLinkGetter(t)
+			linkGetterMock.On("GetLink", "abc").
+				Return(tc.mockLink, tc.mockError).
+				Once()
+
+			clickCounterMock := mocks.NewClickCounter(t)
+			if tc.mockCount {
+				clickCounterMock.On("CountClicks", "abc").
+					Return(42, tc.countError).
+					Once()
+			}
+
+			handler := preview.New(slogdiscard.NewDiscardLogger(), linkGetterMock, clickCounterMock, shortURL)
+
+			r := chi.NewRouter()
+			r.Use(middleware.URLFormat)
+			r.Get("/{alias}+", handler)
+			r.Get("/{alias}/preview", handler)
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+			require.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+
+			if tc.wantHTML != nil {
+				require.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+				require.NotEmpty(t, rr.Header().Get("Content-Security-Policy"))
+				for _, want := range tc.wantHTML {
+					require.Contains(t, rr.Body.String(), want)
+				}
+				if tc.mockLink.Protected() {
+					require.NotContains(t, rr.Body.String(), "example.com")
+				}
+				return
+			}
+
+			var body preview.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			require.Equal(t, tc.respError, body.Error)
+
+			if tc.respError == "" {
+				want := &preview.Preview{
+					Alias:     "abc",
+					ShortURL:  "https://sho.rt/abc",
+					URL:       link.URL,
+					Domain:    "www.example.com",
+					CreatedAt: createdAt,
+					Clicks:    42,
+				}
+				if tc.mockLink.Protected() {
+					want.URL, want.Domain, want.Protected = "", "", true
+				}
+				require.Equal(t, want, body.Preview)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package qr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/qr"
+	"url-shortener/internal/storage"
+)
+
+// cacheControl lets clients and proxies keep codes for a day, the short URL
+// of an alias never changes.
+const cacheControl = "public, max-age=86400"
+
+// LinkGetter is an interface for getting a link by alias.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=LinkGetter
+type LinkGetter interface {
+	GetLink(alias string) (storage.Link, error)
+}
+
+// ShortURL returns the full short URL of alias as requested through r.
+type ShortURL func(r *http.Request, alias string) string
+
+// New renders a QR code of the short URL of the alias. Links that are not
+// active yet get a code too, so it can be printed ahead of time.
+//
+// @Summary      QR code of a short link
+// @Description  Возвращает QR-код полного короткого URL в формате PNG или SVG
+// @Produce      image/png
+// @Produce      image/svg+xml
+// @Param        alias  path   string  true   "Short URL alias"
+// @Param        format query  string  false  "png (default) or svg"
+// @Param        size   query  int     false  "Width and height in pixels, 64 to 2048, 256 by default"
+// @Param        ecc    query  string  false  "Error correction level: L, M (default), Q or H"
+// @Param        margin query  int     false  "Quiet zone in modules, 0 to 16, 4 by default"
+// @Success      200 {file} file
+// @Success      304 "Not Modified"
+// @Failure      400 {object} resp.Response
+// @Failure      404 {object} resp.Response
+// @Failure      410 {object} resp.Response "Link expired or used up"
+// @Failure      429 {object} resp.Response "Rate limit exceeded"
+// @Failure      500 {object} resp.Response
+// @Router       /{alias}/qr [get]
+func New(log *slog.Logger, linkGetter LinkGetter, shortURL ShortURL) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.qr.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		opts, err := parseOptions(r)
+		if err != nil {
+			log.Info("invalid options", sl.Err(err))
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		alias := chi.URLParam(r, "alias")
+
+		link, err := linkGetter.GetLink(alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, resp.Error("not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url", sl.Err(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("internal error"))
+			return
+		}
+		if link.Expired(time.Now()) {
+			log.Info("url expired", slog.String("alias", alias))
+			w.WriteHeader(http.StatusGone)
+			render.JSON(w, r, resp.Error("link expired"))
+			return
+		}
+		if link.UsedUp() {
+			log.Info("url used up", slog.String("alias", alias))
+			w.WriteHeader(http.StatusGone)
+			render.JSON(w, r, resp.Error("link used up"))
+			return
+		}
+
+		content := shortURL(r, alias)
+		tag := etag(content, opts)
+
+		w.Header().Set("ETag", tag)
+		w.Header().Set("Cache-Control", cacheControl)
+
+		if etagMatches(r.Header.Get("If-None-Match"), tag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := qr.Encode(&buf, content, opts); err != nil {
+			log.Error("failed to encode qr code", sl.Err(err))
+			w.Header().Del("ETag")
+			w.Header().Del("Cache-Control")
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("internal error"))
+			return
+		}
+
+		w.Header().Set("Content-Type", opts.Format.ContentType())
+		w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+		_, _ = buf.WriteTo(w)
+	}
+}
+
+// parseOptions reads the format, size, ecc and margin query parameters.
+func parseOptions(r *http.Request) (qr.Options, error) {
+	opts := qr.DefaultOptions
+	query := r.URL.Query()
+
+	if s := query.Get("format"); s != "" {
+		format, err := qr.ParseFormat(s)
+		if err != nil {
+			return opts, errors.New("format must be png or svg")
+		}
+		opts.Format = format
+	}
+
+	if s := query.Get("ecc"); s != "" {
+		level, err := qr.ParseLevel(s)
+		if err != nil {
+			return opts, errors.New("ecc must be L, M, Q or H")
+		}
+		opts.Level = level
+	}
+
+	if s := query.Get("size"); s != "" {
+		size, err := strconv.Atoi(s)
+		if err != nil {
+			return opts, qr.ErrInvalidSize
+		}
+		opts.Size = size
+	}
+
+	if s := query.Get("margin"); s != "" {
+		margin, err := strconv.Atoi(s)
+		if err != nil {
+			return opts, qr.ErrInvalidMargin
+		}
+		opts.Margin = margin
+	}
+
+	return opts, opts.Validate()
+}
+
+// etag identifies the image rendered for content with opts.
+func etag(content string, opts qr.Options) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%d\x00%s\x00%d", content, opts.Format, opts.Size, opts.Level, opts.Margin)))
+
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether the If-None-Match header lists tag.
+func etagMatches(header, tag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == tag || candidate == "*" {
+			return true
+		}
+	}
+
+	return false
+}
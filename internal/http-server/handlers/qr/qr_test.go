@@ -0,0 +1,188 @@
+package qr_test
+
+import (
+	"encoding/json"
+	"errors"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/qr"
+	"url-shortener/internal/http-server/handlers/qr/mocks"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func shortURL(_ *http.Request, alias string) string {
+	return "https://sho.rt/" + alias
+}
+
+func TestQRHandler(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	cases := []struct {
+		name        string
+		query       string
+		respCode    int
+		respError   string
+		contentType string
+		// mockLink is returned by GetLink, nil means GetLink is not called.
+		mockLink  *storage.Link
+		mockError error
+	}{
+		{
+			name:        "PNG",
+			respCode:    http.StatusOK,
+			contentType: "image/png",
+			mockLink:    &storage.Link{Alias: "abc"},
+		},
+		{
+			name:        "SVG",
+			query:       "?format=svg&size=512&ecc=h&margin=0",
+			respCode:    http.StatusOK,
+			contentType: "image/svg+xml",
+			mockLink:    &storage.Link{Alias: "abc"},
+		},
+		{
+			name:        "Not active yet",
+			respCode:    http.StatusOK,
+			contentType: "image/png",
+			mockLink:    &storage.Link{Alias: "abc", LinkOptions: storage.LinkOptions{ActiveFrom: &future}},
+		},
+		{
+			name:      "Expired",
+			respCode:  http.StatusGone,
+			respError: "link expired",
+			mockLink:  &storage.Link{Alias: "abc", LinkOptions: storage.LinkOptions{ExpiresAt: &past}},
+		},
+		{
+			name:      "Used up",
+			respCode:  http.StatusGone,
+			respError: "link used up",
+			mockLink:  &storage.Link{Alias: "abc", LinkOptions: storage.LinkOptions{MaxClicks: 1}, UsedClicks: 1},
+		},
+		{
+			name:      "Not found",
+			respCode:  http.StatusNotFound,
+			respError: "not found",
+			mockLink:  &storage.Link{},
+			mockError: storage.ErrURLNotFound,
+		},
+		{
+			name:      "Storage error",
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+			mockLink:  &storage.Link{},
+			mockError: errors.New("unexpected error"),
+		},
+		{
+			name:      "Unknown format",
+			query:     "?format=gif",
+			respCode:  http.StatusBadRequest,
+			respError: "format must be png or svg",
+		},
+		{
+			name:      "Unknown level",
+			query:     "?ecc=X",
+			respCode:  http.StatusBadRequest,
+			respError: "ecc must be L, M, Q or H",
+		},
+		{
+			name:      "Size too large",
+			query:     "?size=4096",
+			respCode:  http.StatusBadRequest,
+			respError: "size must be between 64 and 2048",
+		},
+		{
+			name:      "Invalid margin",
+			query:     "?margin=wide",
+			respCode:  http.StatusBadRequest,
+			respError: "margin must be between 0 and 16",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			linkGetterMock := mocks.NewLinkGetter(t)
+			if tc.mockLink != nil {
+				linkGetterMock.On("GetLink", "abc").
+					Return(*tc.mockLink, tc.mockError).
+					Once()
+			}
+
+			r := chi.NewRouter()
+			r.Get("/{alias}/qr", qr.New(slogdiscard.NewDiscardLogger(), linkGetterMock, shortURL))
+
+			req := httptest.NewRequest(http.MethodGet, "/abc/qr"+tc.query, nil)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			if tc.respError != "" {
+				var body resp.Response
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+				require.Equal(t, tc.respError, body.Error)
+				require.Empty(t, rr.Header().Get("ETag"))
+				return
+			}
+
+			require.Equal(t, tc.contentType, rr.Header().Get("Content-Type"))
+			require.NotEmpty(t, rr.Header().Get("ETag"))
+			require.Contains(t, rr.Header().Get("Cache-Control"), "public")
+
+			if tc.contentType == "image/png" {
+				img, err := png.Decode(rr.Body)
+				require.NoError(t, err)
+				require.Equal(t, 256, img.Bounds().Dx())
+			} else {
+				require.True(t, strings.HasPrefix(rr.Body.String(), `<svg xmlns="http://www.w3.org/2000/svg" width="512" height="512"`))
+			}
+		})
+	}
+}
+
+func TestQRHandler_ETag(t *testing.T) {
+	linkGetterMock := mocks.NewLinkGetter(t)
+	linkGetterMock.On("GetLink", "abc").Return(storage.Link{Alias: "abc"}, nil)
+
+	r := chi.NewRouter()
+	r.Get("/{alias}/qr", qr.New(slogdiscard.NewDiscardLogger(), linkGetterMock, shortURL))
+
+	get := func(query, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/abc/qr"+query, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	first := get("", "")
+	require.Equal(t, http.StatusOK, first.Code)
+	tag := first.Header().Get("ETag")
+
+	cached := get("", `"other", `+tag)
+	require.Equal(t, http.StatusNotModified, cached.Code)
+	require.Empty(t, cached.Body.Bytes())
+	require.Equal(t, tag, cached.Header().Get("ETag"))
+
+	// Other options render another image.
+	other := get("?size=512", tag)
+	require.Equal(t, http.StatusOK, other.Code)
+	require.NotEqual(t, tag, other.Header().Get("ETag"))
+}
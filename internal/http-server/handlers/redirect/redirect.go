@@ -1,24 +1,83 @@
 package redirect
 
 import (
+	"bytes"
+	"embed"
 	"errors"
+	"html/template"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"golang.org/x/exp/slog"
 
+	"url-shortener/internal/http-server/middleware/ratelimit"
+	"url-shortener/internal/lib/account"
 	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/linkpass"
 	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/platform"
+	"url-shortener/internal/lib/rotation"
 	"url-shortener/internal/storage"
 )
 
-// URLGetter is an interface for getting url by alias.
+//go:embed templates/password.html
+var templateFS embed.FS
+
+var passwordForm = template.Must(template.ParseFS(templateFS, "templates/password.html"))
+
+// contentSecurityPolicy allows the inline style of the form and posting it
+// back, nothing else.
+const contentSecurityPolicy = "default-src 'none'; style-src 'unsafe-inline'; form-action 'self'; frame-ancestors 'none'"
+
+// CookieName is the cookie proving the password of a link was entered. It is
+// scoped to the path of the link, so every protected link has its own.
+const CookieName = "link_password"
+
+// VariantCookieName remembers the variant a visitor of a link with sticky
+// variants was sent to, scoped to the path of the link like CookieName.
+const VariantCookieName = "link_variant"
+
+// variantCookieMaxAge is how long a visitor stays on their variant.
+const variantCookieMaxAge = 30 * 24 * time.Hour
+
+// maxFormSize bounds the body of a password form submission.
+const maxFormSize = 4 << 10
+
+// URLGetter is an interface for getting a link by alias.
 //
 //go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLGetter
 type URLGetter interface {
-	GetURL(alias string) (string, error)
+	GetLink(alias string) (storage.Link, error)
+}
+
+// ClickLimiter is an interface for counting redirects of links with a click limit.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=ClickLimiter
+type ClickLimiter interface {
+	UseClick(alias string) (int64, error)
+}
+
+// ClickRecorder is an interface for recording served redirects. variant is
+// the number of the variant served, 0 for links without variants.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=ClickRecorder
+type ClickRecorder interface {
+	RecordClick(r *http.Request, alias string, variant int)
+}
+
+// IsValidCode reports whether code can be used to redirect a link.
+func IsValidCode(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	default:
+		return false
+	}
 }
 
 type Response struct {
@@ -26,15 +85,64 @@ type Response struct {
 	Error  string `json:"error,omitempty"`
 }
 
+// Passwords configures links protected by a password.
+type Passwords struct {
+	// Signer issues the cookies that let visitors through without the form.
+	Signer *linkpass.Signer
+	// Attempts keeps the password attempts per alias. Every attempt counts,
+	// so guessing locks out everyone until the limit refills.
+	Attempts ratelimit.Store
+	Limit    ratelimit.Limit
+}
+
+// New redirects to the link saved under the alias. Links saved without a
+// redirect code use defaultCode. Permanent redirects may be cached by clients
+// for permanentMaxAge, temporary ones are never cached.
+//
+// Password protected links get an HTML form instead, which is posted back to
+// the same path. The correct password redirects and sets a cookie that skips
+// the form until it expires.
+//
+// Links with max_clicks answer 410 Gone once clickLimiter has counted all of
+// their redirects. Redirects of protected and limited links are never cached.
+//
+// Links with targets send clients to the target of their platform, told by
+// the User-Agent, and fall back to the link URL. Links with variants send
+// everyone else to a variant picked by weight and are never cached either.
+//
 // @Summary Redirect to original URL
-// @Description Перенаправляет пользователя на оригинальный URL по его короткому идентификатору
+// @Description Перенаправляет пользователя на оригинальный URL по его короткому идентификатору.
+// @Description Для ссылок с паролем показывает форму ввода пароля.
+// @Accept x-www-form-urlencoded
+// @Produce html
+// @Produce json
 // @Param alias path string true "Short URL alias"
-// @Success 200 "Successfully redirected"
+// @Param password formData string false "Password of a protected link, only posted"
+// @Success 200 "Password form of a protected link"
+// @Success 301 "Moved Permanently"
 // @Success 302 "Moved Temporarily"
+// @Success 307 "Temporary Redirect"
+// @Success 308 "Permanent Redirect"
+// @Success 303 "Password accepted"
+// @Failure 401 "Wrong password, the form is shown again"
 // @Failure 404 {object} Response
+// @Failure 405 {object} Response "Link is not password protected"
+// @Failure 410 {object} Response "Link expired or used up"
+// @Failure 429 {object} Response "Rate limit exceeded"
 // @Failure 500 {object} Response
 // @Router /{alias} [get]
-func New(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
+// @Router /{alias} [post]
+func New(
+	log *slog.Logger,
+	urlGetter URLGetter,
+	clickLimiter ClickLimiter,
+	clickRecorder ClickRecorder,
+	defaultCode int,
+	permanentMaxAge time.Duration,
+	passwords Passwords,
+) http.HandlerFunc {
+	permanentCacheControl := "public, max-age=" + strconv.Itoa(int(permanentMaxAge.Seconds()))
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.redirect.New"
 
@@ -52,10 +160,11 @@ func New(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
 			return
 		}
 
-		resURL, err := urlGetter.GetURL(alias)
+		link, err := urlGetter.GetLink(alias)
 		if errors.Is(err, storage.ErrURLNotFound) {
 			log.Info("url not found", "alias", alias)
 
+			w.WriteHeader(http.StatusNotFound)
 			render.JSON(w, r, resp.Error("not found"))
 
 			return
@@ -63,14 +172,273 @@ func New(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
 		if err != nil {
 			log.Error("failed to get url", sl.Err(err))
 
+			w.WriteHeader(http.StatusInternalServerError)
 			render.JSON(w, r, resp.Error("internal error"))
 
 			return
 		}
 
+		now := time.Now()
+
+		// A link that is not active yet looks the same as an unknown one.
+		if link.Pending(now) {
+			log.Info("url is not active yet", "alias", alias)
+
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, resp.Error("not found"))
+
+			return
+		}
+		if link.Expired(now) {
+			log.Info("url expired", "alias", alias)
+
+			w.WriteHeader(http.StatusGone)
+			render.JSON(w, r, resp.Error("link expired"))
+
+			return
+		}
+		if link.UsedUp() {
+			log.Info("url used up", "alias", alias)
+
+			w.WriteHeader(http.StatusGone)
+			render.JSON(w, r, resp.Error("link used up"))
+
+			return
+		}
+
+		if r.Method == http.MethodPost && !link.Protected() {
+			log.Info("password posted for unprotected url", "alias", alias)
+
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			render.JSON(w, r, resp.Error("link is not password protected"))
+
+			return
+		}
+
+		if link.Protected() && !passwords.unlocked(r, link, now) {
+			if r.Method != http.MethodPost {
+				renderForm(w, r, log, http.StatusOK, alias, "")
+				return
+			}
+
+			if !passwords.check(w, r, log, link, now) {
+				return
+			}
+		}
+
+		// The link may come from a cache that lags behind, the storage
+		// decides whether a click is left.
+		if link.MaxClicks > 0 {
+			left, err := clickLimiter.UseClick(alias)
+			if errors.Is(err, storage.ErrClicksExhausted) {
+				log.Info("url used up", "alias", alias)
+
+				w.WriteHeader(http.StatusGone)
+				render.JSON(w, r, resp.Error("link used up"))
+
+				return
+			}
+			if errors.Is(err, storage.ErrURLNotFound) {
+				log.Info("url not found", "alias", alias)
+
+				w.WriteHeader(http.StatusNotFound)
+				render.JSON(w, r, resp.Error("not found"))
+
+				return
+			}
+			if err != nil {
+				log.Error("failed to use click", sl.Err(err))
+
+				w.WriteHeader(http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error("internal error"))
+
+				return
+			}
+
+			log.Info("click used", slog.Int64("clicks_left", left))
+		}
+
+		var resURL string
+		if link.Targets != nil {
+			client := platform.Detect(r.UserAgent())
+			resURL = platformTarget(link.Targets, client)
+			// Shared caches must not hand one platform the redirect of another.
+			w.Header().Add("Vary", "User-Agent")
+
+			log.Info("platform detected", slog.String("platform", string(client)))
+		}
+
+		// A platform target wins over the rotation.
+		var variant int
+		if resURL == "" && len(link.Variants) > 0 {
+			variant = pickVariant(w, r, link)
+			resURL = link.Variants[variant-1].URL
+
+			log.Info("variant picked", slog.Int("variant", variant))
+		}
+		if resURL == "" {
+			resURL = link.URL
+		}
+
 		log.Info("got url", slog.String("url", resURL))
 
+		clickRecorder.RecordClick(r, alias, variant)
+
+		code := link.RedirectCode
+		if code == 0 {
+			code = defaultCode
+		}
+
+		switch {
+		case link.Protected(), link.MaxClicks > 0, len(link.Variants) > 0:
+			// A cached redirect would skip the password, the click count
+			// or the rotation.
+			code = temporary(code)
+			if r.Method == http.MethodPost {
+				code = http.StatusSeeOther
+			}
+			w.Header().Set("Cache-Control", "no-store")
+		case code == http.StatusMovedPermanently, code == http.StatusPermanentRedirect:
+			w.Header().Set("Cache-Control", permanentCacheControl)
+		default:
+			w.Header().Set("Cache-Control", "no-store")
+		}
+
 		// redirect to found url
-		http.Redirect(w, r, resURL, http.StatusFound)
+		http.Redirect(w, r, resURL, code)
 	}
 }
+
+// platformTarget returns the target for clients on p, empty if there is none.
+func platformTarget(targets *storage.Targets, p platform.Platform) string {
+	switch p {
+	case platform.IOS:
+		return targets.IOS
+	case platform.Android:
+		return targets.Android
+	case platform.Desktop:
+		return targets.Desktop
+	default:
+		return ""
+	}
+}
+
+// pickVariant returns the number of the variant r is sent to. Links with
+// sticky variants keep a visitor on the variant remembered in the cookie and
+// remember a newly picked one.
+func pickVariant(w http.ResponseWriter, r *http.Request, link storage.Link) int {
+	if !link.StickyVariants {
+		return rotation.Random(link.Variants)
+	}
+
+	// The variants may have changed since the cookie was set.
+	if cookie, err := r.Cookie(VariantCookieName); err == nil {
+		if n, err := strconv.Atoi(cookie.Value); err == nil && n >= 1 && n <= len(link.Variants) {
+			return n
+		}
+	}
+
+	variant := rotation.Random(link.Variants)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     VariantCookieName,
+		Value:    strconv.Itoa(variant),
+		Path:     "/" + link.Alias,
+		MaxAge:   int(variantCookieMaxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return variant
+}
+
+// unlocked reports whether r carries a valid cookie for link.
+func (p Passwords) unlocked(r *http.Request, link storage.Link, now time.Time) bool {
+	cookie, err := r.Cookie(CookieName)
+	if err != nil {
+		return false
+	}
+
+	return p.Signer.Valid(cookie.Value, link.Alias, link.PasswordHash, now)
+}
+
+// check verifies the posted password of link and sets the cookie when it is
+// correct. Otherwise it writes the form again and returns false.
+func (p Passwords) check(w http.ResponseWriter, r *http.Request, log *slog.Logger, link storage.Link, now time.Time) bool {
+	// Attempts are limited before the password is hashed, which is slow on purpose.
+	if p.Limit.Enabled() {
+		res, err := p.Attempts.Take("password:"+link.Alias, p.Limit, 1, now)
+		if err != nil {
+			log.Error("failed to take password attempt, letting it through", sl.Err(err))
+		} else if !res.Allowed {
+			log.Info("too many password attempts", "alias", link.Alias)
+
+			// Rounded up, so clients never retry early.
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(res.RetryAfter.Seconds()))))
+			renderForm(w, r, log, http.StatusTooManyRequests, link.Alias, "Too many attempts, try again later.")
+
+			return false
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
+	if !account.CheckPassword(link.PasswordHash, r.PostFormValue("password")) {
+		log.Info("wrong link password", "alias", link.Alias)
+
+		renderForm(w, r, log, http.StatusUnauthorized, link.Alias, "Wrong password.")
+
+		return false
+	}
+
+	token, expires := p.Signer.Token(link.Alias, link.PasswordHash, now)
+	http.SetCookie(w, &http.Cookie{
+		Name:     CookieName,
+		Value:    token,
+		Path:     "/" + link.Alias,
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return true
+}
+
+// temporary returns the temporary counterpart of a permanent redirect code.
+func temporary(code int) int {
+	switch code {
+	case http.StatusMovedPermanently:
+		return http.StatusFound
+	case http.StatusPermanentRedirect:
+		return http.StatusTemporaryRedirect
+	default:
+		return code
+	}
+}
+
+// renderForm executes the template into a buffer first, so a failing
+// template still leads to a clean 500.
+func renderForm(w http.ResponseWriter, r *http.Request, log *slog.Logger, code int, alias, msg string) {
+	var buf bytes.Buffer
+	err := passwordForm.Execute(&buf, struct {
+		Alias string
+		Error string
+	}{
+		Alias: alias,
+		Error: msg,
+	})
+	if err != nil {
+		log.Error("failed to render password form", sl.Err(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, resp.Error("internal error"))
+		return
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/html; charset=utf-8")
+	h.Set("Content-Security-Policy", contentSecurityPolicy)
+	h.Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_, _ = buf.WriteTo(w)
+}
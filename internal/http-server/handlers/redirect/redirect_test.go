@@ -1,54 +1,526 @@
 package redirect_test
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
 	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"url-shortener/internal/http-server/handlers/redirect"
 	"url-shortener/internal/http-server/handlers/redirect/mocks"
-	"url-shortener/internal/lib/api"
+	"url-shortener/internal/http-server/middleware/ratelimit"
+	"url-shortener/internal/lib/account"
+	"url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/linkpass"
 	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
 )
 
 func TestSaveHandler(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
 	cases := []struct {
-		name      string
-		alias     string
-		url       string
-		respError string
-		mockError error
+		name         string
+		alias        string
+		url          string
+		opts         storage.LinkOptions
+		respError    string
+		respCode     int
+		cacheControl string
+		mockError    error
+		usedClicks   int64
+		// useClick means UseClick is called and returns useClickError.
+		useClick      bool
+		useClickError error
 	}{
 		{
-			name:  "Success",
-			alias: "test_alias",
-			url:   "https://www.google.com/",
+			name:         "Success",
+			alias:        "test_alias",
+			url:          "https://www.google.com/",
+			respCode:     http.StatusFound,
+			cacheControl: "no-store",
+		},
+		{
+			name:         "Permanent",
+			alias:        "test_alias",
+			url:          "https://www.google.com/",
+			opts:         storage.LinkOptions{RedirectCode: http.StatusMovedPermanently},
+			respCode:     http.StatusMovedPermanently,
+			cacheControl: "public, max-age=3600",
+		},
+		{
+			name:         "Permanent keeps method",
+			alias:        "test_alias",
+			url:          "https://www.google.com/",
+			opts:         storage.LinkOptions{RedirectCode: http.StatusPermanentRedirect},
+			respCode:     http.StatusPermanentRedirect,
+			cacheControl: "public, max-age=3600",
+		},
+		{
+			name:         "Temporary keeps method",
+			alias:        "test_alias",
+			url:          "https://www.google.com/",
+			opts:         storage.LinkOptions{RedirectCode: http.StatusTemporaryRedirect},
+			respCode:     http.StatusTemporaryRedirect,
+			cacheControl: "no-store",
+		},
+		{
+			name:         "Within window",
+			alias:        "test_alias",
+			url:          "https://www.google.com/",
+			opts:         storage.LinkOptions{ActiveFrom: &past, ExpiresAt: &future},
+			respCode:     http.StatusFound,
+			cacheControl: "no-store",
+		},
+		{
+			name:         "Click limited",
+			alias:        "test_alias",
+			url:          "https://www.google.com/",
+			opts:         storage.LinkOptions{MaxClicks: 3, RedirectCode: http.StatusMovedPermanently},
+			usedClicks:   2,
+			useClick:     true,
+			respCode:     http.StatusFound,
+			cacheControl: "no-store",
+		},
+		{
+			name:       "Used up",
+			alias:      "test_alias",
+			url:        "https://www.google.com/",
+			opts:       storage.LinkOptions{MaxClicks: 3},
+			usedClicks: 3,
+			respError:  "link used up",
+			respCode:   http.StatusGone,
+		},
+		{
+			name:          "Used up by another request",
+			alias:         "test_alias",
+			url:           "https://www.google.com/",
+			opts:          storage.LinkOptions{MaxClicks: 1},
+			useClick:      true,
+			useClickError: storage.ErrClicksExhausted,
+			respError:     "link used up",
+			respCode:      http.StatusGone,
+		},
+		{
+			name:          "UseClick Error",
+			alias:         "test_alias",
+			url:           "https://www.google.com/",
+			opts:          storage.LinkOptions{MaxClicks: 1},
+			useClick:      true,
+			useClickError: errors.New("unexpected error"),
+			respError:     "internal error",
+			respCode:      http.StatusInternalServerError,
+		},
+		{
+			name:      "Not found",
+			alias:     "missing",
+			respError: "not found",
+			respCode:  http.StatusNotFound,
+			mockError: storage.ErrURLNotFound,
+		},
+		{
+			name:      "Not active yet",
+			alias:     "test_alias",
+			url:       "https://www.google.com/",
+			opts:      storage.LinkOptions{ActiveFrom: &future},
+			respError: "not found",
+			respCode:  http.StatusNotFound,
+		},
+		{
+			name:      "Expired",
+			alias:     "test_alias",
+			url:       "https://www.google.com/",
+			opts:      storage.LinkOptions{ExpiresAt: &past},
+			respError: "link expired",
+			respCode:  http.StatusGone,
+		},
+		{
+			name:      "GetLink Error",
+			alias:     "test_alias",
+			respError: "internal error",
+			respCode:  http.StatusInternalServerError,
+			mockError: errors.New("unexpected error"),
 		},
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
 			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("GetLink", tc.alias).
+				Return(storage.Link{Alias: tc.alias, URL: tc.url, LinkOptions: tc.opts, UsedClicks: tc.usedClicks}, tc.mockError).Once()
+
+			clickLimiterMock := mocks.NewClickLimiter(t)
+			if tc.useClick {
+				clickLimiterMock.On("UseClick", tc.alias).
+					Return(tc.opts.MaxClicks-tc.usedClicks-1, tc.useClickError).
+					Once()
+			}
 
-			if tc.respError == "" || tc.mockError != nil {
-				urlGetterMock.On("GetURL", tc.alias).
-					Return(tc.url, tc.mockError).Once()
+			clickRecorderMock := mocks.NewClickRecorder(t)
+			if tc.respError == "" {
+				clickRecorderMock.On("RecordClick", mock.Anything, tc.alias, 0).Once()
 			}
 
 			r := chi.NewRouter()
-			r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock))
+			r.Get("/{alias}", redirect.New(
+				slogdiscard.NewDiscardLogger(),
+				urlGetterMock,
+				clickLimiterMock,
+				clickRecorderMock,
+				http.StatusFound,
+				time.Hour,
+				redirect.Passwords{},
+			))
 
 			ts := httptest.NewServer(r)
 			defer ts.Close()
 
-			redirectedToURL, err := api.GetRedirect(ts.URL + "/" + tc.alias)
+			// Stop at the first response to check the redirect itself.
+			client := &http.Client{
+				CheckRedirect: func(*http.Request, []*http.Request) error {
+					return http.ErrUseLastResponse
+				},
+			}
+
+			res, err := client.Get(ts.URL + "/" + tc.alias)
 			require.NoError(t, err)
+			defer res.Body.Close()
+
+			require.Equal(t, tc.respCode, res.StatusCode)
+
+			if tc.respError == "" {
+				assert.Equal(t, tc.url, res.Header.Get("Location"))
+				assert.Equal(t, tc.cacheControl, res.Header.Get("Cache-Control"))
+
+				return
+			}
+
+			var resp response.Response
+
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
+
+func TestRedirectHandler_Targets(t *testing.T) {
+	const (
+		fallback = "https://example.com/app"
+		appStore = "https://apps.apple.com/app/id1"
+		playURL  = "https://play.google.com/store/apps/details?id=app"
+	)
+
+	targets := &storage.Targets{IOS: appStore, Android: playURL}
+
+	cases := []struct {
+		name      string
+		userAgent string
+		targets   *storage.Targets
+		location  string
+		vary      string
+	}{
+		{
+			name:      "iOS",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+			targets:   targets,
+			location:  appStore,
+			vary:      "User-Agent",
+		},
+		{
+			name:      "Android",
+			userAgent: "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.6312.40 Mobile Safari/537.36",
+			targets:   targets,
+			location:  playURL,
+			vary:      "User-Agent",
+		},
+		{
+			name:      "Desktop without target",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36",
+			targets:   targets,
+			location:  fallback,
+			vary:      "User-Agent",
+		},
+		{
+			name:      "Unknown client",
+			userAgent: "curl/8.5.0",
+			targets:   targets,
+			location:  fallback,
+			vary:      "User-Agent",
+		},
+		{
+			name:      "No targets",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+			location:  fallback,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("GetLink", "app").
+				Return(storage.Link{Alias: "app", URL: fallback, LinkOptions: storage.LinkOptions{Targets: tc.targets}}, nil).
+				Once()
+
+			clickRecorderMock := mocks.NewClickRecorder(t)
+			clickRecorderMock.On("RecordClick", mock.Anything, "app", 0).Once()
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(
+				slogdiscard.NewDiscardLogger(),
+				urlGetterMock,
+				mocks.NewClickLimiter(t),
+				clickRecorderMock,
+				http.StatusFound,
+				time.Hour,
+				redirect.Passwords{},
+			))
+
+			req := httptest.NewRequest(http.MethodGet, "/app", nil)
+			req.Header.Set("User-Agent", tc.userAgent)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
 
-			// Check the final URL after redirection.
-			assert.Equal(t, tc.url, redirectedToURL)
+			require.Equal(t, http.StatusFound, rr.Code)
+			assert.Equal(t, tc.location, rr.Header().Get("Location"))
+			assert.Equal(t, tc.vary, rr.Header().Get("Vary"))
 		})
 	}
 }
+
+func TestRedirectHandler_Variants(t *testing.T) {
+	const (
+		first   = "https://example.com/a"
+		second  = "https://example.com/b"
+		iPhone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1"
+		appLink = "https://apps.apple.com/app/id1"
+	)
+
+	// The first variant is never picked at random, so only a cookie sends
+	// visitors there.
+	variants := []storage.Variant{{URL: first, Weight: 0}, {URL: second, Weight: 1}}
+
+	cases := []struct {
+		name      string
+		opts      storage.LinkOptions
+		userAgent string
+		cookie    string
+		location  string
+		variant   int
+		// setCookie is the variant remembered in the response, if any.
+		setCookie string
+	}{
+		{
+			name:     "Weighted",
+			opts:     storage.LinkOptions{Variants: variants, RedirectCode: http.StatusMovedPermanently},
+			location: second,
+			variant:  2,
+		},
+		{
+			name:     "Cookie ignored without sticky",
+			opts:     storage.LinkOptions{Variants: variants},
+			cookie:   "1",
+			location: second,
+			variant:  2,
+		},
+		{
+			name:      "Sticky first visit",
+			opts:      storage.LinkOptions{Variants: variants, StickyVariants: true},
+			location:  second,
+			variant:   2,
+			setCookie: "2",
+		},
+		{
+			name:     "Sticky returning visitor",
+			opts:     storage.LinkOptions{Variants: variants, StickyVariants: true},
+			cookie:   "1",
+			location: first,
+			variant:  1,
+		},
+		{
+			name:      "Sticky stale cookie",
+			opts:      storage.LinkOptions{Variants: variants, StickyVariants: true},
+			cookie:    "5",
+			location:  second,
+			variant:   2,
+			setCookie: "2",
+		},
+		{
+			name:      "Platform target wins",
+			opts:      storage.LinkOptions{Variants: variants, Targets: &storage.Targets{IOS: appLink}},
+			userAgent: iPhone,
+			location:  appLink,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("GetLink", "ab").
+				Return(storage.Link{Alias: "ab", URL: first, LinkOptions: tc.opts}, nil).
+				Once()
+
+			clickRecorderMock := mocks.NewClickRecorder(t)
+			clickRecorderMock.On("RecordClick", mock.Anything, "ab", tc.variant).Once()
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(
+				slogdiscard.NewDiscardLogger(),
+				urlGetterMock,
+				mocks.NewClickLimiter(t),
+				clickRecorderMock,
+				http.StatusFound,
+				time.Hour,
+				redirect.Passwords{},
+			))
+
+			req := httptest.NewRequest(http.MethodGet, "/ab", nil)
+			req.Header.Set("User-Agent", tc.userAgent)
+			if tc.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: redirect.VariantCookieName, Value: tc.cookie})
+			}
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			// Rotating links are never cached, not even permanent ones.
+			require.Equal(t, http.StatusFound, rr.Code)
+			assert.Equal(t, tc.location, rr.Header().Get("Location"))
+			assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+
+			cookies := rr.Result().Cookies()
+			if tc.setCookie == "" {
+				assert.Empty(t, cookies)
+				return
+			}
+
+			require.Len(t, cookies, 1)
+			assert.Equal(t, redirect.VariantCookieName, cookies[0].Name)
+			assert.Equal(t, tc.setCookie, cookies[0].Value)
+			assert.Equal(t, "/ab", cookies[0].Path)
+			assert.True(t, cookies[0].HttpOnly)
+		})
+	}
+}
+
+func TestRedirectHandler_Password(t *testing.T) {
+	hash, err := account.HashPassword("correct horse")
+	require.NoError(t, err)
+
+	link := storage.Link{
+		Alias:       "secret",
+		URL:         "https://www.google.com/",
+		LinkOptions: storage.LinkOptions{RedirectCode: http.StatusMovedPermanently, PasswordHash: hash},
+	}
+
+	signer, err := linkpass.New("cookie secret", time.Hour)
+	require.NoError(t, err)
+
+	urlGetterMock := mocks.NewURLGetter(t)
+	urlGetterMock.On("GetLink", "secret").Return(link, nil)
+	urlGetterMock.On("GetLink", "open").Return(storage.Link{Alias: "open", URL: "https://go.dev/"}, nil)
+
+	clickRecorderMock := mocks.NewClickRecorder(t)
+	clickRecorderMock.On("RecordClick", mock.Anything, "secret", 0).Twice()
+
+	handler := redirect.New(
+		slogdiscard.NewDiscardLogger(),
+		urlGetterMock,
+		mocks.NewClickLimiter(t),
+		clickRecorderMock,
+		http.StatusFound,
+		time.Hour,
+		redirect.Passwords{
+			Signer:   signer,
+			Attempts: ratelimit.NewMemoryStore(),
+			Limit:    ratelimit.Limit{Requests: 2, Period: time.Hour},
+		},
+	)
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", handler)
+	r.Post("/{alias}", handler)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+
+	client := &http.Client{
+		Jar: jar,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	get := func() *http.Response {
+		res, err := client.Get(ts.URL + "/secret")
+		require.NoError(t, err)
+		res.Body.Close()
+
+		return res
+	}
+	// stranger has no cookies.
+	stranger := &http.Client{CheckRedirect: client.CheckRedirect}
+
+	post := func(client *http.Client, alias, password string) (*http.Response, string) {
+		res, err := client.PostForm(ts.URL+"/"+alias, url.Values{"password": {password}})
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+
+		return res, string(body)
+	}
+
+	// The form is shown instead of the redirect.
+	res, err := client.Get(ts.URL + "/secret")
+	require.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "text/html; charset=utf-8", res.Header.Get("Content-Type"))
+	require.Equal(t, "no-store", res.Header.Get("Cache-Control"))
+	require.NotEmpty(t, res.Header.Get("Content-Security-Policy"))
+	require.Contains(t, string(body), `<form method="post">`)
+	require.Empty(t, res.Header.Get("Location"))
+
+	res, body2 := post(client, "secret", "wrong password")
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	require.Contains(t, body2, "Wrong password.")
+	require.Empty(t, res.Cookies())
+
+	res, _ = post(client, "secret", "correct horse")
+	require.Equal(t, http.StatusSeeOther, res.StatusCode)
+	require.Equal(t, link.URL, res.Header.Get("Location"))
+	require.Equal(t, "no-store", res.Header.Get("Cache-Control"))
+	require.Len(t, res.Cookies(), 1)
+	require.Equal(t, redirect.CookieName, res.Cookies()[0].Name)
+
+	// The cookie skips the form, the permanent redirect is served as a temporary one.
+	res = get()
+	require.Equal(t, http.StatusFound, res.StatusCode)
+	require.Equal(t, link.URL, res.Header.Get("Location"))
+	require.Equal(t, "no-store", res.Header.Get("Cache-Control"))
+
+	// Both attempts of the hour are used up, even the correct password waits.
+	res, body2 = post(stranger, "secret", "correct horse")
+	require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	require.NotEmpty(t, res.Header.Get("Retry-After"))
+	require.Contains(t, body2, "Too many attempts")
+
+	res, _ = post(stranger, "open", "anything")
+	require.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+}
@@ -0,0 +1,543 @@
+package batch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"url-shortener/internal/http-server/handlers/url/save"
+	"url-shortener/internal/http-server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/exp/slog"
+)
+
+// ContentTypeNDJSON selects the newline delimited variant: one request per
+// line in, one result per line out.
+const ContentTypeNDJSON = "application/x-ndjson"
+
+// chunkSize is the number of NDJSON entries saved in one transaction. The
+// results of a chunk are sent before the next one is read.
+const chunkSize = 100
+
+// Result is the outcome of one entry of the batch, Index is its position in the request.
+type Result struct {
+	Index  int    `json:"index"`
+	Status string `json:"status"`
+	Alias  string `json:"alias,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type Response struct {
+	resp.Response
+	Results []Result `json:"results,omitempty"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLBatchSaver
+type URLBatchSaver interface {
+	SaveURLBatch(links []storage.NewLink) ([]storage.BatchResult, error)
+}
+
+// Quota charges the links of a batch against the caller's rate limit. It
+// writes the response and returns false when the caller is over the limit.
+type Quota func(w http.ResponseWriter, r *http.Request, links int) bool
+
+// errTooLarge is returned by the decoders once more than maxSize entries were read.
+var errTooLarge = errors.New("batch too large")
+
+// @Summary      Создать несколько сокращенных URL
+// @Description  Принимает JSON массив или NDJSON (application/x-ndjson) с запросами
+// @Description  как у POST /url. Массив сохраняется в одной транзакции. NDJSON
+// @Description  сохраняется частями по 100 записей, результаты каждой части
+// @Description  отправляются, пока читается остальной запрос. Если ошибка
+// @Description  случилась после начала ответа, она приходит последней строкой,
+// @Description  сохраненные до нее части остаются. Результаты и ошибки
+// @Description  возвращаются для каждой записи в порядке запроса.
+// @Accept       json
+// @Accept       application/x-ndjson
+// @Produce      json
+// @Produce      application/x-ndjson
+// @Security     BasicAuth
+// @Security     BearerAuth
+// @Param        request body []save.Request true "URL для сокращения"
+// @Success      200 {object} Response
+// @Failure      400 {object} Response
+// @Failure      413 {object} Response
+// @Failure      429 {object} Response "Rate limit exceeded, every entry costs one request"
+// @Failure      500 {object} Response
+// @Router       /url/batch [post]
+func New(
+	log *slog.Logger,
+	saver URLBatchSaver,
+	aliasValidator save.AliasValidator,
+	aliasAllowed save.AliasPermission,
+	quota Quota,
+	maxSize int,
+	maxBodySize int64,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.batch.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		body := http.MaxBytesReader(w, r.Body, maxBodySize)
+
+		l := &linker{
+			validate:       validator.New(),
+			now:            time.Now(),
+			ownerID:        auth.CreatorID(r.Context()),
+			customAllowed:  aliasAllowed(r),
+			aliasValidator: aliasValidator,
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType == ContentTypeNDJSON {
+			s := &stream{
+				log:         log,
+				w:           w,
+				r:           r,
+				saver:       saver,
+				quota:       quota,
+				maxSize:     maxSize,
+				maxBodySize: maxBodySize,
+			}
+			s.run(body, l)
+			return
+		}
+
+		entries, err := decodeArray(body, maxSize)
+		if err != nil {
+			writeDecodeError(log, w, r, err, maxSize, maxBodySize)
+			return
+		}
+		if len(entries) == 0 {
+			log.Info("empty batch")
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("empty request"))
+			return
+		}
+
+		log.Info("batch decoded", slog.Int("entries", len(entries)))
+
+		if !quota(w, r, len(entries)) {
+			return
+		}
+
+		var c chunk
+		for i, entry := range entries {
+			l.add(&c, i, entry)
+		}
+
+		if err := c.save(saver); err != nil {
+			log.Error("failed to add urls", sl.Err(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("failed to add urls"))
+			return
+		}
+
+		failed := c.failed()
+		log.Info("batch added", slog.Int("saved", len(c.results)-failed), slog.Int("failed", failed))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Results:  c.results,
+		})
+	}
+}
+
+// writeDecodeError writes the response for a body that could not be decoded.
+func writeDecodeError(log *slog.Logger, w http.ResponseWriter, r *http.Request, err error, maxSize int, maxBodySize int64) {
+	if errors.Is(err, errTooLarge) {
+		log.Info("batch too large", slog.Int("max_size", maxSize))
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		render.JSON(w, r, resp.Error(tooLargeMessage(maxSize)))
+		return
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		log.Info("batch body too large", slog.Int64("max_body_size", maxBodySize))
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		render.JSON(w, r, resp.Error(bodyTooLargeMessage(maxBodySize)))
+		return
+	}
+
+	log.Error("failed to decode request body", sl.Err(err))
+	w.WriteHeader(http.StatusBadRequest)
+	render.JSON(w, r, resp.Error("failed to decode request"))
+}
+
+func tooLargeMessage(maxSize int) string {
+	return fmt.Sprintf("batch must not have more than %d entries", maxSize)
+}
+
+func bodyTooLargeMessage(maxBodySize int64) string {
+	return fmt.Sprintf("request body must not be larger than %d bytes", maxBodySize)
+}
+
+// linker turns the entries of one request into links.
+type linker struct {
+	validate       *validator.Validate
+	now            time.Time
+	ownerID        int64
+	customAllowed  bool
+	aliasValidator save.AliasValidator
+}
+
+// add validates entry index and adds it to c. Invalid entries only get a
+// result carrying the error.
+func (l *linker) add(c *chunk, index int, entry json.RawMessage) {
+	res := Result{Index: index}
+
+	var req save.Request
+	if err := json.Unmarshal(entry, &req); err != nil {
+		res.Error = "failed to decode entry"
+		c.results = append(c.results, res)
+		return
+	}
+
+	if msg := validateEntry(l.validate, req, l.now, l.customAllowed, l.aliasValidator); msg != "" {
+		res.Alias = req.Alias
+		res.Error = msg
+		c.results = append(c.results, res)
+		return
+	}
+
+	c.links = append(c.links, storage.NewLink{
+		URL:   req.URL,
+		Alias: req.Alias,
+		LinkOptions: storage.LinkOptions{
+			ActiveFrom:     req.ActiveFrom,
+			ExpiresAt:      req.ExpiresAt,
+			RedirectCode:   req.RedirectCode,
+			OwnerID:        l.ownerID,
+			MaxClicks:      req.MaxClicks,
+			Targets:        req.Targets.LinkTargets(),
+			Variants:       save.LinkVariants(req.Variants),
+			StickyVariants: req.StickyVariants,
+		},
+	})
+	c.positions = append(c.positions, len(c.results))
+	c.results = append(c.results, res)
+}
+
+// chunk collects entries that are saved in one transaction.
+type chunk struct {
+	results []Result
+	links   []storage.NewLink
+	// positions maps the links passed to the storage back to their results.
+	positions []int
+}
+
+// save saves the valid links of the chunk and sets the status of every result.
+func (c *chunk) save(saver URLBatchSaver) error {
+	if len(c.links) > 0 {
+		saved, err := saver.SaveURLBatch(c.links)
+		if err != nil {
+			return err
+		}
+
+		for j, res := range saved {
+			result := &c.results[c.positions[j]]
+			result.Alias = res.Alias
+
+			switch {
+			case errors.Is(res.Err, storage.ErrURLExists):
+				result.Error = fmt.Sprintf("url with alias: %s already exists", res.Alias)
+			case errors.Is(res.Err, storage.ErrAliasSpaceExhausted):
+				result.Error = "no free aliases left"
+			case res.Err != nil:
+				result.Error = "failed to add url"
+			}
+		}
+	}
+
+	for i := range c.results {
+		c.results[i].Status = resp.StatusOK
+		if c.results[i].Error != "" {
+			c.results[i].Status = resp.StatusError
+		}
+	}
+
+	return nil
+}
+
+// fail marks every entry of the chunk as failed with msg.
+func (c *chunk) fail(msg string) {
+	for i := range c.results {
+		c.results[i].Status = resp.StatusError
+		c.results[i].Error = msg
+	}
+}
+
+func (c *chunk) failed() int {
+	n := 0
+	for _, res := range c.results {
+		if res.Status == resp.StatusError {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (c *chunk) reset() {
+	c.results = c.results[:0]
+	c.links = c.links[:0]
+	c.positions = c.positions[:0]
+}
+
+// stream handles an NDJSON batch. Entries are saved chunk by chunk and the
+// results of each chunk are written and flushed before the next one is read.
+// Until the first chunk is written errors get the usual responses, after
+// that the status is sent and an error ends the stream as its last line.
+type stream struct {
+	log         *slog.Logger
+	w           http.ResponseWriter
+	r           *http.Request
+	saver       URLBatchSaver
+	quota       Quota
+	maxSize     int
+	maxBodySize int64
+
+	rc      *http.ResponseController
+	enc     *json.Encoder
+	started bool
+	saved   int
+	failed  int
+}
+
+func (s *stream) run(body io.Reader, l *linker) {
+	s.rc = http.NewResponseController(s.w)
+	// HTTP/1 servers stop reading the body once the response is written
+	// unless told otherwise. Other writers read it in full anyway.
+	_ = s.rc.EnableFullDuplex()
+
+	dec := json.NewDecoder(body)
+
+	var c chunk
+	index := 0
+	for {
+		var entry json.RawMessage
+		err := dec.Decode(&entry)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err == nil && index == s.maxSize {
+			err = errTooLarge
+		}
+		if err != nil {
+			// The entries of the unfinished chunk are dropped, so only
+			// the chunks already reported are saved.
+			s.abort(index, err)
+			return
+		}
+
+		l.add(&c, index, entry)
+		index++
+
+		if len(c.results) == chunkSize {
+			if !s.flush(&c) {
+				return
+			}
+			c.reset()
+		}
+	}
+
+	if index == 0 {
+		s.log.Info("empty batch")
+		s.w.WriteHeader(http.StatusBadRequest)
+		render.JSON(s.w, s.r, resp.Error("empty request"))
+		return
+	}
+
+	if len(c.results) > 0 && !s.flush(&c) {
+		return
+	}
+
+	s.log.Info("batch added", slog.Int("entries", index), slog.Int("saved", s.saved), slog.Int("failed", s.failed))
+}
+
+// flush charges and saves a chunk and writes its results. It returns false
+// when the stream has ended.
+func (s *stream) flush(c *chunk) bool {
+	w := s.w
+	if s.started {
+		// A quota rejecting a later chunk must not write into the stream.
+		w = detached{header: http.Header{}}
+	}
+	if !s.quota(w, s.r, len(c.results)) {
+		if s.started {
+			c.fail("rate limit exceeded")
+			s.write(c.results)
+		}
+		return false
+	}
+
+	if err := c.save(s.saver); err != nil {
+		s.log.Error("failed to add urls", sl.Err(err))
+		if !s.started {
+			s.w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(s.w, s.r, resp.Error("failed to add urls"))
+			return false
+		}
+
+		c.fail("failed to add urls")
+		s.write(c.results)
+		return false
+	}
+
+	failed := c.failed()
+	s.saved += len(c.results) - failed
+	s.failed += failed
+
+	return s.write(c.results)
+}
+
+// abort ends the stream after the body failed to decode at entry index.
+func (s *stream) abort(index int, err error) {
+	if !s.started {
+		writeDecodeError(s.log, s.w, s.r, err, s.maxSize, s.maxBodySize)
+		return
+	}
+
+	res := Result{Index: index, Status: resp.StatusError}
+
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, errTooLarge):
+		s.log.Info("batch too large", slog.Int("max_size", s.maxSize))
+		res.Error = tooLargeMessage(s.maxSize)
+	case errors.As(err, &maxBytesErr):
+		s.log.Info("batch body too large", slog.Int64("max_body_size", s.maxBodySize))
+		res.Error = bodyTooLargeMessage(s.maxBodySize)
+	default:
+		s.log.Error("failed to decode request body", sl.Err(err))
+		res.Error = "failed to decode entry"
+	}
+
+	s.write([]Result{res})
+}
+
+// write writes one result per line and flushes them to the client.
+func (s *stream) write(results []Result) bool {
+	if !s.started {
+		s.w.Header().Set("Content-Type", ContentTypeNDJSON)
+		s.enc = json.NewEncoder(s.w)
+		s.started = true
+	}
+
+	for _, res := range results {
+		if err := s.enc.Encode(res); err != nil {
+			s.log.Info("client went away", sl.Err(err))
+			return false
+		}
+	}
+
+	// Writers that cannot flush send the results with the response.
+	_ = s.rc.Flush()
+
+	return true
+}
+
+// detached stands in for the response writer once the stream has started.
+type detached struct {
+	header http.Header
+}
+
+func (d detached) Header() http.Header { return d.header }
+
+func (detached) Write(p []byte) (int, error) { return len(p), nil }
+
+func (detached) WriteHeader(int) {}
+
+// validateEntry checks one entry the same way the single save handler does
+// and returns a client error message, or an empty string if it is valid.
+func validateEntry(
+	validate *validator.Validate,
+	req save.Request,
+	now time.Time,
+	customAllowed bool,
+	aliasValidator save.AliasValidator,
+) string {
+	if err := validate.Struct(req); err != nil {
+		var validateErr validator.ValidationErrors
+		if errors.As(err, &validateErr) {
+			return resp.ValidationError(validateErr).Error
+		}
+
+		return "invalid entry"
+	}
+
+	if msg := save.ValidateWindow(req, now); msg != "" {
+		return msg
+	}
+
+	// Hashing a password takes long enough that a full batch would run
+	// into the write timeout.
+	if req.Password != "" {
+		return "password protected links must be created one by one"
+	}
+
+	if req.Alias == "" {
+		return ""
+	}
+
+	if !customAllowed {
+		return "custom aliases are not allowed"
+	}
+
+	if err := aliasValidator.Validate(req.Alias); err != nil {
+		return fmt.Sprintf("invalid alias: %s", err)
+	}
+
+	return ""
+}
+
+// decodeArray reads a JSON array entry by entry, so an oversized batch is
+// rejected without reading all of it.
+func decodeArray(body io.Reader, maxSize int) ([]json.RawMessage, error) {
+	dec := json.NewDecoder(body)
+
+	tok, err := dec.Token()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return nil, errors.New("request body is not a JSON array")
+	}
+
+	var entries []json.RawMessage
+	for dec.More() {
+		if len(entries) == maxSize {
+			return nil, errTooLarge
+		}
+
+		var entry json.RawMessage
+		if err := dec.Decode(&entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,488 @@
+package batch_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/render"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/batch"
+	"url-shortener/internal/http-server/handlers/url/batch/mocks"
+	"url-shortener/internal/http-server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	customalias "url-shortener/internal/lib/custom_alias"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestBatchHandler(t *testing.T) {
+	cases := []struct {
+		name        string
+		body        string
+		contentType string
+		aliasDenied bool
+		maxSize     int
+		maxBodySize int64
+		// quotaLeft is how many links the caller may still create, zero means no limit.
+		quotaLeft   int
+		ownerID     int64
+		saved       []storage.NewLink
+		mockResults []storage.BatchResult
+		mockError   error
+		respCode    int
+		respError   string
+		wantResults []batch.Result
+		wantNDJSON  bool
+	}{
+		{
+			name: "Generated and custom aliases",
+			body: `[{"url": "https://google.com"}, {"url": "https://go.dev", "alias": "golang", "redirect_code": 308}]`,
+			saved: []storage.NewLink{
+				{URL: "https://google.com"},
+				{URL: "https://go.dev", Alias: "golang", LinkOptions: storage.LinkOptions{RedirectCode: 308}},
+			},
+			mockResults: []storage.BatchResult{{Alias: "a", ID: 1}, {Alias: "golang", ID: 2}},
+			wantResults: []batch.Result{
+				{Index: 0, Status: "OK", Alias: "a"},
+				{Index: 1, Status: "OK", Alias: "golang"},
+			},
+		},
+		{
+			name: "Per-entry errors keep input order",
+			body: `[
+				{"url": "not a url"},
+				{"url": "https://google.com", "alias": "swagger"},
+				{"url": "https://go.dev", "alias": "taken"},
+				"nonsense",
+				{"url": "https://pkg.go.dev", "expires_at": "2020-01-01T00:00:00Z"},
+				{"url": "https://google.de"}
+			]`,
+			saved: []storage.NewLink{
+				{URL: "https://go.dev", Alias: "taken"},
+				{URL: "https://google.de"},
+			},
+			mockResults: []storage.BatchResult{
+				{Alias: "taken", Err: storage.ErrURLExists},
+				{Err: storage.ErrAliasSpaceExhausted},
+			},
+			wantResults: []batch.Result{
+				{Index: 0, Status: "Error", Error: "field URL is not a valid URL"},
+				{Index: 1, Status: "Error", Alias: "swagger", Error: "invalid alias: alias is reserved"},
+				{Index: 2, Status: "Error", Alias: "taken", Error: "url with alias: taken already exists"},
+				{Index: 3, Status: "Error", Error: "failed to decode entry"},
+				{Index: 4, Status: "Error", Error: "expires_at must be in the future"},
+				{Index: 5, Status: "Error", Error: "no free aliases left"},
+			},
+		},
+		{
+			name:        "Custom alias not allowed",
+			body:        `[{"url": "https://go.dev", "alias": "golang"}, {"url": "https://google.com"}]`,
+			aliasDenied: true,
+			saved:       []storage.NewLink{{URL: "https://google.com"}},
+			mockResults: []storage.BatchResult{{Alias: "a", ID: 1}},
+			wantResults: []batch.Result{
+				{Index: 0, Status: "Error", Alias: "golang", Error: "custom aliases are not allowed"},
+				{Index: 1, Status: "OK", Alias: "a"},
+			},
+		},
+		{
+			name:    "Owned by caller",
+			body:    `[{"url": "https://google.com"}]`,
+			ownerID: 7,
+			saved: []storage.NewLink{
+				{URL: "https://google.com", LinkOptions: storage.LinkOptions{OwnerID: 7}},
+			},
+			mockResults: []storage.BatchResult{{Alias: "a", ID: 1}},
+			wantResults: []batch.Result{{Index: 0, Status: "OK", Alias: "a"}},
+		},
+		{
+			name:        "NDJSON",
+			body:        "{\"url\": \"https://google.com\"}\n\n{\"url\": \"invalid\"}\n{\"url\": \"https://go.dev\"}\n",
+			contentType: batch.ContentTypeNDJSON,
+			saved: []storage.NewLink{
+				{URL: "https://google.com"},
+				{URL: "https://go.dev"},
+			},
+			mockResults: []storage.BatchResult{{Alias: "a", ID: 1}, {Alias: "b", ID: 2}},
+			wantResults: []batch.Result{
+				{Index: 0, Status: "OK", Alias: "a"},
+				{Index: 1, Status: "Error", Error: "field URL is not a valid URL"},
+				{Index: 2, Status: "OK", Alias: "b"},
+			},
+			wantNDJSON: true,
+		},
+		{
+			name: "Nothing valid",
+			body: `[{"url": ""}]`,
+			wantResults: []batch.Result{
+				{Index: 0, Status: "Error", Error: "field URL is a required field"},
+			},
+		},
+		{
+			name: "Password not supported",
+			body: `[{"url": "https://go.dev", "password": "correct horse"}]`,
+			wantResults: []batch.Result{
+				{Index: 0, Status: "Error", Error: "password protected links must be created one by one"},
+			},
+		},
+		{
+			name:      "Too many entries",
+			body:      `[{"url": "https://a.com"}, {"url": "https://b.com"}, {"url": "https://c.com"}, {"url": "https://d.com"}]`,
+			maxSize:   3,
+			respCode:  http.StatusRequestEntityTooLarge,
+			respError: "batch must not have more than 3 entries",
+		},
+		{
+			name:        "Too many NDJSON entries",
+			body:        "{\"url\": \"https://a.com\"}\n{\"url\": \"https://b.com\"}\n{\"url\": \"https://c.com\"}\n{\"url\": \"https://d.com\"}\n",
+			contentType: batch.ContentTypeNDJSON,
+			maxSize:     3,
+			respCode:    http.StatusRequestEntityTooLarge,
+			respError:   "batch must not have more than 3 entries",
+		},
+		{
+			name:        "Body too large",
+			body:        `[{"url": "https://google.com/` + strings.Repeat("a", 100) + `"}]`,
+			maxBodySize: 64,
+			respCode:    http.StatusRequestEntityTooLarge,
+			respError:   "request body must not be larger than 64 bytes",
+		},
+		{
+			name:      "Every entry is charged",
+			body:      `[{"url": "https://a.com"}, {"url": "https://b.com"}, {"url": "https://c.com"}]`,
+			quotaLeft: 2,
+			respCode:  http.StatusTooManyRequests,
+			respError: "rate limit exceeded",
+		},
+		{
+			name:      "Empty batch",
+			body:      `[]`,
+			respCode:  http.StatusBadRequest,
+			respError: "empty request",
+		},
+		{
+			name:      "Empty body",
+			respCode:  http.StatusBadRequest,
+			respError: "empty request",
+		},
+		{
+			name:      "Not an array",
+			body:      `{"url": "https://google.com"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "failed to decode request",
+		},
+		{
+			name:      "Malformed array",
+			body:      `[{"url": "https://google.com"`,
+			respCode:  http.StatusBadRequest,
+			respError: "failed to decode request",
+		},
+		{
+			name:      "Storage error",
+			body:      `[{"url": "https://google.com"}]`,
+			saved:     []storage.NewLink{{URL: "https://google.com"}},
+			mockError: errors.New("unexpected error"),
+			respCode:  http.StatusInternalServerError,
+			respError: "failed to add urls",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			saverMock := mocks.NewURLBatchSaver(t)
+			if tc.saved != nil {
+				saverMock.On("SaveURLBatch", mock.MatchedBy(func(links []storage.NewLink) bool {
+					return equalLinks(tc.saved, links)
+				})).
+					Return(tc.mockResults, tc.mockError).
+					Once()
+			}
+
+			aliasAllowed := func(*http.Request) bool { return !tc.aliasDenied }
+
+			maxSize := tc.maxSize
+			if maxSize == 0 {
+				maxSize = 10
+			}
+
+			maxBodySize := tc.maxBodySize
+			if maxBodySize == 0 {
+				maxBodySize = 1 << 20
+			}
+
+			quota := func(w http.ResponseWriter, r *http.Request, links int) bool {
+				if tc.quotaLeft == 0 || links <= tc.quotaLeft {
+					return true
+				}
+
+				w.WriteHeader(http.StatusTooManyRequests)
+				render.JSON(w, r, resp.Error("rate limit exceeded"))
+
+				return false
+			}
+
+			handler := batch.New(slogdiscard.NewDiscardLogger(), saverMock, customalias.New(3, 32, nil), aliasAllowed, quota, maxSize, maxBodySize)
+
+			req := httptest.NewRequest(http.MethodPost, "/url/batch", strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			if tc.ownerID != 0 {
+				req = req.WithContext(auth.NewContext(req.Context(), auth.Principal{UserID: tc.ownerID}))
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			respCode := tc.respCode
+			if respCode == 0 {
+				respCode = http.StatusOK
+			}
+			require.Equal(t, respCode, rr.Code)
+
+			if tc.wantNDJSON {
+				require.Equal(t, batch.ContentTypeNDJSON, rr.Header().Get("Content-Type"))
+
+				var results []batch.Result
+				scanner := bufio.NewScanner(rr.Body)
+				for scanner.Scan() {
+					var res batch.Result
+					require.NoError(t, json.Unmarshal(scanner.Bytes(), &res))
+					results = append(results, res)
+				}
+				require.Equal(t, tc.wantResults, results)
+				return
+			}
+
+			var resp batch.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.wantResults, resp.Results)
+		})
+	}
+}
+
+// equalLinks compares the fields set by the tests, the time windows are
+// covered by the save handler tests.
+func equalLinks(want, got []storage.NewLink) bool {
+	if len(want) != len(got) {
+		return false
+	}
+
+	for i := range want {
+		if want[i].URL != got[i].URL || want[i].Alias != got[i].Alias ||
+			want[i].RedirectCode != got[i].RedirectCode || want[i].OwnerID != got[i].OwnerID {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ndjsonEntries returns n NDJSON entries with distinct URLs.
+func ndjsonEntries(from, n int) string {
+	var b strings.Builder
+	for i := from; i < from+n; i++ {
+		fmt.Fprintf(&b, "{\"url\": \"https://example.com/%d\"}\n", i)
+	}
+
+	return b.String()
+}
+
+func okResults(n int) []storage.BatchResult {
+	results := make([]storage.BatchResult, n)
+	for i := range results {
+		results[i] = storage.BatchResult{Alias: fmt.Sprintf("a%d", i), ID: int64(i + 1)}
+	}
+
+	return results
+}
+
+func readResults(t *testing.T, r io.Reader) []batch.Result {
+	t.Helper()
+
+	var results []batch.Result
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var res batch.Result
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &res))
+		results = append(results, res)
+	}
+	require.NoError(t, scanner.Err())
+
+	return results
+}
+
+func TestBatchHandler_NDJSONChunks(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+		// quotaLeft is how many links the caller may create over the whole request.
+		quotaLeft int
+		maxSize   int
+		// saves are the chunk sizes passed to the storage.
+		saves     []int
+		wantLen   int
+		lastIndex int
+		lastError string
+	}{
+		{
+			name:      "Saved chunk by chunk",
+			body:      ndjsonEntries(0, 150),
+			quotaLeft: 1000,
+			saves:     []int{100, 50},
+			wantLen:   150,
+			lastIndex: 149,
+		},
+		{
+			name:      "Rate limited after the first chunk",
+			body:      ndjsonEntries(0, 150),
+			quotaLeft: 120,
+			saves:     []int{100},
+			wantLen:   150,
+			lastIndex: 149,
+			lastError: "rate limit exceeded",
+		},
+		{
+			name:      "Malformed after the first chunk",
+			body:      ndjsonEntries(0, 120) + "{\"url\": \n",
+			quotaLeft: 1000,
+			saves:     []int{100},
+			wantLen:   101,
+			lastIndex: 120,
+			lastError: "failed to decode entry",
+		},
+		{
+			name:      "Too many after the first chunk",
+			body:      ndjsonEntries(0, 120),
+			quotaLeft: 1000,
+			maxSize:   110,
+			saves:     []int{100},
+			wantLen:   101,
+			lastIndex: 110,
+			lastError: "batch must not have more than 110 entries",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			saverMock := mocks.NewURLBatchSaver(t)
+			for _, n := range tc.saves {
+				n := n
+				saverMock.On("SaveURLBatch", mock.MatchedBy(func(links []storage.NewLink) bool {
+					return len(links) == n
+				})).
+					Return(okResults(n), nil).
+					Once()
+			}
+
+			left := tc.quotaLeft
+			quota := func(w http.ResponseWriter, r *http.Request, links int) bool {
+				if links > left {
+					w.WriteHeader(http.StatusTooManyRequests)
+					render.JSON(w, r, resp.Error("rate limit exceeded"))
+					return false
+				}
+				left -= links
+
+				return true
+			}
+
+			maxSize := tc.maxSize
+			if maxSize == 0 {
+				maxSize = 1000
+			}
+
+			aliasAllowed := func(*http.Request) bool { return true }
+			handler := batch.New(slogdiscard.NewDiscardLogger(), saverMock, customalias.New(3, 32, nil), aliasAllowed, quota, maxSize, 1<<20)
+
+			req := httptest.NewRequest(http.MethodPost, "/url/batch", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", batch.ContentTypeNDJSON)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			// The status was sent with the first chunk.
+			require.Equal(t, http.StatusOK, rr.Code)
+			require.Equal(t, batch.ContentTypeNDJSON, rr.Header().Get("Content-Type"))
+
+			results := readResults(t, rr.Body)
+			require.Len(t, results, tc.wantLen)
+			for i, res := range results[:len(results)-1] {
+				require.Equal(t, i, res.Index)
+			}
+
+			// Errors of the body end the stream with the entry they were found at.
+			last := results[len(results)-1]
+			require.Equal(t, tc.lastIndex, last.Index)
+			if tc.lastError == "" {
+				require.Equal(t, resp.StatusOK, last.Status)
+				return
+			}
+			require.Equal(t, resp.StatusOK, results[99].Status)
+			require.Equal(t, resp.StatusError, last.Status)
+			require.Equal(t, tc.lastError, last.Error)
+		})
+	}
+}
+
+func TestBatchHandler_NDJSONStreams(t *testing.T) {
+	saverMock := mocks.NewURLBatchSaver(t)
+	saverMock.On("SaveURLBatch", mock.Anything).Return(okResults(100), nil).Twice()
+
+	quota := func(http.ResponseWriter, *http.Request, int) bool { return true }
+	aliasAllowed := func(*http.Request) bool { return true }
+	srv := httptest.NewServer(batch.New(slogdiscard.NewDiscardLogger(), saverMock, customalias.New(3, 32, nil), aliasAllowed, quota, 1000, 1<<20))
+	defer srv.Close()
+
+	// The body stays open until the results of the first chunk were read.
+	body, bodyWriter := io.Pipe()
+	go func() {
+		_, _ = io.WriteString(bodyWriter, ndjsonEntries(0, 100))
+	}()
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL, body)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", batch.ContentTypeNDJSON)
+
+	res, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	scanner := bufio.NewScanner(res.Body)
+	for i := 0; i < 100; i++ {
+		require.True(t, scanner.Scan())
+	}
+
+	go func() {
+		_, _ = io.WriteString(bodyWriter, ndjsonEntries(100, 100))
+		_ = bodyWriter.Close()
+	}()
+
+	n := 100
+	for scanner.Scan() {
+		n++
+	}
+	require.NoError(t, scanner.Err())
+	require.Equal(t, 200, n)
+}
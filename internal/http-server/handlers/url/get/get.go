@@ -0,0 +1,83 @@
+package get
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+type Response struct {
+	resp.Response
+	Link *storage.Link `json:"link,omitempty"`
+	// ClicksLeft is how many redirects a link with max_clicks still serves.
+	ClicksLeft *int64 `json:"clicks_left,omitempty"`
+}
+
+// LinkGetter is an interface for getting link metadata by alias.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=LinkGetter
+type LinkGetter interface {
+	GetLink(alias string) (storage.Link, error)
+}
+
+// @Summary      Get short URL metadata
+// @Description  Возвращает сохраненную ссылку по ее короткому идентификатору
+// @Produce      json
+// @Security     BasicAuth
+// @Security     BearerAuth
+// @Param        alias path string true "Short URL alias"
+// @Success      200 {object} Response
+// @Failure      404 {object} Response
+// @Failure      500 {object} Response
+// @Router       /url/{alias} [get]
+func New(log *slog.Logger, linkGetter LinkGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.get.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+
+		link, err := linkGetter.GetLink(alias)
+		if err == nil && !link.OwnedBy(auth.OwnerID(r.Context())) {
+			// Links of other users are hidden, not forbidden.
+			err = storage.ErrURLNotFound
+		}
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, resp.Error("not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url", sl.Err(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("internal error"))
+			return
+		}
+
+		var clicksLeft *int64
+		if link.MaxClicks > 0 {
+			left := link.ClicksLeft()
+			clicksLeft = &left
+		}
+
+		render.JSON(w, r, Response{
+			Response:   resp.OK(),
+			Link:       &link,
+			ClicksLeft: clicksLeft,
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package get_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/get"
+	"url-shortener/internal/http-server/handlers/url/get/mocks"
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestGetHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		alias     string
+		respError string
+		respCode  int
+		mockError error
+		ownerID   int64
+		// usedClicks of 5 max clicks, nil means the link has no limit.
+		usedClicks *int64
+	}{
+		{
+			name:     "Success",
+			alias:    "abc",
+			respCode: http.StatusOK,
+		},
+		{
+			name:       "Click limited",
+			alias:      "abc",
+			respCode:   http.StatusOK,
+			usedClicks: ptr(int64(2)),
+		},
+		{
+			name:       "Used up",
+			alias:      "abc",
+			respCode:   http.StatusOK,
+			usedClicks: ptr(int64(5)),
+		},
+		{
+			name:      "Not found",
+			alias:     "missing",
+			respError: "not found",
+			respCode:  http.StatusNotFound,
+			mockError: storage.ErrURLNotFound,
+		},
+		{
+			name:      "Link of another user",
+			alias:     "abc",
+			respError: "not found",
+			respCode:  http.StatusNotFound,
+			ownerID:   7,
+		},
+		{
+			name:      "GetLink Error",
+			alias:     "abc",
+			respError: "internal error",
+			respCode:  http.StatusInternalServerError,
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			link := storage.Link{Alias: tc.alias, URL: "https://google.com", LinkOptions: storage.LinkOptions{OwnerID: 3}}
+			if tc.usedClicks != nil {
+				link.MaxClicks = 5
+				link.UsedClicks = *tc.usedClicks
+			}
+
+			linkGetterMock := mocks.NewLinkGetter(t)
+			linkGetterMock.On("GetLink", tc.alias).
+				Return(link, tc.mockError).
+				Once()
+
+			r := chi.NewRouter()
+			r.Get("/url/{alias}", get.New(slogdiscard.NewDiscardLogger(), linkGetterMock))
+
+			req := httptest.NewRequest(http.MethodGet, "/url/"+tc.alias, nil)
+			if tc.ownerID != 0 {
+				req = req.WithContext(auth.NewContext(req.Context(), auth.Principal{UserID: tc.ownerID}))
+			}
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp get.Response
+
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			if tc.respError == "" {
+				require.Equal(t, tc.alias, resp.Link.Alias)
+				require.Equal(t, "https://google.com", resp.Link.URL)
+
+				if tc.usedClicks != nil {
+					require.NotNil(t, resp.ClicksLeft)
+					require.Equal(t, 5-*tc.usedClicks, *resp.ClicksLeft)
+				} else {
+					require.Nil(t, resp.ClicksLeft)
+				}
+			} else {
+				require.Nil(t, resp.Link)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,109 @@
+package list
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+type Response struct {
+	resp.Response
+	Links  []storage.Link `json:"links,omitempty"`
+	Total  int            `json:"total"`
+	Limit  int            `json:"limit"`
+	Offset int            `json:"offset"`
+}
+
+// URLLister is an interface for paging through saved links.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLLister
+type URLLister interface {
+	ListURLs(limit, offset int, ownerID int64) ([]storage.Link, error)
+	CountURLs(ownerID int64) (int, error)
+}
+
+// @Summary      List short URLs
+// @Description  Возвращает страницу ссылок вызывающего пользователя в порядке создания, администратору - все ссылки
+// @Produce      json
+// @Security     BasicAuth
+// @Security     BearerAuth
+// @Param        limit  query int false "Page size (1-100)" default(20)
+// @Param        offset query int false "Number of links to skip" default(0)
+// @Success      200 {object} Response
+// @Failure      400 {object} Response
+// @Failure      500 {object} Response
+// @Router       /url [get]
+func New(log *slog.Logger, urlLister URLLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.list.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		limit, err := queryInt(r, "limit", defaultLimit)
+		if err != nil || limit < 1 || limit > maxLimit {
+			log.Info("invalid limit", slog.String("limit", r.URL.Query().Get("limit")))
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("invalid limit"))
+			return
+		}
+
+		offset, err := queryInt(r, "offset", 0)
+		if err != nil || offset < 0 {
+			log.Info("invalid offset", slog.String("offset", r.URL.Query().Get("offset")))
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("invalid offset"))
+			return
+		}
+
+		ownerID := auth.OwnerID(r.Context())
+
+		links, err := urlLister.ListURLs(limit, offset, ownerID)
+		if err != nil {
+			log.Error("failed to list urls", sl.Err(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("internal error"))
+			return
+		}
+
+		total, err := urlLister.CountURLs(ownerID)
+		if err != nil {
+			log.Error("failed to count urls", sl.Err(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("internal error"))
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Links:    links,
+			Total:    total,
+			Limit:    limit,
+			Offset:   offset,
+		})
+	}
+}
+
+func queryInt(r *http.Request, key string, def int) (int, error) {
+	raw := r.URL.Query().Get(key)
+	if raw == "" {
+		return def, nil
+	}
+
+	return strconv.Atoi(raw)
+}
@@ -0,0 +1,132 @@
+package list_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/list"
+	"url-shortener/internal/http-server/handlers/url/list/mocks"
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestListHandler(t *testing.T) {
+	links := []storage.Link{
+		{Alias: "a", URL: "https://google.com"},
+		{Alias: "b", URL: "https://go.dev"},
+	}
+
+	cases := []struct {
+		name       string
+		query      string
+		limit      int
+		offset     int
+		respError  string
+		respCode   int
+		mockError  error
+		skipMocks  bool
+		wantLength int
+		ownerID    int64
+	}{
+		{
+			name:       "Defaults",
+			limit:      20,
+			wantLength: 2,
+		},
+		{
+			name:       "Custom page",
+			query:      "?limit=5&offset=10",
+			limit:      5,
+			offset:     10,
+			wantLength: 2,
+		},
+		{
+			name:       "Owner",
+			limit:      20,
+			wantLength: 2,
+			ownerID:    7,
+		},
+		{
+			name:      "Invalid limit",
+			query:     "?limit=abc",
+			respError: "invalid limit",
+			respCode:  http.StatusBadRequest,
+			skipMocks: true,
+		},
+		{
+			name:      "Limit too big",
+			query:     "?limit=1000",
+			respError: "invalid limit",
+			respCode:  http.StatusBadRequest,
+			skipMocks: true,
+		},
+		{
+			name:      "Negative offset",
+			query:     "?offset=-1",
+			respError: "invalid offset",
+			respCode:  http.StatusBadRequest,
+			skipMocks: true,
+		},
+		{
+			name:      "ListURLs Error",
+			limit:     20,
+			respError: "internal error",
+			respCode:  http.StatusInternalServerError,
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlListerMock := mocks.NewURLLister(t)
+
+			if !tc.skipMocks {
+				urlListerMock.On("ListURLs", tc.limit, tc.offset, tc.ownerID).
+					Return(links, tc.mockError).
+					Once()
+				if tc.mockError == nil {
+					urlListerMock.On("CountURLs", tc.ownerID).
+						Return(len(links), nil).
+						Once()
+				}
+			}
+
+			handler := list.New(slogdiscard.NewDiscardLogger(), urlListerMock)
+
+			req := httptest.NewRequest(http.MethodGet, "/url"+tc.query, nil)
+			if tc.ownerID != 0 {
+				req = req.WithContext(auth.NewContext(req.Context(), auth.Principal{UserID: tc.ownerID}))
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			respCode := tc.respCode
+			if respCode == 0 {
+				respCode = http.StatusOK
+			}
+			require.Equal(t, respCode, rr.Code)
+
+			var resp list.Response
+
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			require.Len(t, resp.Links, tc.wantLength)
+			if tc.respError == "" {
+				require.Equal(t, len(links), resp.Total)
+				require.Equal(t, tc.limit, resp.Limit)
+				require.Equal(t, tc.offset, resp.Offset)
+			}
+		})
+	}
+}
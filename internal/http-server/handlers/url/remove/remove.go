@@ -0,0 +1,64 @@
+package remove
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+// URLDeleter is an interface for deleting a link by alias.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLDeleter
+type URLDeleter interface {
+	DeleteURL(alias string, ownerID int64) error
+}
+
+// @Summary      Delete short URL
+// @Description  Удаляет короткую ссылку
+// @Produce      json
+// @Security     BasicAuth
+// @Security     BearerAuth
+// @Param        alias path string true "Short URL alias"
+// @Success      200 {object} resp.Response
+// @Failure      404 {object} resp.Response
+// @Failure      500 {object} resp.Response
+// @Router       /url/{alias} [delete]
+func New(log *slog.Logger, urlDeleter URLDeleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.remove.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+
+		err := urlDeleter.DeleteURL(alias, auth.OwnerID(r.Context()))
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, resp.Error("not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to delete url", sl.Err(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("failed to delete url"))
+			return
+		}
+
+		log.Info("url deleted", slog.String("alias", alias))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
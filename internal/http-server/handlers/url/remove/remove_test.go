@@ -0,0 +1,84 @@
+package remove_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/remove"
+	"url-shortener/internal/http-server/handlers/url/remove/mocks"
+	"url-shortener/internal/http-server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestRemoveHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		respError string
+		respCode  int
+		mockError error
+		ownerID   int64
+	}{
+		{
+			name:     "Success",
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Not found",
+			respError: "not found",
+			respCode:  http.StatusNotFound,
+			mockError: storage.ErrURLNotFound,
+		},
+		{
+			name:      "Link of another user",
+			respError: "not found",
+			respCode:  http.StatusNotFound,
+			mockError: storage.ErrURLNotFound,
+			ownerID:   7,
+		},
+		{
+			name:      "DeleteURL Error",
+			respError: "failed to delete url",
+			respCode:  http.StatusInternalServerError,
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlDeleterMock := mocks.NewURLDeleter(t)
+			urlDeleterMock.On("DeleteURL", "abc", tc.ownerID).
+				Return(tc.mockError).
+				Once()
+
+			r := chi.NewRouter()
+			r.Delete("/url/{alias}", remove.New(slogdiscard.NewDiscardLogger(), urlDeleterMock))
+
+			req := httptest.NewRequest(http.MethodDelete, "/url/abc", nil)
+			if tc.ownerID != 0 {
+				req = req.WithContext(auth.NewContext(req.Context(), auth.Principal{UserID: tc.ownerID}))
+			}
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var body resp.Response
+
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+
+			require.Equal(t, tc.respError, body.Error)
+		})
+	}
+}
@@ -2,30 +2,64 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	mock "github.com/stretchr/testify/mock"
+	storage "url-shortener/internal/storage"
+)
 
 // URLSaver is an autogenerated mock type for the URLSaver type
 type URLSaver struct {
 	mock.Mock
 }
 
-// SaveURL provides a mock function with given fields: urlToSave, alias
-func (_m *URLSaver) SaveURL(urlToSave string, alias string) (int64, error) {
-	ret := _m.Called(urlToSave, alias)
+// SaveGeneratedURL provides a mock function with given fields: urlToSave, opts
+func (_m *URLSaver) SaveGeneratedURL(urlToSave string, opts storage.LinkOptions) (string, int64, error) {
+	ret := _m.Called(urlToSave, opts)
+
+	var r0 string
+	var r1 int64
+	var r2 error
+	if rf, ok := ret.Get(0).(func(string, storage.LinkOptions) (string, int64, error)); ok {
+		return rf(urlToSave, opts)
+	}
+	if rf, ok := ret.Get(0).(func(string, storage.LinkOptions) string); ok {
+		r0 = rf(urlToSave, opts)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, storage.LinkOptions) int64); ok {
+		r1 = rf(urlToSave, opts)
+	} else {
+		r1 = ret.Get(1).(int64)
+	}
+
+	if rf, ok := ret.Get(2).(func(string, storage.LinkOptions) error); ok {
+		r2 = rf(urlToSave, opts)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// SaveURL provides a mock function with given fields: urlToSave, alias, opts
+func (_m *URLSaver) SaveURL(urlToSave string, alias string, opts storage.LinkOptions) (int64, error) {
+	ret := _m.Called(urlToSave, alias, opts)
 
 	var r0 int64
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string, string) (int64, error)); ok {
-		return rf(urlToSave, alias)
+	if rf, ok := ret.Get(0).(func(string, string, storage.LinkOptions) (int64, error)); ok {
+		return rf(urlToSave, alias, opts)
 	}
-	if rf, ok := ret.Get(0).(func(string, string) int64); ok {
-		r0 = rf(urlToSave, alias)
+	if rf, ok := ret.Get(0).(func(string, string, storage.LinkOptions) int64); ok {
+		r0 = rf(urlToSave, alias, opts)
 	} else {
 		r0 = ret.Get(0).(int64)
 	}
 
-	if rf, ok := ret.Get(1).(func(string, string) error); ok {
-		r1 = rf(urlToSave, alias)
+	if rf, ok := ret.Get(1).(func(string, string, storage.LinkOptions) error); ok {
+		r1 = rf(urlToSave, alias, opts)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -1,18 +1,17 @@
 package save
 
 import (
-	"context"
-	"database/sql"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"time"
 
-	"url-shortener/internal/config"
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/account"
 	resp "url-shortener/internal/lib/api/response"
-	generatingalias "url-shortener/internal/lib/generating_alias"
 	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/qr"
 	"url-shortener/internal/storage"
 
 	"github.com/go-chi/chi/v5/middleware"
@@ -24,28 +23,128 @@ import (
 type Request struct {
 	URL   string `json:"url" validate:"required,url"`
 	Alias string `json:"alias,omitempty"`
+	// ActiveFrom and ExpiresAt limit when the link redirects, both are optional.
+	ActiveFrom *time.Time `json:"active_from,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	// RedirectCode overrides the server default redirect status.
+	RedirectCode int `json:"redirect_code,omitempty" validate:"omitempty,oneof=301 302 307 308"`
+	// MaxClicks stops the link after that many redirects, 1 makes a one-time link.
+	MaxClicks int64 `json:"max_clicks,omitempty" validate:"omitempty,min=1"`
+	// Targets send clients on some platforms elsewhere than URL.
+	Targets *Targets `json:"targets,omitempty"`
+	// Variants make the link rotate between destinations by weight, URL is
+	// then only shown in previews.
+	Variants []Variant `json:"variants,omitempty" validate:"omitempty,max=20,dive"`
+	// StickyVariants keeps a visitor on the variant they were sent to first,
+	// it requires Variants.
+	StickyVariants bool `json:"sticky_variants,omitempty" validate:"excluded_without=Variants"`
+	// QR asks for a QR code of the short link in the response.
+	QR bool `json:"qr,omitempty"`
+	// Password protects the link, visitors have to enter it before they are
+	// redirected. It must be 8 to 72 bytes long, only its hash is stored.
+	Password string `json:"password,omitempty"`
+}
+
+// LogValue keeps the password out of the logs.
+func (r Request) LogValue() slog.Value {
+	if r.Password != "" {
+		r.Password = "[REDACTED]"
+	}
+
+	// The named type drops this method, so the value is not resolved again.
+	type request Request
+
+	return slog.AnyValue(request(r))
+}
+
+// Targets are alternate destinations per platform, platforms left empty
+// are sent to the link URL.
+type Targets struct {
+	IOS     string `json:"ios,omitempty" validate:"omitempty,url"`
+	Android string `json:"android,omitempty" validate:"omitempty,url"`
+	Desktop string `json:"desktop,omitempty" validate:"omitempty,url"`
+}
+
+// LinkTargets returns t as stored with a link, nil when no platform has a
+// target.
+func (t *Targets) LinkTargets() *storage.Targets {
+	if t == nil || *t == (Targets{}) {
+		return nil
+	}
+
+	targets := storage.Targets(*t)
+
+	return &targets
+}
+
+// Variant is a destination of a rotating link.
+type Variant struct {
+	URL string `json:"url" validate:"required,url"`
+	// Weight is the share of redirects relative to the other variants.
+	Weight int `json:"weight" validate:"required,min=1,max=1000"`
+}
+
+// LinkVariants returns variants as stored with a link, nil for none.
+func LinkVariants(variants []Variant) []storage.Variant {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	linkVariants := make([]storage.Variant, len(variants))
+	for i, v := range variants {
+		linkVariants[i] = storage.Variant{URL: v.URL, Weight: v.Weight}
+	}
+
+	return linkVariants
 }
 
 type Response struct {
 	resp.Response
 	Alias string `json:"alias,omitempty"`
+	// QR is a PNG data URI, only set when the request asked for it.
+	QR string `json:"qr,omitempty"`
 }
 
 //go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLSaver
 type URLSaver interface {
-	SaveURL(urlToSave string, alias string) (int64, error)
+	SaveURL(urlToSave string, alias string, opts storage.LinkOptions) (int64, error)
+	SaveGeneratedURL(urlToSave string, opts storage.LinkOptions) (string, int64, error)
 }
 
+// AliasValidator checks aliases chosen by the client.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=AliasValidator
+type AliasValidator interface {
+	Validate(alias string) error
+}
+
+// AliasPermission reports whether the client of r may choose its own alias.
+type AliasPermission func(r *http.Request) bool
+
+// ShortURL returns the full short URL of alias as requested through r.
+type ShortURL func(r *http.Request, alias string) string
+
 // @Summary      Создать сокращенный URL
 // @Description  Принимает длинный URL и создает для него короткую версию
 // @Accept       json
 // @Produce      json
+// @Security     BasicAuth
+// @Security     BearerAuth
 // @Param        request body Request true "URL для сокращения"
 // @Success      200 {object} Response
 // @Failure      400 {object} Response
+// @Failure      403 {object} Response
+// @Failure      429 {object} Response "Rate limit exceeded"
 // @Failure      500 {object} Response
-// @Router       / [post]
-func New(log *slog.Logger, urlSaver URLSaver, srv *http.Server) http.HandlerFunc {
+// @Failure      507 {object} Response
+// @Router       /url [post]
+func New(
+	log *slog.Logger,
+	urlSaver URLSaver,
+	aliasValidator AliasValidator,
+	aliasAllowed AliasPermission,
+	shortURL ShortURL,
+) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.save.New"
 
@@ -77,104 +176,71 @@ func New(log *slog.Logger, urlSaver URLSaver, srv *http.Server) http.HandlerFunc
 			return
 		}
 
-		if req.Alias != "" {
-			log.Error("Don't allowed to set alias manually", slog.String("alias", req.Alias))
+		if msg := ValidateWindow(req, time.Now()); msg != "" {
+			log.Info("invalid link window", slog.String("error", msg))
 			w.WriteHeader(http.StatusBadRequest)
-			render.JSON(w, r, resp.Error("manual alias setting is not allowed"))
+			render.JSON(w, r, resp.Error(msg))
 			return
 		}
 
-		cfg := config.MustLoad()
-		db, err := sql.Open("sqlite3", cfg.StoragePath)
-		if err != nil {
-			log.Error("failed to open database", sl.Err(err))
-			render.JSON(w, r, resp.Error("internal server error"))
-			return
-		}
-		defer db.Close()
-
-		var alias string
-		var aliasLength, pointerOne, pointerTwo, pointerThree, pointerFour int
-
-		_ = db.QueryRow("SELECT value FROM alias_value WHERE name = 'AliasLength'").Scan(&aliasLength)
-		_ = db.QueryRow("SELECT value FROM alias_value WHERE name = 'PointerOne'").Scan(&pointerOne)
-		_ = db.QueryRow("SELECT value FROM alias_value WHERE name = 'PointerTwo'").Scan(&pointerTwo)
-		_ = db.QueryRow("SELECT value FROM alias_value WHERE name = 'PointerThree'").Scan(&pointerThree)
-		_ = db.QueryRow("SELECT value FROM alias_value WHERE name = 'PointerFour'").Scan(&pointerFour)
-
-		switch aliasLength {
-		case 1:
-			alias = generatingalias.NewGeneratedAliasOneSize(&aliasLength, &pointerOne)
-		case 2:
-			alias = generatingalias.NewGeneratedAliasTwoSize(&aliasLength, &pointerOne, &pointerTwo)
-		case 3:
-			alias = generatingalias.NewGeneratedAliasThreeSize(&aliasLength, &pointerOne, &pointerTwo, &pointerThree)
-		case 4:
-			alias = generatingalias.NewGeneratedAliasFourSize(&aliasLength, &pointerOne, &pointerTwo, &pointerThree, &pointerFour)
-		default:
-			log.Info("No free aliases left. Stopping server.")
-			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-			defer cancel()
-			if err := srv.Shutdown(ctx); err != nil {
-				log.Error("Failed to stop server", sl.Err(err))
-			} else {
-				log.Info("Server stopped successfully")
-			}
-			return
+		opts := storage.LinkOptions{
+			ActiveFrom:     req.ActiveFrom,
+			ExpiresAt:      req.ExpiresAt,
+			RedirectCode:   req.RedirectCode,
+			OwnerID:        auth.CreatorID(r.Context()),
+			MaxClicks:      req.MaxClicks,
+			Targets:        req.Targets.LinkTargets(),
+			Variants:       LinkVariants(req.Variants),
+			StickyVariants: req.StickyVariants,
 		}
 
-		// Сохраняем новые значения в БД
-		res, err := db.Exec("UPDATE alias_value SET value = ? WHERE name = 'AliasLength'", aliasLength)
-		if err != nil {
-			log.Error("failed to update AliasLength", sl.Err(err))
-			return
-		}
-		rowsAffected, _ := res.RowsAffected()
-		if rowsAffected == 0 {
-			log.Warn("AliasLength not updated, possibly missing record")
-		}
+		alias := req.Alias
+		var id int64
 
-		res, err = db.Exec("UPDATE alias_value SET value = ? WHERE name = 'PointerOne'", pointerOne)
-		if err != nil {
-			log.Error("failed to update PointerOne", sl.Err(err))
-			return
-		}
-		rowsAffected, _ = res.RowsAffected()
-		if rowsAffected == 0 {
-			log.Warn("PointerOne not updated, possibly missing record")
-		}
+		if alias != "" {
+			if !aliasAllowed(r) {
+				log.Info("custom alias is not allowed", slog.String("alias", alias))
+				w.WriteHeader(http.StatusForbidden)
+				render.JSON(w, r, resp.Error("custom aliases are not allowed"))
+				return
+			}
 
-		res, err = db.Exec("UPDATE alias_value SET value = ? WHERE name = 'PointerTwo'", pointerTwo)
-		if err != nil {
-			log.Error("failed to update PointerTwo", sl.Err(err))
-			return
-		}
-		rowsAffected, _ = res.RowsAffected()
-		if rowsAffected == 0 {
-			log.Warn("PointerTwo not updated, possibly missing record")
+			if err := aliasValidator.Validate(alias); err != nil {
+				log.Info("invalid alias", slog.String("alias", alias), sl.Err(err))
+				w.WriteHeader(http.StatusBadRequest)
+				render.JSON(w, r, resp.Error(fmt.Sprintf("invalid alias: %s", err)))
+				return
+			}
 		}
 
-		res, err = db.Exec("UPDATE alias_value SET value = ? WHERE name = 'PointerThree'", pointerThree)
-		if err != nil {
-			log.Error("failed to update PointerThree", sl.Err(err))
-			return
-		}
-		rowsAffected, _ = res.RowsAffected()
-		if rowsAffected == 0 {
-			log.Warn("PointerThree not updated, possibly missing record")
+		// Hashed after the cheap checks, bcrypt is slow on purpose.
+		if req.Password != "" {
+			opts.PasswordHash, err = account.HashPassword(req.Password)
+			if errors.Is(err, account.ErrPasswordTooShort) || errors.Is(err, account.ErrPasswordTooLong) {
+				log.Info("invalid link password length")
+				w.WriteHeader(http.StatusBadRequest)
+				render.JSON(w, r, resp.Error(err.Error()))
+				return
+			}
+			if err != nil {
+				log.Error("failed to hash link password", sl.Err(err))
+				w.WriteHeader(http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error("failed to add url"))
+				return
+			}
 		}
 
-		res, err = db.Exec("UPDATE alias_value SET value = ? WHERE name = 'PointerFour'", pointerFour)
-		if err != nil {
-			log.Error("failed to update AliasLength", sl.Err(err))
-			return
+		if alias != "" {
+			id, err = urlSaver.SaveURL(req.URL, alias, opts)
+		} else {
+			alias, id, err = urlSaver.SaveGeneratedURL(req.URL, opts)
 		}
-		rowsAffected, _ = res.RowsAffected()
-		if rowsAffected == 0 {
-			log.Warn("PointerFour not updated, possibly missing record")
+		if errors.Is(err, storage.ErrAliasSpaceExhausted) {
+			log.Error("no free aliases left", sl.Err(err))
+			w.WriteHeader(http.StatusInsufficientStorage)
+			render.JSON(w, r, resp.Error("no free aliases left"))
+			return
 		}
-
-		id, err := urlSaver.SaveURL(req.URL, alias)
 		if errors.Is(err, storage.ErrURLExists) {
 			log.Info("url already exists", slog.String("url", req.URL))
 			w.WriteHeader(http.StatusBadRequest)
@@ -188,13 +254,40 @@ func New(log *slog.Logger, urlSaver URLSaver, srv *http.Server) http.HandlerFunc
 		}
 
 		log.Info("url added", slog.Int64("id", id))
-		responseOK(w, r, alias)
+
+		var qrCode string
+		if req.QR {
+			// The link is saved either way, the client can fetch the code
+			// from /{alias}/qr instead.
+			qrCode, err = qr.DataURI(shortURL(r, alias), qr.DefaultOptions)
+			if err != nil {
+				log.Error("failed to encode qr code", sl.Err(err))
+			}
+		}
+
+		responseOK(w, r, alias, qrCode)
+	}
+}
+
+// ValidateWindow returns a client error message if the link would never redirect.
+func ValidateWindow(req Request, now time.Time) string {
+	if req.ExpiresAt == nil {
+		return ""
 	}
+	if !req.ExpiresAt.After(now) {
+		return "expires_at must be in the future"
+	}
+	if req.ActiveFrom != nil && !req.ExpiresAt.After(*req.ActiveFrom) {
+		return "expires_at must be after active_from"
+	}
+
+	return ""
 }
 
-func responseOK(w http.ResponseWriter, r *http.Request, alias string) {
+func responseOK(w http.ResponseWriter, r *http.Request, alias, qrCode string) {
 	render.JSON(w, r, Response{
 		Response: resp.OK(),
 		Alias:    alias,
+		QR:       qrCode,
 	})
 }
@@ -7,33 +7,183 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slog"
 
 	"url-shortener/internal/http-server/handlers/url/save"
 	"url-shortener/internal/http-server/handlers/url/save/mocks"
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/account"
+	customalias "url-shortener/internal/lib/custom_alias"
 	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
 )
 
 func TestSaveHandler(t *testing.T) {
 	cases := []struct {
-		name      string
-		alias     string
-		url       string
-		respError string
-		mockError error
+		name          string
+		alias         string
+		url           string
+		extra         string
+		respError     string
+		respCode      int
+		mockError     error
+		aliasDenied   bool
+		validateError error
+		ownerID       int64
+		wantQR        bool
+		// password is the one sent in extra, the saved hash must match it.
+		password string
+		// targets are the ones sent in extra as they must be saved.
+		targets *storage.Targets
+		// variants and sticky are the ones sent in extra as they must be saved.
+		variants []storage.Variant
+		sticky   bool
 	}{
 		{
-			name:  "Success",
+			name: "Success",
+			url:  "https://google.com",
+		},
+		{
+			name:    "Owned by caller",
+			url:     "https://google.com",
+			ownerID: 7,
+		},
+		{
+			name:  "Custom alias",
 			alias: "test_alias",
 			url:   "https://google.com",
 		},
 		{
-			name:  "Empty alias",
-			alias: "",
+			name:  "With window",
+			url:   "https://google.com",
+			extra: `, "active_from": "2030-01-01T00:00:00Z", "expires_at": "2030-02-01T00:00:00Z"`,
+		},
+		{
+			name:   "With QR code",
+			alias:  "test_alias",
+			url:    "https://google.com",
+			extra:  `, "qr": true`,
+			wantQR: true,
+		},
+		{
+			name:  "Permanent redirect",
+			url:   "https://google.com",
+			extra: `, "redirect_code": 308`,
+		},
+		{
+			name:      "Invalid redirect code",
+			url:       "https://google.com",
+			extra:     `, "redirect_code": 303`,
+			respError: "field RedirectCode must be one of: 301 302 307 308",
+		},
+		{
+			name:  "One-time link",
+			url:   "https://google.com",
+			extra: `, "max_clicks": 1`,
+		},
+		{
+			name:      "Invalid max clicks",
+			url:       "https://google.com",
+			extra:     `, "max_clicks": -1`,
+			respError: "field MaxClicks must be at least 1",
+		},
+		{
+			name:  "With targets",
+			url:   "https://google.com",
+			extra: `, "targets": {"ios": "https://apps.apple.com/app/id1", "android": "https://play.google.com/store/apps/details?id=app"}`,
+			targets: &storage.Targets{
+				IOS:     "https://apps.apple.com/app/id1",
+				Android: "https://play.google.com/store/apps/details?id=app",
+			},
+		},
+		{
+			name:  "Empty targets",
 			url:   "https://google.com",
+			extra: `, "targets": {}`,
+		},
+		{
+			name:      "Invalid target",
+			url:       "https://google.com",
+			extra:     `, "targets": {"desktop": "not a url"}`,
+			respError: "field Desktop is not a valid URL",
+		},
+		{
+			name:  "With variants",
+			url:   "https://google.com",
+			extra: `, "variants": [{"url": "https://example.com/a", "weight": 1}, {"url": "https://example.com/b", "weight": 2}], "sticky_variants": true`,
+			variants: []storage.Variant{
+				{URL: "https://example.com/a", Weight: 1},
+				{URL: "https://example.com/b", Weight: 2},
+			},
+			sticky: true,
+		},
+		{
+			name:      "Invalid variant URL",
+			url:       "https://google.com",
+			extra:     `, "variants": [{"url": "not a url", "weight": 1}]`,
+			respError: "field URL is not a valid URL",
+		},
+		{
+			name:      "Variant without weight",
+			url:       "https://google.com",
+			extra:     `, "variants": [{"url": "https://example.com/a"}]`,
+			respError: "field Weight is a required field",
+		},
+		{
+			name:      "Too many variants",
+			url:       "https://google.com",
+			extra:     `, "variants": [` + strings.Repeat(`{"url": "https://example.com", "weight": 1}, `, 20) + `{"url": "https://example.com", "weight": 1}]`,
+			respError: "field Variants must be at most 20",
+		},
+		{
+			name:      "Sticky without variants",
+			url:       "https://google.com",
+			extra:     `, "sticky_variants": true`,
+			respError: "field StickyVariants is only allowed with Variants",
+		},
+		{
+			name:      "Expired",
+			url:       "https://google.com",
+			extra:     `, "expires_at": "2020-01-01T00:00:00Z"`,
+			respError: "expires_at must be in the future",
+			respCode:  http.StatusBadRequest,
+		},
+		{
+			name:      "Expires before activation",
+			url:       "https://google.com",
+			extra:     `, "active_from": "2030-02-01T00:00:00Z", "expires_at": "2030-01-01T00:00:00Z"`,
+			respError: "expires_at must be after active_from",
+			respCode:  http.StatusBadRequest,
+		},
+		{
+			name:        "Custom alias not allowed",
+			alias:       "test_alias",
+			url:         "https://google.com",
+			respError:   "custom aliases are not allowed",
+			respCode:    http.StatusForbidden,
+			aliasDenied: true,
+		},
+		{
+			name:          "Reserved alias",
+			alias:         "swagger",
+			url:           "https://google.com",
+			respError:     "invalid alias: alias is reserved",
+			respCode:      http.StatusBadRequest,
+			validateError: customalias.ErrReserved,
+		},
+		{
+			name:      "Custom alias exists",
+			alias:     "test_alias",
+			url:       "https://google.com",
+			respError: "url with alias: test_alias already exists",
+			respCode:  http.StatusBadRequest,
+			mockError: storage.ErrURLExists,
 		},
 		{
 			name:      "Empty URL",
@@ -48,12 +198,38 @@ func TestSaveHandler(t *testing.T) {
 			respError: "field URL is not a valid URL",
 		},
 		{
-			name:      "SaveURL Error",
-			alias:     "test_alias",
+			name:     "With password",
+			url:      "https://google.com",
+			extra:    `, "password": "correct horse"`,
+			password: "correct horse",
+		},
+		{
+			name:      "Password too short",
+			url:       "https://google.com",
+			extra:     `, "password": "short"`,
+			respError: "password must be at least 8 characters",
+			respCode:  http.StatusBadRequest,
+		},
+		{
+			name:      "Password too long",
+			url:       "https://google.com",
+			extra:     `, "password": "` + strings.Repeat("a", 73) + `"`,
+			respError: "password must be at most 72 bytes",
+			respCode:  http.StatusBadRequest,
+		},
+		{
+			name:      "SaveGeneratedURL Error",
 			url:       "https://google.com",
 			respError: "failed to add url",
 			mockError: errors.New("unexpected error"),
 		},
+		{
+			name:      "Alias space exhausted",
+			url:       "https://google.com",
+			respError: "no free aliases left",
+			respCode:  http.StatusInsufficientStorage,
+			mockError: storage.ErrAliasSpaceExhausted,
+		},
 	}
 
 	for _, tc := range cases {
@@ -63,24 +239,60 @@ func TestSaveHandler(t *testing.T) {
 			t.Parallel()
 
 			urlSaverMock := mocks.NewURLSaver(t)
+			aliasValidatorMock := mocks.NewAliasValidator(t)
 
-			if tc.respError == "" || tc.mockError != nil {
-				urlSaverMock.On("SaveURL", tc.url, mock.AnythingOfType("string")).
+			validRequest := tc.respError == "" || tc.mockError != nil || tc.validateError != nil || tc.aliasDenied
+			customAlias := validRequest && tc.alias != "" && !tc.aliasDenied
+
+			if customAlias {
+				aliasValidatorMock.On("Validate", tc.alias).
+					Return(tc.validateError).
+					Once()
+			}
+
+			switch {
+			case customAlias && tc.validateError == nil:
+				urlSaverMock.On("SaveURL", tc.url, tc.alias, savedWith(tc.password, storage.LinkOptions{
+					OwnerID:        tc.ownerID,
+					Targets:        tc.targets,
+					Variants:       tc.variants,
+					StickyVariants: tc.sticky,
+				})).
 					Return(int64(1), tc.mockError).
 					Once()
+			case validRequest && tc.alias == "":
+				urlSaverMock.On("SaveGeneratedURL", tc.url, savedWith(tc.password, storage.LinkOptions{
+					OwnerID:        tc.ownerID,
+					Targets:        tc.targets,
+					Variants:       tc.variants,
+					StickyVariants: tc.sticky,
+				})).
+					Return("a", int64(1), tc.mockError).
+					Once()
 			}
 
-			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, nil)
+			aliasAllowed := func(*http.Request) bool { return !tc.aliasDenied }
+
+			shortURL := func(_ *http.Request, alias string) string { return "https://sho.rt/" + alias }
 
-			input := fmt.Sprintf(`{"url": "%s", "alias": "%s"}`, tc.url, tc.alias)
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, aliasValidatorMock, aliasAllowed, shortURL)
+
+			input := fmt.Sprintf(`{"url": "%s", "alias": "%s"%s}`, tc.url, tc.alias, tc.extra)
 
 			req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(input)))
 			require.NoError(t, err)
+			if tc.ownerID != 0 {
+				req = req.WithContext(auth.NewContext(req.Context(), auth.Principal{UserID: tc.ownerID}))
+			}
 
 			rr := httptest.NewRecorder()
 			handler.ServeHTTP(rr, req)
 
-			require.Equal(t, rr.Code, http.StatusOK)
+			respCode := tc.respCode
+			if respCode == 0 {
+				respCode = http.StatusOK
+			}
+			require.Equal(t, respCode, rr.Code)
 
 			body := rr.Body.String()
 
@@ -90,7 +302,41 @@ func TestSaveHandler(t *testing.T) {
 
 			require.Equal(t, tc.respError, resp.Error)
 
-			// TODO: add more checks
+			if tc.respError == "" && tc.alias != "" {
+				require.Equal(t, tc.alias, resp.Alias)
+			}
+
+			if tc.wantQR {
+				require.True(t, strings.HasPrefix(resp.QR, "data:image/png;base64,"))
+			} else {
+				require.Empty(t, resp.QR)
+			}
 		})
 	}
 }
+
+func savedWith(password string, want storage.LinkOptions) any {
+	return mock.MatchedBy(func(opts storage.LinkOptions) bool {
+		if opts.OwnerID != want.OwnerID || opts.StickyVariants != want.StickyVariants {
+			return false
+		}
+		if !reflect.DeepEqual(opts.Targets, want.Targets) || !reflect.DeepEqual(opts.Variants, want.Variants) {
+			return false
+		}
+		if password == "" {
+			return opts.PasswordHash == ""
+		}
+
+		return account.CheckPassword(opts.PasswordHash, password)
+	})
+}
+
+func TestRequest_LogValue(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	log.Info("request body decoded", slog.Any("request", save.Request{URL: "https://google.com", Password: "correct horse"}))
+
+	require.Contains(t, buf.String(), "https://google.com")
+	require.NotContains(t, buf.String(), "correct horse")
+}
@@ -0,0 +1,94 @@
+package stats
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+const (
+	defaultDays = 30
+	maxDays     = 365
+)
+
+type Response struct {
+	resp.Response
+	Stats *storage.ClickStats `json:"stats,omitempty"`
+}
+
+// StatsGetter is an interface for getting click statistics by alias.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=StatsGetter
+type StatsGetter interface {
+	ClickStats(alias string, since time.Time, ownerID int64) (storage.ClickStats, error)
+}
+
+// @Summary      Get short URL click statistics
+// @Description  Возвращает общее число переходов, уникальных посетителей и переходы по дням
+// @Produce      json
+// @Security     BasicAuth
+// @Security     BearerAuth
+// @Param        alias path string true "Short URL alias"
+// @Param        days query int false "Number of days in the daily breakdown" default(30) maximum(365)
+// @Success      200 {object} Response
+// @Failure      400 {object} Response
+// @Failure      404 {object} Response
+// @Failure      500 {object} Response
+// @Router       /url/{alias}/stats [get]
+func New(log *slog.Logger, statsGetter StatsGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.stats.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+
+		days := defaultDays
+		if v := r.URL.Query().Get("days"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxDays {
+				log.Info("invalid days", slog.String("days", v))
+				w.WriteHeader(http.StatusBadRequest)
+				render.JSON(w, r, resp.Error("days must be between 1 and "+strconv.Itoa(maxDays)))
+				return
+			}
+			days = n
+		}
+
+		// The breakdown covers today and the days-1 full UTC days before it.
+		since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+
+		stats, err := statsGetter.ClickStats(alias, since, auth.OwnerID(r.Context()))
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, resp.Error("not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get click stats", sl.Err(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("internal error"))
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Stats:    &stats,
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package stats_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/stats"
+	"url-shortener/internal/http-server/handlers/url/stats/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestStatsHandler(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	cases := []struct {
+		name      string
+		alias     string
+		query     string
+		since     time.Time
+		respError string
+		respCode  int
+		mockError error
+	}{
+		{
+			name:     "Success",
+			alias:    "abc",
+			since:    today.AddDate(0, 0, -29),
+			respCode: http.StatusOK,
+		},
+		{
+			name:     "Custom days",
+			alias:    "abc",
+			query:    "?days=7",
+			since:    today.AddDate(0, 0, -6),
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Invalid days",
+			alias:     "abc",
+			query:     "?days=0",
+			respError: "days must be between 1 and 365",
+			respCode:  http.StatusBadRequest,
+		},
+		{
+			name:      "Not found",
+			alias:     "missing",
+			since:     today.AddDate(0, 0, -29),
+			respError: "not found",
+			respCode:  http.StatusNotFound,
+			mockError: storage.ErrURLNotFound,
+		},
+		{
+			name:      "ClickStats Error",
+			alias:     "abc",
+			since:     today.AddDate(0, 0, -29),
+			respError: "internal error",
+			respCode:  http.StatusInternalServerError,
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			statsGetterMock := mocks.NewStatsGetter(t)
+			if !tc.since.IsZero() {
+				statsGetterMock.On("ClickStats", tc.alias, mock.MatchedBy(func(since time.Time) bool {
+					// Tolerate the test running across midnight.
+					return since.Equal(tc.since) || since.Equal(tc.since.AddDate(0, 0, 1))
+				}), storage.AnyOwner).
+					Return(storage.ClickStats{Total: 3, UniqueVisitors: 2}, tc.mockError).
+					Once()
+			}
+
+			r := chi.NewRouter()
+			r.Get("/url/{alias}/stats", stats.New(slogdiscard.NewDiscardLogger(), statsGetterMock))
+
+			req := httptest.NewRequest(http.MethodGet, "/url/"+tc.alias+"/stats"+tc.query, nil)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp stats.Response
+
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			if tc.respError == "" {
+				require.Equal(t, 3, resp.Stats.Total)
+				require.Equal(t, 2, resp.Stats.UniqueVisitors)
+			} else {
+				require.Nil(t, resp.Stats)
+			}
+		})
+	}
+}
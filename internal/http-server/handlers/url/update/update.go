@@ -0,0 +1,150 @@
+package update
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/http-server/handlers/url/save"
+	"url-shortener/internal/http-server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+// Request changes the URL, the platform targets, the variants, their
+// stickiness or any of them. Targets and variants replace the saved ones, an
+// empty object or array removes them. Replaced variants count their clicks
+// from zero.
+type Request struct {
+	URL            string         `json:"url,omitempty" validate:"required_without_all=Targets Variants StickyVariants,omitempty,url"`
+	Targets        *save.Targets  `json:"targets,omitempty"`
+	Variants       []save.Variant `json:"variants,omitempty" validate:"omitempty,max=20,dive"`
+	StickyVariants *bool          `json:"sticky_variants,omitempty"`
+}
+
+// LinkUpdate returns the changes r asks for.
+func (r Request) LinkUpdate() storage.LinkUpdate {
+	update := storage.LinkUpdate{
+		URL:            r.URL,
+		StickyVariants: r.StickyVariants,
+	}
+
+	if r.Targets != nil {
+		update.Targets = &storage.Targets{}
+		if targets := r.Targets.LinkTargets(); targets != nil {
+			update.Targets = targets
+		}
+	}
+
+	if r.Variants != nil {
+		// Not nil even when empty, an empty array removes the variants.
+		update.Variants = append([]storage.Variant{}, save.LinkVariants(r.Variants)...)
+	}
+
+	return update
+}
+
+// Response is the link as saved after the update.
+type Response struct {
+	resp.Response
+	Alias          string            `json:"alias,omitempty"`
+	URL            string            `json:"url,omitempty"`
+	Targets        *storage.Targets  `json:"targets,omitempty"`
+	Variants       []storage.Variant `json:"variants,omitempty"`
+	StickyVariants bool              `json:"sticky_variants,omitempty"`
+}
+
+// URLUpdater is an interface for changing the destinations of an alias.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLUpdater
+type URLUpdater interface {
+	UpdateLink(alias string, update storage.LinkUpdate, ownerID int64) (storage.Link, error)
+}
+
+// @Summary      Change short URL destination
+// @Description  Меняет URL, адреса для платформ и варианты, на которые перенаправляет короткая ссылка
+// @Description  Закрепить варианты (sticky_variants) можно только у ссылки с вариантами
+// @Accept       json
+// @Produce      json
+// @Security     BasicAuth
+// @Security     BearerAuth
+// @Param        alias   path string  true "Short URL alias"
+// @Param        request body Request true "Новый URL"
+// @Success      200 {object} Response
+// @Failure      400 {object} Response
+// @Failure      404 {object} Response
+// @Failure      500 {object} Response
+// @Router       /url/{alias} [patch]
+func New(log *slog.Logger, urlUpdater URLUpdater) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.update.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+
+		var req Request
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("empty request"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", sl.Err(err))
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+			return
+		}
+
+		link, err := urlUpdater.UpdateLink(alias, req.LinkUpdate(), auth.OwnerID(r.Context()))
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, resp.Error("not found"))
+			return
+		}
+		if errors.Is(err, storage.ErrNoVariants) {
+			log.Info("sticky link without variants", slog.String("alias", alias))
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("only links with variants can be sticky"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to update url", sl.Err(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("failed to update url"))
+			return
+		}
+
+		log.Info("url updated", slog.String("alias", alias))
+
+		render.JSON(w, r, Response{
+			Response:       resp.OK(),
+			Alias:          link.Alias,
+			URL:            link.URL,
+			Targets:        link.Targets,
+			Variants:       link.Variants,
+			StickyVariants: link.StickyVariants,
+		})
+	}
+}
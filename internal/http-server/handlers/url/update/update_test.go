@@ -0,0 +1,217 @@
+package update_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/update"
+	"url-shortener/internal/http-server/handlers/url/update/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestUpdateHandler(t *testing.T) {
+	sticky := true
+
+	// saved is the link as stored after every successful update.
+	saved := storage.Link{
+		Alias: "abc",
+		URL:   "https://google.com",
+		LinkOptions: storage.LinkOptions{
+			Targets:        &storage.Targets{IOS: "https://apps.apple.com/app/id1"},
+			Variants:       []storage.Variant{{URL: "https://example.com/a", Weight: 3, Clicks: 5}},
+			StickyVariants: true,
+		},
+	}
+
+	cases := []struct {
+		name  string
+		alias string
+		body  string
+		// update is what the request must be turned into, nil for invalid requests.
+		update    *storage.LinkUpdate
+		respError string
+		respCode  int
+		mockError error
+	}{
+		{
+			name:     "Success",
+			alias:    "abc",
+			body:     `{"url": "https://google.com"}`,
+			update:   &storage.LinkUpdate{URL: "https://google.com"},
+			respCode: http.StatusOK,
+		},
+		{
+			name:     "Targets only",
+			alias:    "abc",
+			body:     `{"targets": {"ios": "https://apps.apple.com/app/id1"}}`,
+			update:   &storage.LinkUpdate{Targets: &storage.Targets{IOS: "https://apps.apple.com/app/id1"}},
+			respCode: http.StatusOK,
+		},
+		{
+			name:  "URL and targets",
+			alias: "abc",
+			body:  `{"url": "https://google.com", "targets": {"android": "https://play.google.com/store/apps/details?id=app"}}`,
+			update: &storage.LinkUpdate{
+				URL:     "https://google.com",
+				Targets: &storage.Targets{Android: "https://play.google.com/store/apps/details?id=app"},
+			},
+			respCode: http.StatusOK,
+		},
+		{
+			name:     "Remove targets",
+			alias:    "abc",
+			body:     `{"targets": {}}`,
+			update:   &storage.LinkUpdate{Targets: &storage.Targets{}},
+			respCode: http.StatusOK,
+		},
+		{
+			name:  "Variants only",
+			alias: "abc",
+			body:  `{"variants": [{"url": "https://example.com/a", "weight": 3}, {"url": "https://example.com/b", "weight": 1}], "sticky_variants": true}`,
+			update: &storage.LinkUpdate{
+				Variants: []storage.Variant{
+					{URL: "https://example.com/a", Weight: 3},
+					{URL: "https://example.com/b", Weight: 1},
+				},
+				StickyVariants: &sticky,
+			},
+			respCode: http.StatusOK,
+		},
+		{
+			name:     "Remove variants",
+			alias:    "abc",
+			body:     `{"variants": []}`,
+			update:   &storage.LinkUpdate{Variants: []storage.Variant{}},
+			respCode: http.StatusOK,
+		},
+		{
+			name:     "Stickiness only",
+			alias:    "abc",
+			body:     `{"sticky_variants": true}`,
+			update:   &storage.LinkUpdate{StickyVariants: &sticky},
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Sticky without variants",
+			alias:     "abc",
+			body:      `{"sticky_variants": true}`,
+			update:    &storage.LinkUpdate{StickyVariants: &sticky},
+			respError: "only links with variants can be sticky",
+			respCode:  http.StatusBadRequest,
+			mockError: storage.ErrNoVariants,
+		},
+		{
+			name:      "Sticky with variants removed",
+			alias:     "abc",
+			body:      `{"variants": [], "sticky_variants": true}`,
+			update:    &storage.LinkUpdate{Variants: []storage.Variant{}, StickyVariants: &sticky},
+			respError: "only links with variants can be sticky",
+			respCode:  http.StatusBadRequest,
+			mockError: storage.ErrNoVariants,
+		},
+		{
+			name:      "Invalid variant weight",
+			alias:     "abc",
+			body:      `{"variants": [{"url": "https://example.com/a", "weight": 1001}]}`,
+			respError: "field Weight must be at most 1000",
+			respCode:  http.StatusBadRequest,
+		},
+		{
+			name:      "Variant without weight",
+			alias:     "abc",
+			body:      `{"variants": [{"url": "https://example.com/a"}]}`,
+			respError: "field Weight is a required field",
+			respCode:  http.StatusBadRequest,
+		},
+		{
+			name:      "Nothing to update",
+			alias:     "abc",
+			body:      `{"url": ""}`,
+			respError: "field URL is required without all of: Targets Variants StickyVariants",
+			respCode:  http.StatusBadRequest,
+		},
+		{
+			name:      "Invalid URL",
+			alias:     "abc",
+			body:      `{"url": "some invalid URL"}`,
+			respError: "field URL is not a valid URL",
+			respCode:  http.StatusBadRequest,
+		},
+		{
+			name:      "Invalid target",
+			alias:     "abc",
+			body:      `{"targets": {"desktop": "not a url"}}`,
+			respError: "field Desktop is not a valid URL",
+			respCode:  http.StatusBadRequest,
+		},
+		{
+			name:      "Not found",
+			alias:     "missing",
+			body:      `{"url": "https://google.com"}`,
+			update:    &storage.LinkUpdate{URL: "https://google.com"},
+			respError: "not found",
+			respCode:  http.StatusNotFound,
+			mockError: storage.ErrURLNotFound,
+		},
+		{
+			name:      "UpdateLink Error",
+			alias:     "abc",
+			body:      `{"url": "https://google.com", "targets": {}}`,
+			update:    &storage.LinkUpdate{URL: "https://google.com", Targets: &storage.Targets{}},
+			respError: "failed to update url",
+			respCode:  http.StatusInternalServerError,
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlUpdaterMock := mocks.NewURLUpdater(t)
+
+			if tc.update != nil {
+				link := saved
+				if tc.mockError != nil {
+					link = storage.Link{}
+				}
+				urlUpdaterMock.On("UpdateLink", tc.alias, *tc.update, storage.AnyOwner).
+					Return(link, tc.mockError).
+					Once()
+			}
+
+			r := chi.NewRouter()
+			r.Patch("/url/{alias}", update.New(slogdiscard.NewDiscardLogger(), urlUpdaterMock))
+
+			req := httptest.NewRequest(http.MethodPatch, "/url/"+tc.alias, bytes.NewReader([]byte(tc.body)))
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp update.Response
+
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			if tc.respError == "" {
+				// The response shows the stored link, not the request.
+				require.Equal(t, saved.Alias, resp.Alias)
+				require.Equal(t, saved.URL, resp.URL)
+				require.Equal(t, saved.Targets, resp.Targets)
+				require.Equal(t, saved.Variants, resp.Variants)
+				require.Equal(t, saved.StickyVariants, resp.StickyVariants)
+			}
+		})
+	}
+}
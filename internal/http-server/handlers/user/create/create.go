@@ -0,0 +1,135 @@
+package create
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/lib/account"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+type Request struct {
+	Name     string `json:"name" validate:"required"`
+	Password string `json:"password" validate:"required"`
+	Role     string `json:"role,omitempty"`
+}
+
+type Response struct {
+	resp.Response
+	User *storage.User `json:"user,omitempty"`
+}
+
+// UserSaver is an interface for saving users.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=UserSaver
+type UserSaver interface {
+	SaveUser(user storage.User) (int64, error)
+}
+
+// @Summary      Create user
+// @Description  Создает пользователя, роль по умолчанию - user
+// @Accept       json
+// @Produce      json
+// @Security     BasicAuth
+// @Security     BearerAuth
+// @Param        request body Request true "Name, password and role"
+// @Success      200 {object} Response
+// @Failure      400 {object} Response
+// @Failure      409 {object} Response
+// @Failure      500 {object} Response
+// @Router       /admin/users [post]
+func New(log *slog.Logger, userSaver UserSaver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.user.create.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("empty request"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Info("invalid request", sl.Err(err))
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+			return
+		}
+
+		if req.Role == "" {
+			req.Role = account.RoleUser
+		}
+		if err := account.ValidateRole(req.Role); err != nil {
+			log.Info("invalid role", sl.Err(err))
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(fmt.Sprintf("invalid role: %s", req.Role)))
+			return
+		}
+
+		hash, err := account.HashPassword(req.Password)
+		if errors.Is(err, account.ErrPasswordTooShort) || errors.Is(err, account.ErrPasswordTooLong) {
+			log.Info("invalid password length")
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+		if err != nil {
+			log.Error("failed to hash password", sl.Err(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("internal error"))
+			return
+		}
+
+		user := storage.User{
+			Name:         req.Name,
+			Role:         req.Role,
+			PasswordHash: hash,
+			CreatedAt:    time.Now().UTC(),
+		}
+
+		user.ID, err = userSaver.SaveUser(user)
+		if errors.Is(err, storage.ErrUserExists) {
+			log.Info("user already exists", slog.String("name", req.Name))
+			w.WriteHeader(http.StatusConflict)
+			render.JSON(w, r, resp.Error("user already exists"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to save user", sl.Err(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("failed to save user"))
+			return
+		}
+
+		log.Info("user created", slog.Int64("id", user.ID), slog.String("name", user.Name))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			User:     &user,
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package create_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/user/create"
+	"url-shortener/internal/http-server/handlers/user/create/mocks"
+	"url-shortener/internal/lib/account"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestCreateHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		body      string
+		role      string
+		respError string
+		respCode  int
+		mockError error
+		noMock    bool
+	}{
+		{
+			name:     "Success",
+			body:     `{"name": "alice", "password": "alicepass"}`,
+			role:     account.RoleUser,
+			respCode: http.StatusOK,
+		},
+		{
+			name:     "Admin",
+			body:     `{"name": "root", "password": "rootpass", "role": "admin"}`,
+			role:     account.RoleAdmin,
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Unknown role",
+			body:      `{"name": "alice", "password": "alicepass", "role": "owner"}`,
+			respError: "invalid role: owner",
+			respCode:  http.StatusBadRequest,
+			noMock:    true,
+		},
+		{
+			name:      "Short password",
+			body:      `{"name": "alice", "password": "short"}`,
+			respError: account.ErrPasswordTooShort.Error(),
+			respCode:  http.StatusBadRequest,
+			noMock:    true,
+		},
+		{
+			name:      "Long password",
+			body:      `{"name": "alice", "password": "` + strings.Repeat("a", 73) + `"}`,
+			respError: account.ErrPasswordTooLong.Error(),
+			respCode:  http.StatusBadRequest,
+			noMock:    true,
+		},
+		{
+			name:      "No password",
+			body:      `{"name": "alice"}`,
+			respError: "field Password is a required field",
+			respCode:  http.StatusBadRequest,
+			noMock:    true,
+		},
+		{
+			name:      "Exists",
+			body:      `{"name": "alice", "password": "alicepass"}`,
+			role:      account.RoleUser,
+			respError: "user already exists",
+			respCode:  http.StatusConflict,
+			mockError: storage.ErrUserExists,
+		},
+		{
+			name:      "SaveUser Error",
+			body:      `{"name": "alice", "password": "alicepass"}`,
+			role:      account.RoleUser,
+			respError: "failed to save user",
+			respCode:  http.StatusInternalServerError,
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			userSaverMock := mocks.NewUserSaver(t)
+
+			if !tc.noMock {
+				userSaverMock.On("SaveUser", mock.MatchedBy(func(user storage.User) bool {
+					return user.Role == tc.role && user.PasswordHash != "" && !bytes.Contains([]byte(tc.body), []byte(user.PasswordHash))
+				})).
+					Return(int64(5), tc.mockError).
+					Once()
+			}
+
+			handler := create.New(slogdiscard.NewDiscardLogger(), userSaverMock)
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/users", bytes.NewReader([]byte(tc.body)))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var body create.Response
+
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+
+			require.Equal(t, tc.respError, body.Error)
+			require.NotContains(t, rr.Body.String(), "password_hash")
+
+			if tc.respError == "" {
+				require.Equal(t, int64(5), body.User.ID)
+			}
+		})
+	}
+}
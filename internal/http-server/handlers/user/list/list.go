@@ -0,0 +1,57 @@
+package list
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+type Response struct {
+	resp.Response
+	Users []storage.User `json:"users,omitempty"`
+}
+
+// UserLister is an interface for listing users.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=UserLister
+type UserLister interface {
+	ListUsers() ([]storage.User, error)
+}
+
+// @Summary      List users
+// @Description  Возвращает всех пользователей без хешей паролей
+// @Produce      json
+// @Security     BasicAuth
+// @Security     BearerAuth
+// @Success      200 {object} Response
+// @Failure      500 {object} Response
+// @Router       /admin/users [get]
+func New(log *slog.Logger, userLister UserLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.user.list.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		users, err := userLister.ListUsers()
+		if err != nil {
+			log.Error("failed to list users", sl.Err(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("internal error"))
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Users:    users,
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package list_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/user/list"
+	"url-shortener/internal/http-server/handlers/user/list/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestListHandler(t *testing.T) {
+	users := []storage.User{
+		{ID: 1, Name: "alice", Role: "user", CreatedAt: time.Now().UTC()},
+	}
+
+	cases := []struct {
+		name      string
+		respError string
+		respCode  int
+		mockUsers []storage.User
+		mockError error
+	}{
+		{
+			name:      "Success",
+			respCode:  http.StatusOK,
+			mockUsers: users,
+		},
+		{
+			name:      "ListUsers Error",
+			respError: "internal error",
+			respCode:  http.StatusInternalServerError,
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			userListerMock := mocks.NewUserLister(t)
+			userListerMock.On("ListUsers").
+				Return(tc.mockUsers, tc.mockError).
+				Once()
+
+			handler := list.New(slogdiscard.NewDiscardLogger(), userListerMock)
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var body list.Response
+
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+
+			require.Equal(t, tc.respError, body.Error)
+			require.Len(t, body.Users, len(tc.mockUsers))
+		})
+	}
+}
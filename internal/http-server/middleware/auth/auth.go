@@ -0,0 +1,206 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/lib/account"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+const realm = "url-shortener"
+
+// Principal is the authenticated client of a request.
+type Principal struct {
+	// Name is the basic auth user or the API key name.
+	Name string
+	// UserID is the account the request acts for, zero for the configured
+	// basic auth users and for API keys not tied to a user.
+	UserID int64
+	// KeyID is the API key used, zero for basic auth.
+	KeyID  int64
+	Scopes []string
+}
+
+// OwnerID returns whose links the principal may manage: storage.AnyOwner for
+// admins, its own user, or storage.NoOwner for credentials not tied to a user.
+func (p Principal) OwnerID() int64 {
+	switch {
+	case apikey.HasScope(p.Scopes, apikey.ScopeAdmin):
+		return storage.AnyOwner
+	case p.UserID == 0:
+		return storage.NoOwner
+	default:
+		return p.UserID
+	}
+}
+
+// CreatorID returns the owner of the links the principal creates: its own
+// user, or no owner for admins and credentials not tied to a user. Links
+// without an owner can only be managed by admins, see OwnerID.
+func (p Principal) CreatorID() int64 {
+	return p.UserID
+}
+
+type ctxKey struct{}
+
+// NewContext returns a copy of ctx carrying p.
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, ctxKey{}, p)
+}
+
+// FromContext returns the principal stored by the middleware.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(ctxKey{}).(Principal)
+
+	return p, ok
+}
+
+// OwnerID returns the owner the request is restricted to. Requests that did
+// not pass through New are not restricted.
+func OwnerID(ctx context.Context) int64 {
+	p, ok := FromContext(ctx)
+	if !ok {
+		return storage.AnyOwner
+	}
+
+	return p.OwnerID()
+}
+
+// CreatorID returns the owner of the links created by the request, links
+// created outside of New have no owner.
+func CreatorID(ctx context.Context) int64 {
+	p, _ := FromContext(ctx)
+
+	return p.CreatorID()
+}
+
+// CredentialStore is an interface for looking up API keys and users.
+type CredentialStore interface {
+	GetAPIKeyByHash(hash string) (storage.APIKey, error)
+	TouchAPIKey(id int64, usedAt time.Time) error
+	GetUserByName(name string) (storage.User, error)
+}
+
+// New authenticates requests with an API key sent as "Authorization: Bearer"
+// or with basic auth. The configured users are granted every scope, stored
+// users the scopes of their role.
+func New(log *slog.Logger, creds CredentialStore, users map[string]string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/auth"),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			log := log.With(slog.String("request_id", middleware.GetReqID(r.Context())))
+
+			p, err := authenticate(log, r, creds, users)
+			if errors.Is(err, errUnauthorized) {
+				log.Info("unauthorized request")
+				w.Header().Add("WWW-Authenticate", `Bearer realm="`+realm+`"`)
+				w.Header().Add("WWW-Authenticate", `Basic realm="`+realm+`"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				render.JSON(w, r, resp.Error("unauthorized"))
+				return
+			}
+			if err != nil {
+				log.Error("failed to authenticate", sl.Err(err))
+				w.WriteHeader(http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error("internal error"))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), p)))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// RequireScope rejects requests whose principal lacks scope with 403.
+// It must be used after New.
+func RequireScope(scope string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			p, ok := FromContext(r.Context())
+			if !ok || !apikey.HasScope(p.Scopes, scope) {
+				w.WriteHeader(http.StatusForbidden)
+				render.JSON(w, r, resp.Error("missing scope: "+scope))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+var errUnauthorized = errors.New("unauthorized")
+
+// touchInterval is how stale the last use of an API key may get before it
+// is updated, so busy keys do not cost a write per request.
+const touchInterval = time.Minute
+
+func authenticate(log *slog.Logger, r *http.Request, creds CredentialStore, users map[string]string) (Principal, error) {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		key, err := creds.GetAPIKeyByHash(apikey.Hash(token))
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			return Principal{}, errUnauthorized
+		}
+		if err != nil {
+			return Principal{}, err
+		}
+		if key.RevokedAt != nil {
+			return Principal{}, errUnauthorized
+		}
+
+		now := time.Now().UTC()
+		if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= touchInterval {
+			// The last use is informational, failing to record it does
+			// not reject the request.
+			if err := creds.TouchAPIKey(key.ID, now); err != nil {
+				log.Error("failed to record api key use", slog.Int64("key_id", key.ID), sl.Err(err))
+			}
+		}
+
+		return Principal{Name: key.Name, UserID: key.UserID, KeyID: key.ID, Scopes: key.Scopes}, nil
+	}
+
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		return Principal{}, errUnauthorized
+	}
+
+	if expected, ok := users[user]; ok {
+		if subtle.ConstantTimeCompare([]byte(pass), []byte(expected)) != 1 {
+			return Principal{}, errUnauthorized
+		}
+
+		return Principal{Name: user, Scopes: []string{apikey.ScopeAdmin}}, nil
+	}
+
+	u, err := creds.GetUserByName(user)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return Principal{}, errUnauthorized
+	}
+	if err != nil {
+		return Principal{}, err
+	}
+	if !account.CheckPassword(u.PasswordHash, pass) {
+		return Principal{}, errUnauthorized
+	}
+
+	return Principal{Name: u.Name, UserID: u.ID, Scopes: account.Scopes(u.Role)}, nil
+}
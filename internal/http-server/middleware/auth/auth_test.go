@@ -0,0 +1,170 @@
+package auth_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/account"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+)
+
+func TestAuth(t *testing.T) {
+	store := memory.New(0, nil)
+
+	newKey := func(scopes ...string) string {
+		key, prefix, hash, err := apikey.Generate()
+		require.NoError(t, err)
+
+		_, err = store.SaveAPIKey(storage.APIKey{Name: "svc", Prefix: prefix, Scopes: scopes, CreatedAt: time.Now()}, hash)
+		require.NoError(t, err)
+
+		return key
+	}
+
+	readKey := newKey(apikey.ScopeRead)
+	adminKey := newKey(apikey.ScopeAdmin)
+	revokedKey := newKey(apikey.ScopeRead)
+	require.NoError(t, store.RevokeAPIKey(3, time.Now()))
+
+	hash, err := account.HashPassword("alicepass")
+	require.NoError(t, err)
+	aliceID, err := store.SaveUser(storage.User{Name: "alice", PasswordHash: hash, Role: account.RoleUser, CreatedAt: time.Now()})
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Use(auth.New(slogdiscard.NewDiscardLogger(), store, map[string]string{"myuser": "mypass"}))
+	r.With(auth.RequireScope(apikey.ScopeRead)).Get("/read", func(w http.ResponseWriter, r *http.Request) {
+		p, ok := auth.FromContext(r.Context())
+		require.True(t, ok)
+		_, _ = w.Write([]byte(p.Name))
+	})
+	r.Get("/owner", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, aliceID, auth.OwnerID(r.Context()))
+	})
+	r.With(auth.RequireScope(apikey.ScopeDelete)).Get("/delete", func(http.ResponseWriter, *http.Request) {})
+
+	cases := []struct {
+		name     string
+		path     string
+		bearer   string
+		user     string
+		password string
+		respCode int
+	}{
+		{name: "No credentials", path: "/read", respCode: http.StatusUnauthorized},
+		{name: "Key with scope", path: "/read", bearer: readKey, respCode: http.StatusOK},
+		{name: "Key without scope", path: "/delete", bearer: readKey, respCode: http.StatusForbidden},
+		{name: "Admin key", path: "/delete", bearer: adminKey, respCode: http.StatusOK},
+		{name: "Revoked key", path: "/read", bearer: revokedKey, respCode: http.StatusUnauthorized},
+		{name: "Unknown key", path: "/read", bearer: "usk_unknown", respCode: http.StatusUnauthorized},
+		{name: "Basic auth", path: "/delete", user: "myuser", password: "mypass", respCode: http.StatusOK},
+		{name: "Wrong password", path: "/read", user: "myuser", password: "nope", respCode: http.StatusUnauthorized},
+		{name: "Stored user", path: "/read", user: "alice", password: "alicepass", respCode: http.StatusOK},
+		{name: "Stored user owner", path: "/owner", user: "alice", password: "alicepass", respCode: http.StatusOK},
+		{name: "Stored user wrong password", path: "/read", user: "alice", password: "nope", respCode: http.StatusUnauthorized},
+		{name: "Unknown user", path: "/read", user: "bob", password: "bobpass", respCode: http.StatusUnauthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.bearer != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.bearer)
+			}
+			if tc.user != "" {
+				req.SetBasicAuth(tc.user, tc.password)
+			}
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+		})
+	}
+
+	key, err := store.GetAPIKeyByHash(apikey.Hash(readKey))
+	require.NoError(t, err)
+	require.NotNil(t, key.LastUsedAt)
+}
+
+// touchCounter counts the recorded uses of API keys and fails them with err.
+type touchCounter struct {
+	*memory.Storage
+	touches int
+	err     error
+}
+
+func (s *touchCounter) TouchAPIKey(id int64, usedAt time.Time) error {
+	s.touches++
+	if s.err != nil {
+		return s.err
+	}
+
+	return s.Storage.TouchAPIKey(id, usedAt)
+}
+
+func TestAuth_TouchAPIKey(t *testing.T) {
+	cases := []struct {
+		name     string
+		lastUsed time.Duration
+		touchErr error
+		touches  int
+	}{
+		{name: "Never used", touches: 1},
+		{name: "Used recently", lastUsed: 10 * time.Second, touches: 0},
+		{name: "Used a while ago", lastUsed: 2 * time.Minute, touches: 1},
+		{name: "Touch fails", touchErr: errors.New("database is locked"), touches: 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &touchCounter{Storage: memory.New(0, nil), err: tc.touchErr}
+
+			token, prefix, hash, err := apikey.Generate()
+			require.NoError(t, err)
+			id, err := store.SaveAPIKey(storage.APIKey{Name: "svc", Prefix: prefix, Scopes: []string{apikey.ScopeRead}, CreatedAt: time.Now()}, hash)
+			require.NoError(t, err)
+			if tc.lastUsed != 0 {
+				require.NoError(t, store.Storage.TouchAPIKey(id, time.Now().UTC().Add(-tc.lastUsed)))
+			}
+
+			handler := auth.New(slogdiscard.NewDiscardLogger(), store, nil)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+			require.Equal(t, tc.touches, store.touches)
+		})
+	}
+}
+
+func TestPrincipal_OwnerID(t *testing.T) {
+	require.Equal(t, int64(7), auth.Principal{UserID: 7, Scopes: []string{apikey.ScopeRead}}.OwnerID())
+	require.Equal(t, storage.AnyOwner, auth.Principal{UserID: 7, Scopes: []string{apikey.ScopeAdmin}}.OwnerID())
+	require.Equal(t, storage.AnyOwner, auth.Principal{Scopes: []string{apikey.ScopeAdmin}}.OwnerID())
+	require.Equal(t, storage.NoOwner, auth.Principal{Scopes: []string{apikey.ScopeRead, apikey.ScopeUpdate, apikey.ScopeDelete}}.OwnerID())
+}
+
+func TestPrincipal_CreatorID(t *testing.T) {
+	require.Equal(t, int64(7), auth.Principal{UserID: 7, Scopes: []string{apikey.ScopeAdmin}}.CreatorID())
+	require.Zero(t, auth.Principal{Scopes: []string{apikey.ScopeAdmin}}.CreatorID())
+
+	// Links created without a user stay out of reach of other userless keys.
+	creator := auth.Principal{Scopes: []string{apikey.ScopeCreate, apikey.ScopeUpdate}}
+	link := storage.Link{LinkOptions: storage.LinkOptions{OwnerID: creator.CreatorID()}}
+	require.False(t, link.OwnedBy(creator.OwnerID()))
+	require.True(t, link.OwnedBy(auth.Principal{Scopes: []string{apikey.ScopeAdmin}}.OwnerID()))
+}
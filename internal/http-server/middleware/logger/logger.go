@@ -8,7 +8,13 @@ import (
 	"golang.org/x/exp/slog"
 )
 
-func New(log *slog.Logger) func(next http.Handler) http.Handler {
+// RequestObserver is notified about every completed request, for example to
+// collect metrics from the status the logger already captures.
+type RequestObserver interface {
+	ObserveRequest(r *http.Request, status int, duration time.Duration)
+}
+
+func New(log *slog.Logger, observers ...RequestObserver) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		log := log.With(
 			slog.String("component", "middleware/logger"),
@@ -28,11 +34,17 @@ func New(log *slog.Logger) func(next http.Handler) http.Handler {
 
 			t1 := time.Now()
 			defer func() {
+				duration := time.Since(t1)
+
 				entry.Info("request completed",
 					slog.Int("status", ww.Status()),
 					slog.Int("bytes", ww.BytesWritten()),
-					slog.String("duration", time.Since(t1).String()),
+					slog.String("duration", duration.String()),
 				)
+
+				for _, o := range observers {
+					o.ObserveRequest(r, ww.Status(), duration)
+				}
 			}()
 
 			next.ServeHTTP(ww, r)
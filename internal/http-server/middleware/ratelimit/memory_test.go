@@ -0,0 +1,86 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/middleware/ratelimit"
+)
+
+func TestMemoryStore_Take(t *testing.T) {
+	s := ratelimit.NewMemoryStore()
+	limit := ratelimit.Limit{Requests: 60, Period: time.Minute, Burst: 3}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 2; i >= 0; i-- {
+		res, err := s.Take("a", limit, 1, now)
+		require.NoError(t, err)
+		require.True(t, res.Allowed)
+		require.Equal(t, 3, res.Limit)
+		require.Equal(t, i, res.Remaining)
+	}
+
+	res, err := s.Take("a", limit, 1, now)
+	require.NoError(t, err)
+	require.False(t, res.Allowed)
+	require.Equal(t, time.Second, res.RetryAfter)
+	require.Equal(t, 3*time.Second, res.Reset)
+
+	// Other keys have their own bucket.
+	res, err = s.Take("b", limit, 1, now)
+	require.NoError(t, err)
+	require.True(t, res.Allowed)
+
+	// One token is added per second.
+	res, err = s.Take("a", limit, 1, now.Add(time.Second))
+	require.NoError(t, err)
+	require.True(t, res.Allowed)
+	require.Zero(t, res.Remaining)
+
+	// The bucket never holds more than Burst tokens.
+	res, err = s.Take("a", limit, 1, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 2, res.Remaining)
+}
+
+func TestMemoryStore_TakeMany(t *testing.T) {
+	s := ratelimit.NewMemoryStore()
+	limit := ratelimit.Limit{Requests: 60, Period: time.Minute, Burst: 3}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	res, err := s.Take("a", limit, 2, now)
+	require.NoError(t, err)
+	require.True(t, res.Allowed)
+	require.Equal(t, 1, res.Remaining)
+
+	// Nothing is taken when fewer tokens are left than asked for.
+	res, err = s.Take("a", limit, 2, now)
+	require.NoError(t, err)
+	require.False(t, res.Allowed)
+	require.Equal(t, 1, res.Remaining)
+	require.Equal(t, time.Second, res.RetryAfter)
+
+	// More than the bucket holds is never allowed.
+	res, err = s.Take("b", limit, 4, now)
+	require.NoError(t, err)
+	require.False(t, res.Allowed)
+	require.Zero(t, res.RetryAfter)
+}
+
+func TestMemoryStore_Sweep(t *testing.T) {
+	s := ratelimit.NewMemoryStore()
+	limit := ratelimit.Limit{Requests: 1, Period: time.Second}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	_, err := s.Take("a", limit, 1, now)
+	require.NoError(t, err)
+	_, err = s.Take("b", limit, 1, now.Add(time.Minute))
+	require.NoError(t, err)
+	_, err = s.Take("c", limit, 1, now.Add(2*time.Minute))
+	require.NoError(t, err)
+
+	// "a" and "b" have refilled and were dropped.
+	require.Equal(t, 1, s.Len())
+}
@@ -0,0 +1,189 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+)
+
+// Limit allows Requests per Period on average and bursts of up to Burst
+// requests at once.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	// Burst is the bucket size, zero means Requests.
+	Burst int
+}
+
+// Enabled reports whether the limit restricts anything.
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Period > 0
+}
+
+// rate is the number of tokens added per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+
+	return l.Requests
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed bool
+	// Limit is the bucket size.
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until a token is available, zero when allowed.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// Store keeps the token buckets. MemoryStore keeps them in process, a shared
+// store lets several instances enforce one limit.
+type Store interface {
+	// Take takes n tokens from the bucket of key at now, or none if fewer
+	// are left.
+	Take(key string, limit Limit, n int, now time.Time) (Result, error)
+}
+
+// KeyFunc returns the bucket key of a request.
+type KeyFunc func(r *http.Request) string
+
+// ClientKey identifies authenticated requests by their API key or user and
+// all other requests by the client IP. It must run after auth.New to see
+// the credential. The IP is taken from RemoteAddr, so behind a proxy the
+// middleware.RealIP middleware has to run first (http_server.trust_proxy).
+func ClientKey(r *http.Request) string {
+	if p, ok := auth.FromContext(r.Context()); ok {
+		if p.KeyID != 0 {
+			return "key:" + strconv.FormatInt(p.KeyID, 10)
+		}
+
+		return "user:" + p.Name
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+// Limiter limits requests per key with a token bucket. Requests are let
+// through when the store fails, so an unavailable store does not take the
+// service down.
+type Limiter struct {
+	log   *slog.Logger
+	store Store
+	name  string
+	limit Limit
+	key   KeyFunc
+}
+
+// NewLimiter creates a Limiter. name separates the buckets of different
+// routes sharing a store.
+func NewLimiter(log *slog.Logger, store Store, name string, limit Limit, key KeyFunc) *Limiter {
+	return &Limiter{
+		log: log.With(
+			slog.String("component", "middleware/ratelimit"),
+			slog.String("limiter", name),
+		),
+		store: store,
+		name:  name,
+		limit: limit,
+		key:   key,
+	}
+}
+
+// New returns the middleware of a Limiter, every request costs one token.
+func New(log *slog.Logger, store Store, name string, limit Limit, key KeyFunc) func(next http.Handler) http.Handler {
+	return NewLimiter(log, store, name, limit, key).Middleware
+}
+
+// Middleware takes one token per request.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	if !l.limit.Enabled() {
+		return next
+	}
+
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if l.Charge(w, r, 1) {
+			next.ServeHTTP(w, r)
+		}
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// Charge takes n tokens for r, for requests that cost more than one. It
+// sets the rate limit headers and, when the caller is over the limit, writes
+// the 429 response and returns false.
+func (l *Limiter) Charge(w http.ResponseWriter, r *http.Request, n int) bool {
+	if !l.limit.Enabled() {
+		return true
+	}
+
+	k := l.key(r)
+
+	res, err := l.store.Take(l.name+":"+k, l.limit, n, time.Now())
+	if err != nil {
+		l.log.Error("failed to take token, letting the request through",
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.Err(err),
+		)
+		return true
+	}
+
+	h := w.Header()
+	h.Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("X-RateLimit-Reset", seconds(res.Reset))
+
+	if res.Allowed {
+		return true
+	}
+
+	l.log.Info("rate limit exceeded",
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+		slog.String("key", k),
+		slog.Int("cost", n),
+	)
+
+	// A request costing more than the bucket holds can never pass, so there
+	// is nothing to wait for.
+	if n > res.Limit {
+		w.WriteHeader(http.StatusTooManyRequests)
+		render.JSON(w, r, resp.Error(fmt.Sprintf("rate limit exceeded: request costs %d, at most %d allowed at once", n, res.Limit)))
+		return false
+	}
+
+	h.Set("Retry-After", seconds(res.RetryAfter))
+	w.WriteHeader(http.StatusTooManyRequests)
+	render.JSON(w, r, resp.Error("rate limit exceeded"))
+
+	return false
+}
+
+// seconds formats d as whole seconds, rounded up so clients never retry early.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,143 @@
+package ratelimit_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/http-server/middleware/ratelimit"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestRateLimit(t *testing.T) {
+	limit := ratelimit.Limit{Requests: 2, Period: time.Minute}
+	limiter := ratelimit.New(slogdiscard.NewDiscardLogger(), ratelimit.NewMemoryStore(), "save", limit, ratelimit.ClientKey)
+	handler := limiter(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	request := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/url", nil)
+		req.RemoteAddr = remoteAddr
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	rr := request("10.0.0.1:1234")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "2", rr.Header().Get("X-RateLimit-Limit"))
+	require.Equal(t, "1", rr.Header().Get("X-RateLimit-Remaining"))
+	require.Equal(t, "30", rr.Header().Get("X-RateLimit-Reset"))
+
+	// The port does not matter, the client is identified by its IP.
+	rr = request("10.0.0.1:4321")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	rr = request("10.0.0.1:1234")
+	require.Equal(t, http.StatusTooManyRequests, rr.Code)
+	require.Equal(t, "0", rr.Header().Get("X-RateLimit-Remaining"))
+	require.Equal(t, "30", rr.Header().Get("Retry-After"))
+	require.JSONEq(t, `{"status":"Error","error":"rate limit exceeded"}`, rr.Body.String())
+
+	rr = request("10.0.0.2:1234")
+	require.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestRateLimit_Disabled(t *testing.T) {
+	limiter := ratelimit.New(slogdiscard.NewDiscardLogger(), ratelimit.NewMemoryStore(), "save", ratelimit.Limit{}, ratelimit.ClientKey)
+	handler := limiter(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Empty(t, rr.Header().Get("X-RateLimit-Limit"))
+}
+
+func TestLimiter_Charge(t *testing.T) {
+	limit := ratelimit.Limit{Requests: 3, Period: time.Minute}
+	limiter := ratelimit.NewLimiter(slogdiscard.NewDiscardLogger(), ratelimit.NewMemoryStore(), "save", limit, ratelimit.ClientKey)
+
+	charge := func(n int) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		ok := limiter.Charge(rr, httptest.NewRequest(http.MethodPost, "/url/batch", nil), n)
+		require.Equal(t, ok, rr.Code == http.StatusOK)
+
+		return rr
+	}
+
+	rr := charge(2)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "1", rr.Header().Get("X-RateLimit-Remaining"))
+
+	rr = charge(2)
+	require.Equal(t, http.StatusTooManyRequests, rr.Code)
+	require.Equal(t, "20", rr.Header().Get("Retry-After"))
+
+	rr = charge(4)
+	require.Equal(t, http.StatusTooManyRequests, rr.Code)
+	require.Empty(t, rr.Header().Get("Retry-After"))
+	require.JSONEq(t, `{"status":"Error","error":"rate limit exceeded: request costs 4, at most 3 allowed at once"}`, rr.Body.String())
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(string, ratelimit.Limit, int, time.Time) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store is down")
+}
+
+func TestRateLimit_StoreError(t *testing.T) {
+	limit := ratelimit.Limit{Requests: 1, Period: time.Minute}
+	limiter := ratelimit.New(slogdiscard.NewDiscardLogger(), failingStore{}, "save", limit, ratelimit.ClientKey)
+	handler := limiter(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestClientKey(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	require.Equal(t, "ip:10.0.0.1", ratelimit.ClientKey(req))
+
+	ctx := auth.NewContext(req.Context(), auth.Principal{Name: "ci", KeyID: 4})
+	require.Equal(t, "key:4", ratelimit.ClientKey(req.WithContext(ctx)))
+
+	ctx = auth.NewContext(req.Context(), auth.Principal{Name: "alice", UserID: 2})
+	require.Equal(t, "user:alice", ratelimit.ClientKey(req.WithContext(ctx)))
+}
+
+func TestClientKey_BehindProxy(t *testing.T) {
+	limit := ratelimit.Limit{Requests: 1, Period: time.Minute}
+	limiter := ratelimit.New(slogdiscard.NewDiscardLogger(), ratelimit.NewMemoryStore(), "save", limit, ratelimit.ClientKey)
+	handler := middleware.RealIP(limiter(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})))
+
+	request := func(header, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/url", nil)
+		// Every request comes from the proxy.
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set(header, ip)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	rr := request("X-Forwarded-For", "203.0.113.1, 10.0.0.1")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	// Clients behind the same proxy get their own buckets.
+	rr = request("X-Forwarded-For", "203.0.113.2")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	rr = request("X-Real-IP", "203.0.113.1")
+	require.Equal(t, http.StatusTooManyRequests, rr.Code)
+}
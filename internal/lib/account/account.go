@@ -0,0 +1,85 @@
+package account
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"url-shortener/internal/lib/apikey"
+)
+
+const (
+	// RoleUser may manage only the links it owns.
+	RoleUser = "user"
+	// RoleAdmin sees and manages every link, key and user.
+	RoleAdmin = "admin"
+)
+
+// Roles lists every known role.
+var Roles = []string{RoleUser, RoleAdmin}
+
+// MinPasswordLength is the shortest password in characters accepted for new users.
+const MinPasswordLength = 8
+
+// MaxPasswordLength is the longest password in bytes bcrypt can hash.
+const MaxPasswordLength = 72
+
+var (
+	ErrUnknownRole      = errors.New("unknown role")
+	ErrPasswordTooShort = fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	ErrPasswordTooLong  = fmt.Errorf("password must be at most %d bytes", MaxPasswordLength)
+	ErrScopeNotAllowed  = errors.New("admin scope requires an admin user")
+)
+
+// ValidateRole checks that role is a known role.
+func ValidateRole(role string) error {
+	if !slices.Contains(Roles, role) {
+		return fmt.Errorf("%w: %q", ErrUnknownRole, role)
+	}
+
+	return nil
+}
+
+// HashPassword returns the bcrypt hash to store for password.
+func HashPassword(password string) (string, error) {
+	if utf8.RuneCountInString(password) < MinPasswordLength {
+		return "", ErrPasswordTooShort
+	}
+	if len(password) > MaxPasswordLength {
+		return "", ErrPasswordTooLong
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("account.HashPassword: %w", err)
+	}
+
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password matches hash.
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// Scopes returns the API scopes granted to a role.
+func Scopes(role string) []string {
+	if role == RoleAdmin {
+		return []string{apikey.ScopeAdmin}
+	}
+
+	return []string{apikey.ScopeCreate, apikey.ScopeRead, apikey.ScopeUpdate, apikey.ScopeDelete}
+}
+
+// CheckKeyScopes checks that an API key issued to a user with role does not
+// grant more than the role has.
+func CheckKeyScopes(role string, scopes []string) error {
+	if role != RoleAdmin && apikey.HasScope(scopes, apikey.ScopeAdmin) {
+		return ErrScopeNotAllowed
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package account_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/account"
+	"url-shortener/internal/lib/apikey"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := account.HashPassword("correct horse")
+	require.NoError(t, err)
+	require.NotContains(t, hash, "correct horse")
+
+	require.True(t, account.CheckPassword(hash, "correct horse"))
+	require.False(t, account.CheckPassword(hash, "battery staple"))
+	require.False(t, account.CheckPassword("not a hash", "correct horse"))
+
+	_, err = account.HashPassword("short")
+	require.ErrorIs(t, err, account.ErrPasswordTooShort)
+
+	// The minimum counts characters, not bytes.
+	_, err = account.HashPassword("пароль")
+	require.ErrorIs(t, err, account.ErrPasswordTooShort)
+	_, err = account.HashPassword("пароль12")
+	require.NoError(t, err)
+
+	_, err = account.HashPassword(strings.Repeat("a", account.MaxPasswordLength+1))
+	require.ErrorIs(t, err, account.ErrPasswordTooLong)
+}
+
+func TestValidateRole(t *testing.T) {
+	require.NoError(t, account.ValidateRole(account.RoleUser))
+	require.NoError(t, account.ValidateRole(account.RoleAdmin))
+	require.ErrorIs(t, account.ValidateRole("root"), account.ErrUnknownRole)
+}
+
+func TestScopes(t *testing.T) {
+	require.True(t, apikey.HasScope(account.Scopes(account.RoleAdmin), apikey.ScopeAdmin))
+	require.True(t, apikey.HasScope(account.Scopes(account.RoleUser), apikey.ScopeDelete))
+	require.False(t, apikey.HasScope(account.Scopes(account.RoleUser), apikey.ScopeAdmin))
+}
+
+func TestCheckKeyScopes(t *testing.T) {
+	require.NoError(t, account.CheckKeyScopes(account.RoleUser, []string{apikey.ScopeRead}))
+	require.NoError(t, account.CheckKeyScopes(account.RoleAdmin, []string{apikey.ScopeAdmin}))
+	require.ErrorIs(t, account.CheckKeyScopes(account.RoleUser, []string{apikey.ScopeAdmin}), account.ErrScopeNotAllowed)
+}
@@ -37,8 +37,20 @@ func ValidationError(errs validator.ValidationErrors) Response {
 		switch err.ActualTag() {
 		case "required":
 			errMsgs = append(errMsgs, fmt.Sprintf("field %s is a required field", err.Field()))
+		case "required_without":
+			errMsgs = append(errMsgs, fmt.Sprintf("field %s is required without %s", err.Field(), err.Param()))
 		case "url":
 			errMsgs = append(errMsgs, fmt.Sprintf("field %s is not a valid URL", err.Field()))
+		case "oneof":
+			errMsgs = append(errMsgs, fmt.Sprintf("field %s must be one of: %s", err.Field(), err.Param()))
+		case "min":
+			errMsgs = append(errMsgs, fmt.Sprintf("field %s must be at least %s", err.Field(), err.Param()))
+		case "max":
+			errMsgs = append(errMsgs, fmt.Sprintf("field %s must be at most %s", err.Field(), err.Param()))
+		case "required_without_all":
+			errMsgs = append(errMsgs, fmt.Sprintf("field %s is required without all of: %s", err.Field(), err.Param()))
+		case "excluded_without":
+			errMsgs = append(errMsgs, fmt.Sprintf("field %s is only allowed with %s", err.Field(), err.Param()))
 		default:
 			errMsgs = append(errMsgs, fmt.Sprintf("field %s is not valid", err.Field()))
 		}
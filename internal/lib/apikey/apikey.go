@@ -0,0 +1,75 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+const (
+	ScopeCreate = "create"
+	ScopeRead   = "read"
+	ScopeUpdate = "update"
+	ScopeDelete = "delete"
+	// ScopeAdmin grants every other scope and access to key management.
+	ScopeAdmin = "admin"
+)
+
+// Scopes lists every known scope.
+var Scopes = []string{ScopeCreate, ScopeRead, ScopeUpdate, ScopeDelete, ScopeAdmin}
+
+var (
+	ErrNoScopes     = errors.New("at least one scope is required")
+	ErrUnknownScope = errors.New("unknown scope")
+)
+
+const (
+	keyPrefix = "usk_"
+	// secretBytes of randomness make the key infeasible to guess, so a fast
+	// hash is enough to store it.
+	secretBytes = 24
+	// prefixLength is how much of the key is kept in clear to identify it.
+	prefixLength = len(keyPrefix) + 8
+)
+
+// Generate returns a new random key, its displayable prefix and the hash to store.
+func Generate() (key, prefix, hash string, err error) {
+	secret := make([]byte, secretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", fmt.Errorf("apikey.Generate: %w", err)
+	}
+
+	key = keyPrefix + hex.EncodeToString(secret)
+
+	return key, key[:prefixLength], Hash(key), nil
+}
+
+// Hash returns the hash a key is stored and looked up by.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidateScopes checks that scopes is a non-empty list of known scopes.
+func ValidateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return ErrNoScopes
+	}
+
+	for _, s := range scopes {
+		if !slices.Contains(Scopes, s) {
+			return fmt.Errorf("%w: %q", ErrUnknownScope, s)
+		}
+	}
+
+	return nil
+}
+
+// HasScope reports whether granted allows scope.
+func HasScope(granted []string, scope string) bool {
+	return slices.Contains(granted, scope) || slices.Contains(granted, ScopeAdmin)
+}
@@ -0,0 +1,54 @@
+package apikey_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/apikey"
+)
+
+func TestGenerate(t *testing.T) {
+	key, prefix, hash, err := apikey.Generate()
+	require.NoError(t, err)
+
+	require.True(t, strings.HasPrefix(key, prefix))
+	require.True(t, strings.HasPrefix(key, "usk_"))
+	require.Equal(t, apikey.Hash(key), hash)
+	require.NotContains(t, hash, key)
+
+	other, _, _, err := apikey.Generate()
+	require.NoError(t, err)
+	require.NotEqual(t, key, other)
+}
+
+func TestValidateScopes(t *testing.T) {
+	cases := []struct {
+		name   string
+		scopes []string
+		err    error
+	}{
+		{name: "Valid", scopes: []string{"create", "read"}},
+		{name: "Admin", scopes: []string{"admin"}},
+		{name: "Empty", err: apikey.ErrNoScopes},
+		{name: "Unknown", scopes: []string{"read", "write"}, err: apikey.ErrUnknownScope},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := apikey.ValidateScopes(tc.scopes)
+			if tc.err == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	require.True(t, apikey.HasScope([]string{"read"}, "read"))
+	require.False(t, apikey.HasScope([]string{"read"}, "delete"))
+	require.True(t, apikey.HasScope([]string{"admin"}, "delete"))
+}
@@ -0,0 +1,93 @@
+package customalias
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidCharset = errors.New("alias may only contain letters, digits, '-' and '_'")
+	ErrInvalidLength  = errors.New("alias has invalid length")
+	ErrReserved       = errors.New("alias is reserved")
+)
+
+// DefaultReserved protects the first path segment of the service's own
+// routes, so a custom alias can never shadow them.
+var DefaultReserved = []string{
+	"url",
+	"swagger",
+	"health",
+	"healthz",
+	"readyz",
+	"metrics",
+	"admin",
+	"api",
+}
+
+// Reserved is a set of reserved words, matched case-insensitively.
+type Reserved map[string]struct{}
+
+// NewReserved returns the set of DefaultReserved and words.
+func NewReserved(words []string) Reserved {
+	r := make(Reserved, len(DefaultReserved)+len(words))
+
+	for _, word := range DefaultReserved {
+		r[word] = struct{}{}
+	}
+	for _, word := range words {
+		r[strings.ToLower(word)] = struct{}{}
+	}
+
+	return r
+}
+
+// Contains reports whether alias is reserved.
+func (r Reserved) Contains(alias string) bool {
+	_, ok := r[strings.ToLower(alias)]
+
+	return ok
+}
+
+// Validator checks aliases chosen by users.
+type Validator struct {
+	minLength int
+	maxLength int
+	reserved  Reserved
+}
+
+// New creates a Validator accepting aliases of minLength..maxLength
+// characters that are neither in DefaultReserved nor in reserved.
+// Reserved words are matched case-insensitively.
+func New(minLength, maxLength int, reserved []string) *Validator {
+	return &Validator{
+		minLength: minLength,
+		maxLength: maxLength,
+		reserved:  NewReserved(reserved),
+	}
+}
+
+func (v *Validator) Validate(alias string) error {
+	if len(alias) < v.minLength || len(alias) > v.maxLength {
+		return fmt.Errorf("%w: must be %d to %d characters long", ErrInvalidLength, v.minLength, v.maxLength)
+	}
+
+	for _, c := range alias {
+		if !isAllowed(c) {
+			return ErrInvalidCharset
+		}
+	}
+
+	if v.reserved.Contains(alias) {
+		return ErrReserved
+	}
+
+	return nil
+}
+
+func isAllowed(c rune) bool {
+	return c >= 'a' && c <= 'z' ||
+		c >= 'A' && c <= 'Z' ||
+		c >= '0' && c <= '9' ||
+		c == '-' || c == '_'
+}
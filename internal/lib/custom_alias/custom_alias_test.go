@@ -0,0 +1,70 @@
+package customalias
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidator_Validate(t *testing.T) {
+	v := New(3, 10, []string{"Promo"})
+
+	tests := []struct {
+		name  string
+		alias string
+		want  error
+	}{
+		{
+			name:  "valid",
+			alias: "my-link_1",
+		},
+		{
+			name:  "too short",
+			alias: "ab",
+			want:  ErrInvalidLength,
+		},
+		{
+			name:  "too long",
+			alias: "abcdefghijk",
+			want:  ErrInvalidLength,
+		},
+		{
+			name:  "slash",
+			alias: "a/b/c",
+			want:  ErrInvalidCharset,
+		},
+		{
+			name:  "non ascii",
+			alias: "ссылка",
+			want:  ErrInvalidLength,
+		},
+		{
+			name:  "non ascii short",
+			alias: "çaé",
+			want:  ErrInvalidCharset,
+		},
+		{
+			name:  "built-in reserved word",
+			alias: "Swagger",
+			want:  ErrReserved,
+		},
+		{
+			name:  "configured reserved word",
+			alias: "promo",
+			want:  ErrReserved,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, v.Validate(tt.alias), tt.want)
+		})
+	}
+}
+
+func TestReserved_Contains(t *testing.T) {
+	r := NewReserved([]string{"Promo"})
+
+	assert.True(t, r.Contains("api"))
+	assert.True(t, r.Contains("PROMO"))
+	assert.False(t, r.Contains("apj"))
+}
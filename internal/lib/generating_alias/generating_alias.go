@@ -2,74 +2,49 @@ package generatingalias
 
 const char = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
 
-func NewGeneratedAliasOneSize(size *int, pointerOne *int) string {
-	for *pointerOne < len(char) {
-		result := string(char[*pointerOne])
-		*pointerOne++
-		return result
+// MaxLength is the longest alias an int64 counter can address.
+const MaxLength = 10
+
+// NewGeneratedAlias returns the alias for the n-th (zero-based) value of the
+// alias counter. Shorter aliases come first: "0".."z", then "00".."zz" and so
+// on, so the alias grows by one character every time a length is used up.
+func NewGeneratedAlias(n int64) string {
+	size := 1
+	for n >= pow(size) {
+		n -= pow(size)
+		size++
 	}
 
-	*pointerOne = 0
-	*size = 2
-	return "full"
-}
-
-func NewGeneratedAliasTwoSize(size *int, pointerOne *int, pointerTwo *int) string {
-	for *pointerOne < len(char) {
-		for *pointerTwo < len(char) {
-			result := string(char[*pointerOne]) + string(char[*pointerTwo])
-			*pointerTwo++
-			return result
-		}
-		*pointerTwo = 0
-		*pointerOne++
+	b := make([]byte, size)
+	for i := size - 1; i >= 0; i-- {
+		b[i] = char[n%int64(len(char))]
+		n /= int64(len(char))
 	}
 
-	*pointerOne, *pointerTwo = 0, 0
-	*size = 3
-	return "full"
+	return string(b)
 }
 
-func NewGeneratedAliasThreeSize(size *int, pointerOne *int, pointerTwo *int, pointerThree *int) string {
-	for *pointerOne < len(char) {
-		for *pointerTwo < len(char) {
-			for *pointerThree < len(char) {
-				result := string(char[*pointerOne]) + string(char[*pointerTwo]) + string(char[*pointerThree])
-				*pointerThree++
-				return result
-			}
-			*pointerThree = 0
-			*pointerTwo++
-		}
-		*pointerTwo = 0
-		*pointerOne++
+// Capacity returns the number of aliases that are at most maxLength
+// characters long. Zero, negative or too big values mean MaxLength.
+func Capacity(maxLength int) int64 {
+	if maxLength <= 0 || maxLength > MaxLength {
+		maxLength = MaxLength
+	}
+
+	var total int64
+	for size := 1; size <= maxLength; size++ {
+		total += pow(size)
 	}
 
-	*pointerOne, *pointerTwo, *pointerThree = 0, 0, 0
-	*size = 4
-	return "full"
+	return total
 }
 
-func NewGeneratedAliasFourSize(size *int, pointerOne *int, pointerTwo *int, pointerThree *int, pointerFour *int) string {
-	for *pointerOne < len(char) {
-		for *pointerTwo < len(char) {
-			for *pointerThree < len(char) {
-				for *pointerFour < len(char) {
-					result := string(char[*pointerOne]) + string(char[*pointerTwo]) + string(char[*pointerThree]) + string(char[*pointerFour])
-					*pointerFour++
-					return result
-				}
-				*pointerFour = 0
-				*pointerThree++
-			}
-			*pointerThree = 0
-			*pointerTwo++
-		}
-		*pointerTwo = 0
-		*pointerOne++
+// pow returns the number of aliases with exactly size characters.
+func pow(size int) int64 {
+	res := int64(1)
+	for i := 0; i < size; i++ {
+		res *= int64(len(char))
 	}
 
-	*pointerOne, *pointerTwo, *pointerThree, *pointerFour = 0, 0, 0, 0
-	*size = 5
-	return "full"
+	return res
 }
@@ -0,0 +1,58 @@
+package generatingalias
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewGeneratedAlias(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int64
+		want string
+	}{
+		{
+			name: "first alias",
+			n:    0,
+			want: "0",
+		},
+		{
+			name: "last one character alias",
+			n:    61,
+			want: "z",
+		},
+		{
+			name: "first two characters alias",
+			n:    62,
+			want: "00",
+		},
+		{
+			name: "last two characters alias",
+			n:    62 + 62*62 - 1,
+			want: "zz",
+		},
+		{
+			name: "first five characters alias",
+			n:    Capacity(4),
+			want: "00000",
+		},
+		{
+			name: "last alias",
+			n:    Capacity(MaxLength) - 1,
+			want: "zzzzzzzzzz",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NewGeneratedAlias(tt.n))
+		})
+	}
+}
+
+func TestCapacity(t *testing.T) {
+	assert.Equal(t, int64(62), Capacity(1))
+	assert.Equal(t, int64(62+62*62), Capacity(2))
+	assert.Equal(t, Capacity(MaxLength), Capacity(0))
+	assert.Equal(t, Capacity(MaxLength), Capacity(MaxLength+1))
+}
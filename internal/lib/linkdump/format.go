@@ -0,0 +1,399 @@
+package linkdump
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"url-shortener/internal/storage"
+)
+
+// maxLineSize bounds one JSONL record.
+const maxLineSize = 1 << 20
+
+// columns are the CSV columns of a link. Dumps written by older versions lack
+// the columns added since, down to the first minColumns.
+var columns = []string{
+	"alias", "url", "created_at", "active_from", "expires_at", "redirect_code", "owner_id",
+	"password_hash", "max_clicks", "used_clicks",
+	"target_ios", "target_android", "target_desktop",
+	"sticky_variants", "variants",
+}
+
+const minColumns = 7
+
+// record is a link as written to JSONL. The password hash is left out of
+// API responses but has to survive a dump.
+type record struct {
+	storage.Link
+	PasswordHash string `json:"password_hash,omitempty"`
+}
+
+type writer interface {
+	WriteHeader(h Header) error
+	Write(link storage.Link) error
+	Flush() error
+}
+
+type reader interface {
+	// Header must be called before the first Read.
+	Header() (Header, error)
+	// Read returns io.EOF after the last link.
+	Read() (storage.Link, error)
+}
+
+func newWriter(w io.Writer, format Format) (writer, error) {
+	switch format {
+	case FormatJSONL:
+		bw := bufio.NewWriter(w)
+		return &jsonlWriter{w: bw, enc: json.NewEncoder(bw)}, nil
+	case FormatCSV:
+		return &csvWriter{out: w, w: csv.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownFormat, format)
+	}
+}
+
+func newReader(r io.Reader, format Format) (reader, error) {
+	switch format {
+	case FormatJSONL:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+		return &jsonlReader{scanner: scanner}, nil
+	case FormatCSV:
+		return &csvReader{r: bufio.NewReader(r)}, nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownFormat, format)
+	}
+}
+
+type jsonlWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (j *jsonlWriter) WriteHeader(h Header) error {
+	return j.enc.Encode(h)
+}
+
+func (j *jsonlWriter) Write(link storage.Link) error {
+	return j.enc.Encode(record{Link: link, PasswordHash: link.PasswordHash})
+}
+
+func (j *jsonlWriter) Flush() error {
+	return j.w.Flush()
+}
+
+type jsonlReader struct {
+	scanner *bufio.Scanner
+}
+
+func (j *jsonlReader) Header() (Header, error) {
+	var h Header
+
+	line, err := j.next()
+	if errors.Is(err, io.EOF) {
+		return h, fmt.Errorf("%w: missing header", ErrInvalidDump)
+	}
+	if err != nil {
+		return h, err
+	}
+
+	if err := json.Unmarshal(line, &h); err != nil {
+		return h, fmt.Errorf("%w: header: %w", ErrInvalidDump, err)
+	}
+
+	return h, nil
+}
+
+func (j *jsonlReader) Read() (storage.Link, error) {
+	var rec record
+
+	line, err := j.next()
+	if err != nil {
+		return rec.Link, err
+	}
+
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return rec.Link, fmt.Errorf("%w: %w", ErrInvalidDump, err)
+	}
+
+	link := rec.Link
+	link.PasswordHash = rec.PasswordHash
+
+	return link, validate(link)
+}
+
+// next returns the next non-blank line.
+func (j *jsonlReader) next() ([]byte, error) {
+	for j.scanner.Scan() {
+		if line := j.scanner.Bytes(); len(strings.TrimSpace(string(line))) > 0 {
+			return line, nil
+		}
+	}
+	if err := j.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, io.EOF
+}
+
+type csvWriter struct {
+	out io.Writer
+	w   *csv.Writer
+}
+
+func (c *csvWriter) WriteHeader(h Header) error {
+	// The comment line goes around csv.Writer, which would quote it. Nothing
+	// is buffered in c.w yet, so it still ends up first.
+	if _, err := fmt.Fprintf(c.out, "# version=%d alias_counter=%d\n", h.Version, h.AliasCounter); err != nil {
+		return err
+	}
+
+	return c.w.Write(columns)
+}
+
+func (c *csvWriter) Write(link storage.Link) error {
+	var targets storage.Targets
+	if link.Targets != nil {
+		targets = *link.Targets
+	}
+
+	// Variants do not fit in columns, they are written as a JSON array.
+	var variants string
+	if len(link.Variants) > 0 {
+		data, err := json.Marshal(link.Variants)
+		if err != nil {
+			return err
+		}
+		variants = string(data)
+	}
+
+	return c.w.Write([]string{
+		link.Alias,
+		link.URL,
+		formatTime(&link.CreatedAt),
+		formatTime(link.ActiveFrom),
+		formatTime(link.ExpiresAt),
+		formatInt(int64(link.RedirectCode)),
+		formatInt(link.OwnerID),
+		link.PasswordHash,
+		formatInt(link.MaxClicks),
+		formatInt(link.UsedClicks),
+		targets.IOS,
+		targets.Android,
+		targets.Desktop,
+		formatBool(link.StickyVariants),
+		variants,
+	})
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type csvReader struct {
+	r *bufio.Reader
+	c *csv.Reader
+}
+
+func (c *csvReader) Header() (Header, error) {
+	var h Header
+
+	line, err := c.r.ReadString('\n')
+	if err != nil && line == "" {
+		if errors.Is(err, io.EOF) {
+			return h, fmt.Errorf("%w: missing header", ErrInvalidDump)
+		}
+		return h, err
+	}
+
+	comment, ok := strings.CutPrefix(strings.TrimSpace(line), "#")
+	if !ok {
+		return h, fmt.Errorf("%w: missing header", ErrInvalidDump)
+	}
+
+	for _, field := range strings.Fields(comment) {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "version":
+			h.Version, err = strconv.Atoi(value)
+		case "alias_counter":
+			h.AliasCounter, err = strconv.ParseInt(value, 10, 64)
+		}
+		if err != nil {
+			return h, fmt.Errorf("%w: header %s: %w", ErrInvalidDump, key, err)
+		}
+	}
+
+	// Every row must have as many fields as the row of column names.
+	c.c = csv.NewReader(c.r)
+	c.c.ReuseRecord = true
+
+	names, err := c.c.Read()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return h, fmt.Errorf("%w: columns: %w", ErrInvalidDump, err)
+	}
+	if err == nil && (len(names) < minColumns || len(names) > len(columns) || !slices.Equal(names, columns[:len(names)])) {
+		return h, fmt.Errorf("%w: columns must be %s", ErrInvalidDump, strings.Join(columns, ","))
+	}
+
+	return h, nil
+}
+
+func (c *csvReader) Read() (storage.Link, error) {
+	record, err := c.c.Read()
+	if errors.Is(err, io.EOF) {
+		return storage.Link{}, io.EOF
+	}
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("%w: %w", ErrInvalidDump, err)
+	}
+
+	link, err := parseRecord(record)
+	if err != nil {
+		return link, fmt.Errorf("%w: %w", ErrInvalidDump, err)
+	}
+
+	return link, validate(link)
+}
+
+// parseRecord converts a CSV row written by csvWriter back to a link.
+func parseRecord(record []string) (storage.Link, error) {
+	link := storage.Link{Alias: record[0], URL: record[1]}
+
+	createdAt, err := parseTime(record[2])
+	if err != nil {
+		return link, err
+	}
+	if createdAt != nil {
+		link.CreatedAt = *createdAt
+	}
+
+	if link.ActiveFrom, err = parseTime(record[3]); err != nil {
+		return link, err
+	}
+	if link.ExpiresAt, err = parseTime(record[4]); err != nil {
+		return link, err
+	}
+
+	code, err := parseInt(record[5])
+	if err != nil {
+		return link, err
+	}
+	link.RedirectCode = int(code)
+
+	if link.OwnerID, err = parseInt(record[6]); err != nil {
+		return link, err
+	}
+
+	if len(record) > 7 {
+		link.PasswordHash = record[7]
+	}
+	if len(record) > 8 {
+		if link.MaxClicks, err = parseInt(record[8]); err != nil {
+			return link, err
+		}
+	}
+	if len(record) > 9 {
+		if link.UsedClicks, err = parseInt(record[9]); err != nil {
+			return link, err
+		}
+	}
+
+	var targets storage.Targets
+	for i, target := range []*string{&targets.IOS, &targets.Android, &targets.Desktop} {
+		if len(record) > 10+i {
+			*target = record[10+i]
+		}
+	}
+	if !targets.IsZero() {
+		link.Targets = &targets
+	}
+
+	if len(record) > 13 {
+		if link.StickyVariants, err = parseBool(record[13]); err != nil {
+			return link, err
+		}
+	}
+	if len(record) > 14 && record[14] != "" {
+		if err := json.Unmarshal([]byte(record[14]), &link.Variants); err != nil {
+			return link, err
+		}
+	}
+
+	return link, nil
+}
+
+// validate rejects links that could not have been exported.
+func validate(link storage.Link) error {
+	if link.Alias == "" {
+		return fmt.Errorf("%w: alias is empty", ErrInvalidDump)
+	}
+	if link.URL == "" {
+		return fmt.Errorf("%w: url is empty", ErrInvalidDump)
+	}
+
+	return nil
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func parseTime(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+func formatInt(n int64) string {
+	if n == 0 {
+		return ""
+	}
+
+	return strconv.FormatInt(n, 10)
+}
+
+func formatBool(b bool) string {
+	if !b {
+		return ""
+	}
+
+	return "true"
+}
+
+func parseBool(s string) (bool, error) {
+	if s == "" {
+		return false, nil
+	}
+
+	return strconv.ParseBool(s)
+}
+
+func parseInt(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	return strconv.ParseInt(s, 10, 64)
+}
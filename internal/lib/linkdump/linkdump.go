@@ -0,0 +1,277 @@
+// Package linkdump exports and imports all links of a storage, so an
+// instance can be migrated to another database or restored.
+//
+// A dump starts with a Header carrying the alias counter, followed by one
+// record per link. Clicks, users and API keys are not part of a dump.
+package linkdump
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"url-shortener/internal/storage"
+)
+
+// Version is the dump format version written by Export.
+const Version = 1
+
+// chunkSize is the number of links read, written and reported at a time.
+const chunkSize = 500
+
+var (
+	// ErrInvalidDump is returned for input that is not a dump of a supported version.
+	ErrInvalidDump   = errors.New("invalid dump")
+	ErrUnknownFormat = errors.New("unknown format")
+	ErrUnknownPolicy = errors.New("unknown conflict policy")
+)
+
+// Format is the file format of a dump.
+type Format string
+
+const (
+	// FormatJSONL has one JSON object per line, the first one is the Header.
+	FormatJSONL Format = "jsonl"
+	// FormatCSV starts with a "#" comment line carrying the Header,
+	// followed by a row of column names.
+	FormatCSV Format = "csv"
+)
+
+// ParseFormat returns the format named s.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatJSONL, FormatCSV:
+		return f, nil
+	default:
+		return "", fmt.Errorf("%w %q", ErrUnknownFormat, s)
+	}
+}
+
+// ContentType returns the media type of dumps in format f.
+func (f Format) ContentType() string {
+	if f == FormatCSV {
+		return "text/csv"
+	}
+
+	return "application/x-ndjson"
+}
+
+// ParseConflictPolicy returns the policy named s.
+func ParseConflictPolicy(s string) (storage.ConflictPolicy, error) {
+	switch p := storage.ConflictPolicy(s); p {
+	case storage.ConflictSkip, storage.ConflictOverwrite, storage.ConflictFail:
+		return p, nil
+	default:
+		return "", fmt.Errorf("%w %q", ErrUnknownPolicy, s)
+	}
+}
+
+// Header is the first record of a dump.
+type Header struct {
+	Version int `json:"version"`
+	// AliasCounter is the alias counter of the exported storage, importing
+	// raises the counter of the target to it.
+	AliasCounter int64 `json:"alias_counter"`
+}
+
+// Progress is called with the number of links processed so far.
+type Progress func(done int)
+
+// Source is the storage a dump is exported from.
+type Source interface {
+	AliasCounter() (int64, error)
+	ListURLs(limit, offset int, ownerID int64) ([]storage.Link, error)
+}
+
+// Export writes all links of src to w and returns how many were written.
+func Export(w io.Writer, format Format, src Source, progress Progress) (int, error) {
+	const op = "linkdump.Export"
+
+	enc, err := newWriter(w, format)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Read before the links, so the counter covers every generated alias exported.
+	counter, err := src.AliasCounter()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := enc.WriteHeader(Header{Version: Version, AliasCounter: counter}); err != nil {
+		return 0, fmt.Errorf("%s: write header: %w", op, err)
+	}
+
+	total := 0
+	for {
+		links, err := src.ListURLs(chunkSize, total, storage.AnyOwner)
+		if err != nil {
+			return total, fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, link := range links {
+			if err := enc.Write(link); err != nil {
+				return total, fmt.Errorf("%s: write link: %w", op, err)
+			}
+		}
+
+		total += len(links)
+		if len(links) > 0 && progress != nil {
+			progress(total)
+		}
+
+		if len(links) < chunkSize {
+			break
+		}
+	}
+
+	if err := enc.Flush(); err != nil {
+		return total, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return total, nil
+}
+
+// Target is the storage a dump is imported into.
+type Target interface {
+	GetLink(alias string) (storage.Link, error)
+	ImportLinks(links []storage.Link, onConflict storage.ConflictPolicy) (storage.ImportStats, error)
+	RaiseAliasCounter(counter int64) error
+}
+
+// Options configure Import.
+type Options struct {
+	Format     Format
+	OnConflict storage.ConflictPolicy
+	// DryRun reports what would be imported without changing dst.
+	DryRun bool
+	// Progress is called after every chunk of links read, it may be nil.
+	Progress Progress
+}
+
+// Import loads the dump in r into dst. Links are saved in chunks, except
+// with storage.ConflictFail: then they are saved in one transaction, so a
+// conflict leaves dst untouched.
+func Import(r io.Reader, dst Target, opts Options) (storage.ImportStats, error) {
+	const op = "linkdump.Import"
+
+	var stats storage.ImportStats
+
+	dec, err := newReader(r, opts.Format)
+	if err != nil {
+		return stats, fmt.Errorf("%s: %w", op, err)
+	}
+
+	header, err := dec.Header()
+	if err != nil {
+		return stats, fmt.Errorf("%s: %w", op, err)
+	}
+	if header.Version != Version {
+		return stats, fmt.Errorf("%s: %w: unsupported version %d", op, ErrInvalidDump, header.Version)
+	}
+
+	// seen holds the aliases checked by a dry run, so that duplicates
+	// within the dump are reported like conflicts with dst.
+	seen := make(map[string]struct{})
+	var pending []storage.Link
+	done := 0
+
+	save := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+
+		var chunk storage.ImportStats
+		var err error
+		if opts.DryRun {
+			chunk, err = check(dst, pending, opts.OnConflict, seen)
+		} else {
+			chunk, err = dst.ImportLinks(pending, opts.OnConflict)
+		}
+		if err != nil {
+			return err
+		}
+
+		stats.Created += chunk.Created
+		stats.Overwritten += chunk.Overwritten
+		stats.Skipped += chunk.Skipped
+		pending = pending[:0]
+
+		return nil
+	}
+
+	for {
+		link, err := dec.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return stats, fmt.Errorf("%s: record %d: %w", op, done+1, err)
+		}
+
+		pending = append(pending, link)
+		done++
+
+		if done%chunkSize != 0 {
+			continue
+		}
+		if opts.DryRun || opts.OnConflict != storage.ConflictFail {
+			if err := save(); err != nil {
+				return stats, fmt.Errorf("%s: %w", op, err)
+			}
+		}
+		if opts.Progress != nil {
+			opts.Progress(done)
+		}
+	}
+
+	if err := save(); err != nil {
+		return stats, fmt.Errorf("%s: %w", op, err)
+	}
+	if opts.Progress != nil && done%chunkSize != 0 {
+		opts.Progress(done)
+	}
+
+	if !opts.DryRun {
+		if err := dst.RaiseAliasCounter(header.AliasCounter); err != nil {
+			return stats, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return stats, nil
+}
+
+// check counts what importing links would do without saving them.
+func check(
+	dst Target,
+	links []storage.Link,
+	onConflict storage.ConflictPolicy,
+	seen map[string]struct{},
+) (storage.ImportStats, error) {
+	var stats storage.ImportStats
+
+	for _, link := range links {
+		_, taken := seen[link.Alias]
+		if !taken {
+			_, err := dst.GetLink(link.Alias)
+			if err != nil && !errors.Is(err, storage.ErrURLNotFound) {
+				return stats, err
+			}
+			taken = err == nil
+		}
+		seen[link.Alias] = struct{}{}
+
+		switch {
+		case !taken:
+			stats.Created++
+		case onConflict == storage.ConflictSkip:
+			stats.Skipped++
+		case onConflict == storage.ConflictOverwrite:
+			stats.Overwritten++
+		default:
+			return stats, fmt.Errorf("alias %q: %w", link.Alias, storage.ErrURLExists)
+		}
+	}
+
+	return stats, nil
+}
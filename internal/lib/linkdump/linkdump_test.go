@@ -0,0 +1,249 @@
+package linkdump_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/linkdump"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+)
+
+func TestExportImport(t *testing.T) {
+	for _, format := range []linkdump.Format{linkdump.FormatJSONL, linkdump.FormatCSV} {
+		format := format
+
+		t.Run(string(format), func(t *testing.T) {
+			t.Parallel()
+
+			src := memory.New(0, nil)
+			activeFrom := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+			expiresAt := time.Date(2030, 2, 1, 0, 0, 0, 0, time.UTC)
+
+			_, err := src.SaveURL("https://google.com", "custom", storage.LinkOptions{
+				ActiveFrom:   &activeFrom,
+				ExpiresAt:    &expiresAt,
+				RedirectCode: 308,
+				PasswordHash: "$2a$10$hash",
+				MaxClicks:    3,
+				Targets:      &storage.Targets{IOS: "https://apps.apple.com/app/id1", Desktop: "https://example.com/a,b"},
+				Variants: []storage.Variant{
+					{URL: "https://example.com/a", Weight: 2},
+					{URL: "https://example.com/\"b\"", Weight: 1},
+				},
+				StickyVariants: true,
+			})
+			require.NoError(t, err)
+			_, err = src.UseClick("custom")
+			require.NoError(t, err)
+			require.NoError(t, src.SaveClicks([]storage.Click{{Alias: "custom", ClickedAt: time.Now(), Variant: 2}}))
+
+			// More than one chunk, so progress is reported more than once.
+			for i := 0; i < 600; i++ {
+				_, _, err := src.SaveGeneratedURL(fmt.Sprintf("https://example.com/%d?q=a,b", i), storage.LinkOptions{})
+				require.NoError(t, err)
+			}
+
+			var dump bytes.Buffer
+			var exported []int
+			n, err := linkdump.Export(&dump, format, src, func(done int) { exported = append(exported, done) })
+			require.NoError(t, err)
+			require.Equal(t, 601, n)
+			require.Equal(t, []int{500, 601}, exported)
+
+			dst := memory.New(0, nil)
+			var imported []int
+			stats, err := linkdump.Import(&dump, dst, linkdump.Options{
+				Format:     format,
+				OnConflict: storage.ConflictFail,
+				Progress:   func(done int) { imported = append(imported, done) },
+			})
+			require.NoError(t, err)
+			require.Equal(t, storage.ImportStats{Created: 601}, stats)
+			require.Equal(t, []int{500, 601}, imported)
+
+			want, err := src.GetLink("custom")
+			require.NoError(t, err)
+			got, err := dst.GetLink("custom")
+			require.NoError(t, err)
+			require.Equal(t, want.URL, got.URL)
+			require.True(t, want.CreatedAt.Equal(got.CreatedAt))
+			require.True(t, activeFrom.Equal(*got.ActiveFrom))
+			require.True(t, expiresAt.Equal(*got.ExpiresAt))
+			require.Equal(t, 308, got.RedirectCode)
+			require.Equal(t, "$2a$10$hash", got.PasswordHash)
+			require.EqualValues(t, 3, got.MaxClicks)
+			require.EqualValues(t, 1, got.UsedClicks)
+			require.Equal(t, want.Targets, got.Targets)
+			require.Equal(t, want.Variants, got.Variants)
+			require.EqualValues(t, 1, got.Variants[1].Clicks)
+			require.True(t, got.StickyVariants)
+
+			srcLinks, err := src.ListURLs(1000, 0, storage.AnyOwner)
+			require.NoError(t, err)
+			for _, link := range srcLinks {
+				url, err := dst.GetURL(link.Alias)
+				require.NoError(t, err)
+				require.Equal(t, link.URL, url)
+			}
+
+			srcCounter, err := src.AliasCounter()
+			require.NoError(t, err)
+			dstCounter, err := dst.AliasCounter()
+			require.NoError(t, err)
+			require.Equal(t, srcCounter, dstCounter)
+		})
+	}
+}
+
+func TestImport_Conflicts(t *testing.T) {
+	dump := `{"version": 1, "alias_counter": 3}
+{"alias": "taken", "url": "https://google.de"}
+{"alias": "fresh", "url": "https://go.dev"}
+`
+
+	cases := []struct {
+		name       string
+		onConflict storage.ConflictPolicy
+		dryRun     bool
+		wantStats  storage.ImportStats
+		wantErr    error
+		wantURL    string
+		wantFresh  bool
+	}{
+		{
+			name:       "Skip",
+			onConflict: storage.ConflictSkip,
+			wantStats:  storage.ImportStats{Created: 1, Skipped: 1},
+			wantURL:    "https://google.com",
+			wantFresh:  true,
+		},
+		{
+			name:       "Overwrite",
+			onConflict: storage.ConflictOverwrite,
+			wantStats:  storage.ImportStats{Created: 1, Overwritten: 1},
+			wantURL:    "https://google.de",
+			wantFresh:  true,
+		},
+		{
+			name:       "Fail",
+			onConflict: storage.ConflictFail,
+			wantErr:    storage.ErrURLExists,
+			wantURL:    "https://google.com",
+		},
+		{
+			name:       "Dry run",
+			onConflict: storage.ConflictOverwrite,
+			dryRun:     true,
+			wantStats:  storage.ImportStats{Created: 1, Overwritten: 1},
+			wantURL:    "https://google.com",
+		},
+		{
+			name:       "Dry run fail",
+			onConflict: storage.ConflictFail,
+			dryRun:     true,
+			wantErr:    storage.ErrURLExists,
+			wantURL:    "https://google.com",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dst := memory.New(0, nil)
+			_, err := dst.SaveURL("https://google.com", "taken", storage.LinkOptions{})
+			require.NoError(t, err)
+
+			stats, err := linkdump.Import(strings.NewReader(dump), dst, linkdump.Options{
+				Format:     linkdump.FormatJSONL,
+				OnConflict: tc.onConflict,
+				DryRun:     tc.dryRun,
+			})
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.wantStats, stats)
+			}
+
+			url, err := dst.GetURL("taken")
+			require.NoError(t, err)
+			require.Equal(t, tc.wantURL, url)
+
+			_, err = dst.GetURL("fresh")
+			require.Equal(t, tc.wantFresh, err == nil)
+
+			counter, err := dst.AliasCounter()
+			require.NoError(t, err)
+			if tc.wantFresh {
+				require.EqualValues(t, 3, counter)
+			} else {
+				require.Zero(t, counter)
+			}
+		})
+	}
+}
+
+func TestImport_Invalid(t *testing.T) {
+	cases := []struct {
+		name   string
+		format linkdump.Format
+		dump   string
+	}{
+		{name: "Empty", format: linkdump.FormatJSONL, dump: ""},
+		{name: "No header", format: linkdump.FormatJSONL, dump: `{"alias": "a", "url": "https://go.dev"}`},
+		{name: "Unsupported version", format: linkdump.FormatJSONL, dump: `{"version": 2}`},
+		{name: "Broken JSON", format: linkdump.FormatJSONL, dump: "{\"version\": 1}\n{\"alias\": "},
+		{name: "Missing URL", format: linkdump.FormatJSONL, dump: "{\"version\": 1}\n{\"alias\": \"a\"}"},
+		{name: "CSV no header", format: linkdump.FormatCSV, dump: "alias,url\n"},
+		{name: "CSV wrong columns", format: linkdump.FormatCSV, dump: "# version=1 alias_counter=0\nalias,url\n"},
+		{
+			name:   "CSV unknown column",
+			format: linkdump.FormatCSV,
+			dump:   "# version=1 alias_counter=0\nalias,url,created_at,active_from,expires_at,redirect_code,owner_id,password_hash,max_clicks,used_clicks,target_ios,target_android,target_desktop,sticky_variants,variants,extra\n",
+		},
+		{
+			name:   "CSV bad time",
+			format: linkdump.FormatCSV,
+			dump:   "# version=1 alias_counter=0\nalias,url,created_at,active_from,expires_at,redirect_code,owner_id\na,https://go.dev,yesterday,,,,\n",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := linkdump.Import(strings.NewReader(tc.dump), memory.New(0, nil), linkdump.Options{
+				Format:     tc.format,
+				OnConflict: storage.ConflictSkip,
+			})
+			require.ErrorIs(t, err, linkdump.ErrInvalidDump)
+		})
+	}
+}
+
+func TestImport_CSVWithoutPasswordColumn(t *testing.T) {
+	dump := "# version=1 alias_counter=0\nalias,url,created_at,active_from,expires_at,redirect_code,owner_id\na,https://go.dev,,,,,\n"
+
+	dst := memory.New(0, nil)
+	stats, err := linkdump.Import(strings.NewReader(dump), dst, linkdump.Options{
+		Format:     linkdump.FormatCSV,
+		OnConflict: storage.ConflictFail,
+	})
+	require.NoError(t, err)
+	require.Equal(t, storage.ImportStats{Created: 1}, stats)
+
+	link, err := dst.GetLink("a")
+	require.NoError(t, err)
+	require.False(t, link.Protected())
+}
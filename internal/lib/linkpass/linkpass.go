@@ -0,0 +1,82 @@
+// Package linkpass issues the tokens that let a visitor who entered the
+// password of a link through without asking again.
+package linkpass
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// secretSize is the length of generated secrets.
+const secretSize = 32
+
+var ErrInvalidTTL = errors.New("ttl must be positive")
+
+// Signer signs and checks tokens. A token is bound to the alias and to the
+// password hash, so changing the password invalidates tokens issued before.
+type Signer struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// New returns a Signer issuing tokens valid for ttl. An empty secret is
+// replaced by a random one, tokens then do not survive a restart and are
+// not accepted by other instances.
+func New(secret string, ttl time.Duration) (*Signer, error) {
+	const op = "linkpass.New"
+
+	if ttl <= 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidTTL)
+	}
+
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, secretSize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return &Signer{secret: key, ttl: ttl}, nil
+}
+
+// Token returns a token for the link with alias and passwordHash, valid
+// until the returned time.
+func (s *Signer) Token(alias, passwordHash string, now time.Time) (string, time.Time) {
+	expires := now.Add(s.ttl).Truncate(time.Second)
+	exp := strconv.FormatInt(expires.Unix(), 10)
+
+	return exp + "." + s.mac(alias, passwordHash, exp), expires
+}
+
+// Valid reports whether token was issued for the link with alias and
+// passwordHash and has not expired at now.
+func (s *Signer) Valid(token, alias, passwordHash string, now time.Time) bool {
+	exp, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || !now.Before(time.Unix(unix, 0)) {
+		return false
+	}
+
+	return hmac.Equal([]byte(sig), []byte(s.mac(alias, passwordHash, exp)))
+}
+
+func (s *Signer) mac(alias, passwordHash, exp string) string {
+	h := hmac.New(sha256.New, s.secret)
+	// Aliases and hashes never contain a newline, so the fields cannot run
+	// into each other.
+	h.Write([]byte(alias + "\n" + passwordHash + "\n" + exp))
+
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
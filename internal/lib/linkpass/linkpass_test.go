@@ -0,0 +1,66 @@
+package linkpass_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/linkpass"
+)
+
+func TestSigner(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	signer, err := linkpass.New("secret", time.Hour)
+	require.NoError(t, err)
+
+	token, expires := signer.Token("abc", "hash", now)
+	require.Equal(t, now.Add(time.Hour), expires)
+
+	cases := []struct {
+		name  string
+		token string
+		alias string
+		hash  string
+		at    time.Time
+		want  bool
+	}{
+		{name: "Valid", token: token, alias: "abc", hash: "hash", at: now, want: true},
+		{name: "Expired", token: token, alias: "abc", hash: "hash", at: expires},
+		{name: "Other alias", token: token, alias: "abd", hash: "hash", at: now},
+		{name: "Password changed", token: token, alias: "abc", hash: "other", at: now},
+		{name: "Expiry extended", token: "9999999999" + token[len("1709297200"):], alias: "abc", hash: "hash", at: now},
+		{name: "Malformed", token: "garbage", alias: "abc", hash: "hash", at: now},
+		{name: "Empty", alias: "abc", hash: "hash", at: now},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.want, signer.Valid(tc.token, tc.alias, tc.hash, tc.at))
+		})
+	}
+}
+
+func TestSigner_Secret(t *testing.T) {
+	now := time.Now()
+
+	a, err := linkpass.New("secret", time.Hour)
+	require.NoError(t, err)
+	b, err := linkpass.New("secret", time.Hour)
+	require.NoError(t, err)
+
+	token, _ := a.Token("abc", "hash", now)
+	require.True(t, b.Valid(token, "abc", "hash", now), "instances sharing a secret accept each other's tokens")
+
+	random, err := linkpass.New("", time.Hour)
+	require.NoError(t, err)
+	require.False(t, random.Valid(token, "abc", "hash", now))
+
+	_, err = linkpass.New("secret", 0)
+	require.ErrorIs(t, err, linkpass.ErrInvalidTTL)
+}
@@ -48,14 +48,15 @@ func (h *PrettyHandler) Handle(_ context.Context, r slog.Record) error {
 
 	fields := make(map[string]interface{}, r.NumAttrs())
 
+	// Values are resolved, so LogValuers can hide what must not be logged.
 	r.Attrs(func(a slog.Attr) bool {
-		fields[a.Key] = a.Value.Any()
+		fields[a.Key] = a.Value.Resolve().Any()
 
 		return true
 	})
 
 	for _, a := range h.attrs {
-		fields[a.Key] = a.Value.Any()
+		fields[a.Key] = a.Value.Resolve().Any()
 	}
 
 	var b []byte
@@ -0,0 +1,44 @@
+// Package platform tells the platform of a client from its User-Agent.
+package platform
+
+import "strings"
+
+// Platform is a kind of client that links can target.
+type Platform string
+
+const (
+	IOS     Platform = "ios"
+	Android Platform = "android"
+	Desktop Platform = "desktop"
+	// Other covers bots, command line tools and mobile systems without
+	// their own target.
+	Other Platform = "other"
+)
+
+// rules are checked in order, the first rule with a matching token wins.
+// Windows Phone claims to be Android and iOS and Android browsers mention
+// desktop systems, so the more specific rules come first.
+var rules = []struct {
+	platform Platform
+	tokens   []string
+}{
+	{platform: Other, tokens: []string{"Windows Phone", "IEMobile", "KAIOS", "BlackBerry", "BB10"}},
+	{platform: IOS, tokens: []string{"iPhone", "iPad", "iPod"}},
+	{platform: Android, tokens: []string{"Android"}},
+	{platform: Other, tokens: []string{"bot", "Bot", "spider", "crawler", "Mobile"}},
+	{platform: Desktop, tokens: []string{"Windows NT", "Macintosh", "CrOS", "X11", "Linux x86_64"}},
+}
+
+// Detect returns the platform of a client sending userAgent. iPads asking
+// for desktop sites send a Mac User-Agent and are seen as desktops.
+func Detect(userAgent string) Platform {
+	for _, rule := range rules {
+		for _, token := range rule.tokens {
+			if strings.Contains(userAgent, token) {
+				return rule.platform
+			}
+		}
+	}
+
+	return Other
+}
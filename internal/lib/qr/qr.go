@@ -0,0 +1,228 @@
+// Package qr renders QR codes of short links as PNG or SVG images.
+package qr
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	MinSize = 64
+	MaxSize = 2048
+	// MaxMargin is the widest quiet zone in modules. The specification asks
+	// for 4, scanners usually cope with less.
+	MaxMargin = 16
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown format")
+	ErrUnknownLevel  = errors.New("unknown error correction level")
+	ErrInvalidSize   = fmt.Errorf("size must be between %d and %d", MinSize, MaxSize)
+	ErrInvalidMargin = fmt.Errorf("margin must be between 0 and %d", MaxMargin)
+)
+
+// Format is the image format of a QR code.
+type Format string
+
+const (
+	FormatPNG Format = "png"
+	FormatSVG Format = "svg"
+)
+
+// ParseFormat returns the format named s.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatPNG, FormatSVG:
+		return f, nil
+	default:
+		return "", fmt.Errorf("%w %q", ErrUnknownFormat, s)
+	}
+}
+
+// ContentType returns the media type of images in format f.
+func (f Format) ContentType() string {
+	if f == FormatSVG {
+		return "image/svg+xml"
+	}
+
+	return "image/png"
+}
+
+// Level is the error correction level, higher levels survive more damage
+// to the printed code at the cost of denser codes.
+type Level string
+
+const (
+	// LevelL restores about 7% of the code.
+	LevelL Level = "L"
+	// LevelM restores about 15% of the code.
+	LevelM Level = "M"
+	// LevelQ restores about 25% of the code.
+	LevelQ Level = "Q"
+	// LevelH restores about 30% of the code.
+	LevelH Level = "H"
+)
+
+// ParseLevel returns the level named s, case insensitive.
+func ParseLevel(s string) (Level, error) {
+	switch l := Level(strings.ToUpper(s)); l {
+	case LevelL, LevelM, LevelQ, LevelH:
+		return l, nil
+	default:
+		return "", fmt.Errorf("%w %q", ErrUnknownLevel, s)
+	}
+}
+
+func (l Level) recovery() qrcode.RecoveryLevel {
+	switch l {
+	case LevelL:
+		return qrcode.Low
+	case LevelQ:
+		return qrcode.High
+	case LevelH:
+		return qrcode.Highest
+	default:
+		return qrcode.Medium
+	}
+}
+
+// Options control how a QR code is rendered.
+type Options struct {
+	Format Format
+	// Size is the width and height of the image in pixels. Modules are
+	// whole pixels, so the code is centered with some extra margin when
+	// Size is not a multiple of the module count. Codes too dense for Size
+	// come out larger.
+	Size  int
+	Level Level
+	// Margin is the quiet zone around the code in modules.
+	Margin int
+}
+
+// DefaultOptions are used for parameters the client leaves out.
+var DefaultOptions = Options{
+	Format: FormatPNG,
+	Size:   256,
+	Level:  LevelM,
+	Margin: 4,
+}
+
+// Validate checks that opts are within limits.
+func (o Options) Validate() error {
+	if o.Size < MinSize || o.Size > MaxSize {
+		return ErrInvalidSize
+	}
+	if o.Margin < 0 || o.Margin > MaxMargin {
+		return ErrInvalidMargin
+	}
+
+	return nil
+}
+
+// Encode writes a QR code of content to w.
+func Encode(w io.Writer, content string, opts Options) error {
+	const op = "qr.Encode"
+
+	if err := opts.Validate(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	code, err := qrcode.New(content, opts.Level.recovery())
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	// The margin is added while rendering, so it can be chosen freely.
+	code.DisableBorder = true
+	modules := code.Bitmap()
+
+	if opts.Format == FormatSVG {
+		err = writeSVG(w, modules, opts)
+	} else {
+		err = writePNG(w, modules, opts)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DataURI returns a QR code of content as a data URI, ready to be used as
+// the src of an img element.
+func DataURI(content string, opts Options) (string, error) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, content, opts); err != nil {
+		return "", err
+	}
+
+	return "data:" + opts.Format.ContentType() + ";base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func writePNG(w io.Writer, modules [][]bool, opts Options) error {
+	total := len(modules) + 2*opts.Margin
+	scale := max(1, opts.Size/total)
+	size := max(opts.Size, total*scale)
+	// Centers the code when size is not a multiple of the module count.
+	offset := (size-total*scale)/2 + opts.Margin*scale
+
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{color.White, color.Black})
+	for y, row := range modules {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex(offset+x*scale+dx, offset+y*scale+dy, 1)
+				}
+			}
+		}
+	}
+
+	enc := png.Encoder{CompressionLevel: png.BestCompression}
+
+	return enc.Encode(w, img)
+}
+
+// writeSVG draws each horizontal run of dark modules as one rectangle in a
+// single path, the image scales without blurring.
+func writeSVG(w io.Writer, modules [][]bool, opts Options) error {
+	total := len(modules) + 2*opts.Margin
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		opts.Size, opts.Size, total, total)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, total, total)
+
+	for y, row := range modules {
+		for x := 0; x < len(row); {
+			if !row[x] {
+				x++
+				continue
+			}
+
+			run := 0
+			for x+run < len(row) && row[x+run] {
+				run++
+			}
+			fmt.Fprintf(&b, "M%d %dh%dv1h-%dz", x+opts.Margin, y+opts.Margin, run, run)
+			x += run
+		}
+	}
+
+	b.WriteString(`"/></svg>`)
+	b.WriteByte('\n')
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
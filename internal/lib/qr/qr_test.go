@@ -0,0 +1,140 @@
+package qr_test
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/qr"
+)
+
+const content = "https://sho.rt/abc"
+
+func TestEncode_PNG(t *testing.T) {
+	cases := []struct {
+		name     string
+		size     int
+		margin   int
+		wantSize int
+		// wantScale is the module size in pixels.
+		wantScale int
+	}{
+		{name: "Exact fit", size: 33 * 8, margin: 4, wantSize: 33 * 8, wantScale: 8},
+		{name: "Centered", size: 256, margin: 4, wantSize: 256, wantScale: 7},
+		{name: "No margin", size: 256, margin: 0, wantSize: 256, wantScale: 10},
+		{name: "Wide margin", size: qr.MinSize, margin: qr.MaxMargin, wantSize: qr.MinSize, wantScale: 1},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := qr.DefaultOptions
+			opts.Size = tc.size
+			opts.Margin = tc.margin
+
+			var buf bytes.Buffer
+			require.NoError(t, qr.Encode(&buf, content, opts))
+
+			img, err := png.Decode(&buf)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantSize, img.Bounds().Dx())
+			require.Equal(t, tc.wantSize, img.Bounds().Dy())
+
+			// The top left finder pattern starts right after the margin
+			// with a dark ring around a light one.
+			modules := 25 // version 2 fits the content at level M
+			offset := (tc.wantSize-(modules+2*tc.margin)*tc.wantScale)/2 + tc.margin*tc.wantScale
+			dark := func(module int) bool {
+				r, _, _, _ := img.At(offset+module*tc.wantScale, offset+module*tc.wantScale).RGBA()
+				return r == 0
+			}
+			if offset > 0 {
+				r, _, _, _ := img.At(offset-1, offset-1).RGBA()
+				require.NotZero(t, r, "margin is light")
+			}
+			require.True(t, dark(0))
+			require.False(t, dark(1))
+			require.True(t, dark(2))
+		})
+	}
+}
+
+func TestEncode_SVG(t *testing.T) {
+	opts := qr.DefaultOptions
+	opts.Format = qr.FormatSVG
+	opts.Margin = 2
+
+	var buf bytes.Buffer
+	require.NoError(t, qr.Encode(&buf, content, opts))
+
+	svg := buf.String()
+	require.True(t, strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" width="256" height="256" viewBox="0 0 29 29"`))
+	// The top row of both upper finder patterns.
+	require.Contains(t, svg, `d="M2 2h7v1h-7z`)
+	require.Contains(t, svg, "M20 2h7v1h-7z")
+}
+
+func TestEncode_TooSmall(t *testing.T) {
+	opts := qr.DefaultOptions
+	opts.Size = qr.MinSize
+	opts.Level = qr.LevelH
+
+	var buf bytes.Buffer
+	require.NoError(t, qr.Encode(&buf, content+"/"+strings.Repeat("x", 200), opts))
+
+	img, err := png.Decode(&buf)
+	require.NoError(t, err)
+	require.Greater(t, img.Bounds().Dx(), qr.MinSize, "dense codes come out larger")
+}
+
+func TestEncode_Level(t *testing.T) {
+	encode := func(level qr.Level) []byte {
+		opts := qr.DefaultOptions
+		opts.Level = level
+
+		var buf bytes.Buffer
+		require.NoError(t, qr.Encode(&buf, content, opts))
+
+		return buf.Bytes()
+	}
+
+	require.NotEqual(t, encode(qr.LevelL), encode(qr.LevelH))
+}
+
+func TestEncode_InvalidOptions(t *testing.T) {
+	opts := qr.DefaultOptions
+	opts.Size = qr.MaxSize + 1
+	require.ErrorIs(t, qr.Encode(&bytes.Buffer{}, content, opts), qr.ErrInvalidSize)
+
+	opts = qr.DefaultOptions
+	opts.Margin = -1
+	require.ErrorIs(t, qr.Encode(&bytes.Buffer{}, content, opts), qr.ErrInvalidMargin)
+}
+
+func TestParse(t *testing.T) {
+	level, err := qr.ParseLevel("q")
+	require.NoError(t, err)
+	require.Equal(t, qr.LevelQ, level)
+
+	_, err = qr.ParseLevel("X")
+	require.ErrorIs(t, err, qr.ErrUnknownLevel)
+
+	format, err := qr.ParseFormat("svg")
+	require.NoError(t, err)
+	require.Equal(t, "image/svg+xml", format.ContentType())
+
+	_, err = qr.ParseFormat("gif")
+	require.ErrorIs(t, err, qr.ErrUnknownFormat)
+}
+
+func TestDataURI(t *testing.T) {
+	uri, err := qr.DataURI(content, qr.DefaultOptions)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(uri, "data:image/png;base64,iVBORw0KGgo"))
+}
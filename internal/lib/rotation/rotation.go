@@ -0,0 +1,44 @@
+// Package rotation picks the variant a visitor of a rotating link is sent to.
+package rotation
+
+import (
+	"math/rand"
+
+	"url-shortener/internal/storage"
+)
+
+// Random picks a variant with a chance proportional to its weight and
+// returns its number, counting from 1. If no variant has a positive weight
+// every variant has the same chance.
+func Random(variants []storage.Variant) int {
+	total := Total(variants)
+	if total == 0 {
+		return rand.Intn(len(variants)) + 1
+	}
+
+	return Pick(variants, rand.Intn(total))
+}
+
+// Pick returns the number of the variant roll falls on when the weights are
+// laid out one after another, roll must be in [0, Total(variants)).
+func Pick(variants []storage.Variant, roll int) int {
+	for i, variant := range variants {
+		weight := max(variant.Weight, 0)
+		if roll < weight {
+			return i + 1
+		}
+		roll -= weight
+	}
+
+	return len(variants)
+}
+
+// Total returns the sum of the positive weights of variants.
+func Total(variants []storage.Variant) int {
+	var total int
+	for _, variant := range variants {
+		total += max(variant.Weight, 0)
+	}
+
+	return total
+}
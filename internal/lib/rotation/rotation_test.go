@@ -0,0 +1,63 @@
+package rotation_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/rotation"
+	"url-shortener/internal/storage"
+)
+
+func TestPick(t *testing.T) {
+	variants := []storage.Variant{
+		{URL: "https://example.com/a", Weight: 2},
+		{URL: "https://example.com/b", Weight: 0},
+		{URL: "https://example.com/c", Weight: 3},
+	}
+
+	cases := []struct {
+		name string
+		roll int
+		want int
+	}{
+		{name: "First", roll: 0, want: 1},
+		{name: "End of first", roll: 1, want: 1},
+		{name: "Zero weight skipped", roll: 2, want: 3},
+		{name: "Last", roll: 4, want: 3},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.want, rotation.Pick(variants, tc.roll))
+		})
+	}
+}
+
+func TestRandom(t *testing.T) {
+	variants := []storage.Variant{
+		{URL: "https://example.com/a", Weight: 3},
+		{URL: "https://example.com/b", Weight: 1},
+	}
+	require.Equal(t, 4, rotation.Total(variants))
+
+	const n = 10000
+
+	counts := make(map[int]int)
+	for i := 0; i < n; i++ {
+		counts[rotation.Random(variants)]++
+	}
+
+	require.Len(t, counts, 2)
+	// 3:1 weights send about 75% to the first variant.
+	require.InDelta(t, 0.75, float64(counts[1])/n, 0.03)
+
+	unweighted := []storage.Variant{{URL: "https://example.com/a"}, {URL: "https://example.com/b"}}
+	for i := 0; i < 100; i++ {
+		require.Contains(t, []int{1, 2}, rotation.Random(unweighted))
+	}
+}
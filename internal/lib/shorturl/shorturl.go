@@ -0,0 +1,46 @@
+// Package shorturl builds the full URLs of short links.
+package shorturl
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var ErrInvalidBase = errors.New("base must be an absolute http or https URL")
+
+// Builder builds short URLs from aliases.
+type Builder struct {
+	base string
+}
+
+// New returns a Builder for links served under base, e.g.
+// "https://sho.rt". An empty base takes the scheme and host from each
+// request, which is wrong behind a proxy that rewrites them.
+func New(base string) (Builder, error) {
+	if base == "" {
+		return Builder{}, nil
+	}
+
+	u, err := url.Parse(base)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Builder{}, ErrInvalidBase
+	}
+
+	return Builder{base: strings.TrimSuffix(base, "/")}, nil
+}
+
+// URL returns the short URL of alias as requested through r.
+func (b Builder) URL(r *http.Request, alias string) string {
+	base := b.base
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+
+	return base + "/" + url.PathEscape(alias)
+}
@@ -0,0 +1,56 @@
+package shorturl_test
+
+import (
+	"crypto/tls"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/shorturl"
+)
+
+func TestBuilder_URL(t *testing.T) {
+	cases := []struct {
+		name  string
+		base  string
+		host  string
+		tls   bool
+		alias string
+		want  string
+	}{
+		{name: "Base", base: "https://sho.rt", host: "internal:8082", alias: "abc", want: "https://sho.rt/abc"},
+		{name: "Base with path", base: "https://example.com/s/", alias: "abc", want: "https://example.com/s/abc"},
+		{name: "Request host", host: "localhost:8082", alias: "abc", want: "http://localhost:8082/abc"},
+		{name: "Request TLS", host: "sho.rt", tls: true, alias: "abc", want: "https://sho.rt/abc"},
+		{name: "Escaped alias", base: "https://sho.rt", alias: "a b", want: "https://sho.rt/a%20b"},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := shorturl.New(tc.base)
+			require.NoError(t, err)
+
+			r := httptest.NewRequest("GET", "/url", nil)
+			if tc.host != "" {
+				r.Host = tc.host
+			}
+			if tc.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+
+			require.Equal(t, tc.want, b.URL(r, tc.alias))
+		})
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	for _, base := range []string{"sho.rt", "ftp://sho.rt", "https://", "://"} {
+		_, err := shorturl.New(base)
+		require.ErrorIs(t, err, shorturl.ErrInvalidBase, base)
+	}
+}
@@ -0,0 +1,170 @@
+package metrics
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"url-shortener/internal/cache"
+)
+
+const namespace = "url_shortener"
+
+// AliasCounter reports how many generated aliases have been used up.
+type AliasCounter interface {
+	AliasCounter() (int64, error)
+}
+
+// Metrics holds the Prometheus collectors of the service.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	redirects       prometheus.Counter
+	aliasesCreated  *prometheus.CounterVec
+	storageDuration *prometheus.HistogramVec
+}
+
+// New registers the service metrics. Alias-space utilization is read from
+// counter on every scrape, capacity is the number of aliases that can be generated.
+func New(counter AliasCounter, capacity int64) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route, method and status.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route, method and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		redirects: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "redirects_total",
+			Help:      "Redirects served.",
+		}),
+		aliasesCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "aliases_created_total",
+			Help:      "Links saved, by generated or custom alias.",
+		}, []string{"kind"}),
+		storageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "storage_operation_duration_seconds",
+			Help:      "Storage operation latency by operation and result.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"operation", "result"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.redirects,
+		m.aliasesCreated,
+		m.storageDuration,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "alias_space_utilization_ratio",
+			Help:      "Share of the generated alias space already used up.",
+		}, func() float64 {
+			used, err := counter.AliasCounter()
+			if err != nil {
+				return math.NaN()
+			}
+
+			return float64(used) / float64(capacity)
+		}),
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// RegisterCache exports the hit and miss counters of the redirect cache.
+func (m *Metrics) RegisterCache(c *cache.Cache) {
+	m.registry.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_hits_total",
+			Help:      "Redirect cache lookups answered from the cache.",
+		}, func() float64 { return float64(c.Stats().Hits) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_misses_total",
+			Help:      "Redirect cache lookups that went to storage.",
+		}, func() float64 { return float64(c.Stats().Misses) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cache_entries",
+			Help:      "Aliases held in the redirect cache.",
+		}, func() float64 { return float64(c.Len()) }),
+	)
+}
+
+// ObserveRequest implements logger.RequestObserver. Requests are labelled by
+// the chi route pattern, so aliases do not end up in label values.
+func (m *Metrics) ObserveRequest(r *http.Request, status int, duration time.Duration) {
+	route := "unmatched"
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		route = rctx.RoutePattern()
+	}
+
+	// Handlers that write nothing answer 200.
+	if status == 0 {
+		status = http.StatusOK
+	}
+	code := strconv.Itoa(status)
+
+	m.requests.WithLabelValues(route, r.Method, code).Inc()
+	m.requestDuration.WithLabelValues(route, r.Method, code).Observe(duration.Seconds())
+}
+
+// ClickRecorder is an interface for recording served redirects.
+type ClickRecorder interface {
+	RecordClick(r *http.Request, alias string, variant int)
+}
+
+type redirectCounter struct {
+	ClickRecorder
+	m *Metrics
+}
+
+// CountRedirects wraps next so that every recorded click, which the redirect
+// handler records once per served redirect, is also counted.
+func (m *Metrics) CountRedirects(next ClickRecorder) ClickRecorder {
+	return redirectCounter{ClickRecorder: next, m: m}
+}
+
+func (rc redirectCounter) RecordClick(r *http.Request, alias string, variant int) {
+	rc.m.redirects.Inc()
+	rc.ClickRecorder.RecordClick(r, alias, variant)
+}
+
+func (m *Metrics) observeAliasCreated(kind string) {
+	m.aliasesCreated.WithLabelValues(kind).Inc()
+}
+
+func (m *Metrics) observeStorage(operation string, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+
+	m.storageDuration.WithLabelValues(operation, result).Observe(time.Since(start).Seconds())
+}
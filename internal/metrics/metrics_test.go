@@ -0,0 +1,73 @@
+package metrics_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	mwLogger "url-shortener/internal/http-server/middleware/logger"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/metrics"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+)
+
+type noopRecorder struct{}
+
+func (noopRecorder) RecordClick(*http.Request, string, int) {}
+
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	body, err := io.ReadAll(rr.Body)
+	require.NoError(t, err)
+
+	return string(body)
+}
+
+func TestMetrics(t *testing.T) {
+	store := memory.New(1, nil)
+	m := metrics.New(store, 62)
+	s := metrics.InstrumentStore(store, m)
+	recorder := m.CountRedirects(noopRecorder{})
+
+	r := chi.NewRouter()
+	r.Use(mwLogger.New(slogdiscard.NewDiscardLogger(), m))
+	r.Get("/{alias}", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := s.GetLink(chi.URLParam(r, "alias")); err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		recorder.RecordClick(r, chi.URLParam(r, "alias"), 0)
+		w.WriteHeader(http.StatusFound)
+	})
+
+	_, _, err := s.SaveGeneratedURL("https://google.com", storage.LinkOptions{})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://go.dev", "godev", storage.LinkOptions{})
+	require.NoError(t, err)
+
+	for _, path := range []string{"/0", "/godev", "/missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	body := scrape(t, m)
+
+	require.Contains(t, body, `url_shortener_http_requests_total{method="GET",route="/{alias}",status="302"} 2`)
+	require.Contains(t, body, `url_shortener_http_requests_total{method="GET",route="/{alias}",status="404"} 1`)
+	require.Contains(t, body, `url_shortener_http_request_duration_seconds_count{method="GET",route="/{alias}",status="302"} 2`)
+	require.Contains(t, body, `url_shortener_redirects_total 2`)
+	require.Contains(t, body, `url_shortener_aliases_created_total{kind="custom"} 1`)
+	require.Contains(t, body, `url_shortener_aliases_created_total{kind="generated"} 1`)
+	require.Contains(t, body, `url_shortener_storage_operation_duration_seconds_count{operation="get_link",result="ok"} 3`)
+	require.Regexp(t, `url_shortener_alias_space_utilization_ratio 0\.0161`, body)
+}
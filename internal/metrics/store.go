@@ -0,0 +1,214 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"url-shortener/internal/storage"
+)
+
+// Store forwards to a storage.Store, timing every operation and counting
+// saved links.
+type Store struct {
+	storage.Store
+	m *Metrics
+}
+
+// InstrumentStore wraps store so that its operations are measured by m.
+func InstrumentStore(store storage.Store, m *Metrics) *Store {
+	return &Store{Store: store, m: m}
+}
+
+func (s *Store) SaveURL(urlToSave string, alias string, opts storage.LinkOptions) (id int64, err error) {
+	defer s.observe("save_url", time.Now(), &err)
+
+	id, err = s.Store.SaveURL(urlToSave, alias, opts)
+	if err == nil {
+		s.m.observeAliasCreated("custom")
+	}
+
+	return id, err
+}
+
+func (s *Store) SaveGeneratedURL(urlToSave string, opts storage.LinkOptions) (alias string, id int64, err error) {
+	defer s.observe("save_generated_url", time.Now(), &err)
+
+	alias, id, err = s.Store.SaveGeneratedURL(urlToSave, opts)
+	if err == nil {
+		s.m.observeAliasCreated("generated")
+	}
+
+	return alias, id, err
+}
+
+func (s *Store) SaveURLBatch(links []storage.NewLink) (results []storage.BatchResult, err error) {
+	defer s.observe("save_url_batch", time.Now(), &err)
+
+	results, err = s.Store.SaveURLBatch(links)
+	for i, res := range results {
+		if res.Err != nil {
+			continue
+		}
+
+		if links[i].Alias == "" {
+			s.m.observeAliasCreated("generated")
+		} else {
+			s.m.observeAliasCreated("custom")
+		}
+	}
+
+	return results, err
+}
+
+func (s *Store) ImportLinks(links []storage.Link, onConflict storage.ConflictPolicy) (stats storage.ImportStats, err error) {
+	defer s.observe("import_links", time.Now(), &err)
+
+	return s.Store.ImportLinks(links, onConflict)
+}
+
+func (s *Store) RaiseAliasCounter(counter int64) (err error) {
+	defer s.observe("raise_alias_counter", time.Now(), &err)
+
+	return s.Store.RaiseAliasCounter(counter)
+}
+
+func (s *Store) AliasCounter() (counter int64, err error) {
+	defer s.observe("alias_counter", time.Now(), &err)
+
+	return s.Store.AliasCounter()
+}
+
+func (s *Store) GetURL(alias string) (url string, err error) {
+	defer s.observe("get_url", time.Now(), &err)
+
+	return s.Store.GetURL(alias)
+}
+
+func (s *Store) GetLink(alias string) (link storage.Link, err error) {
+	defer s.observe("get_link", time.Now(), &err)
+
+	return s.Store.GetLink(alias)
+}
+
+func (s *Store) UseClick(alias string) (left int64, err error) {
+	defer s.observe("use_click", time.Now(), &err)
+
+	return s.Store.UseClick(alias)
+}
+
+func (s *Store) UpdateURL(alias string, newURL string, ownerID int64) (err error) {
+	defer s.observe("update_url", time.Now(), &err)
+
+	return s.Store.UpdateURL(alias, newURL, ownerID)
+}
+
+func (s *Store) UpdateLink(alias string, update storage.LinkUpdate, ownerID int64) (link storage.Link, err error) {
+	defer s.observe("update_link", time.Now(), &err)
+
+	return s.Store.UpdateLink(alias, update, ownerID)
+}
+
+func (s *Store) DeleteURL(alias string, ownerID int64) (err error) {
+	defer s.observe("delete_url", time.Now(), &err)
+
+	return s.Store.DeleteURL(alias, ownerID)
+}
+
+func (s *Store) ListURLs(limit, offset int, ownerID int64) (links []storage.Link, err error) {
+	defer s.observe("list_urls", time.Now(), &err)
+
+	return s.Store.ListURLs(limit, offset, ownerID)
+}
+
+func (s *Store) CountURLs(ownerID int64) (count int, err error) {
+	defer s.observe("count_urls", time.Now(), &err)
+
+	return s.Store.CountURLs(ownerID)
+}
+
+func (s *Store) DeleteExpiredURLs(before time.Time) (deleted int64, err error) {
+	defer s.observe("delete_expired_urls", time.Now(), &err)
+
+	return s.Store.DeleteExpiredURLs(before)
+}
+
+func (s *Store) SaveClicks(clicks []storage.Click) (err error) {
+	defer s.observe("save_clicks", time.Now(), &err)
+
+	return s.Store.SaveClicks(clicks)
+}
+
+func (s *Store) ClickStats(alias string, since time.Time, ownerID int64) (stats storage.ClickStats, err error) {
+	defer s.observe("click_stats", time.Now(), &err)
+
+	return s.Store.ClickStats(alias, since, ownerID)
+}
+
+func (s *Store) CountClicks(alias string) (n int, err error) {
+	defer s.observe("count_clicks", time.Now(), &err)
+
+	return s.Store.CountClicks(alias)
+}
+
+func (s *Store) SaveUser(user storage.User) (id int64, err error) {
+	defer s.observe("save_user", time.Now(), &err)
+
+	return s.Store.SaveUser(user)
+}
+
+func (s *Store) GetUserByName(name string) (user storage.User, err error) {
+	defer s.observe("get_user", time.Now(), &err)
+
+	return s.Store.GetUserByName(name)
+}
+
+func (s *Store) ListUsers() (users []storage.User, err error) {
+	defer s.observe("list_users", time.Now(), &err)
+
+	return s.Store.ListUsers()
+}
+
+func (s *Store) SaveAPIKey(key storage.APIKey, hash string) (id int64, err error) {
+	defer s.observe("save_api_key", time.Now(), &err)
+
+	return s.Store.SaveAPIKey(key, hash)
+}
+
+func (s *Store) GetAPIKeyByHash(hash string) (key storage.APIKey, err error) {
+	defer s.observe("get_api_key", time.Now(), &err)
+
+	return s.Store.GetAPIKeyByHash(hash)
+}
+
+func (s *Store) ListAPIKeys() (keys []storage.APIKey, err error) {
+	defer s.observe("list_api_keys", time.Now(), &err)
+
+	return s.Store.ListAPIKeys()
+}
+
+func (s *Store) TouchAPIKey(id int64, usedAt time.Time) (err error) {
+	defer s.observe("touch_api_key", time.Now(), &err)
+
+	return s.Store.TouchAPIKey(id, usedAt)
+}
+
+func (s *Store) RevokeAPIKey(id int64, revokedAt time.Time) (err error) {
+	defer s.observe("revoke_api_key", time.Now(), &err)
+
+	return s.Store.RevokeAPIKey(id, revokedAt)
+}
+
+// observe records the duration of an operation started at start. err points
+// to the named result, so it is read after the operation returned. Expected
+// outcomes such as storage.ErrURLNotFound count as success.
+func (s *Store) observe(operation string, start time.Time, err *error) {
+	opErr := *err
+	if errors.Is(opErr, storage.ErrURLNotFound) || errors.Is(opErr, storage.ErrURLExists) ||
+		errors.Is(opErr, storage.ErrAPIKeyNotFound) || errors.Is(opErr, storage.ErrUserNotFound) ||
+		errors.Is(opErr, storage.ErrUserExists) || errors.Is(opErr, storage.ErrClicksExhausted) ||
+		errors.Is(opErr, storage.ErrNoVariants) {
+		opErr = nil
+	}
+
+	s.m.observeStorage(operation, start, opErr)
+}
@@ -0,0 +1,556 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	customalias "url-shortener/internal/lib/custom_alias"
+	generatingalias "url-shortener/internal/lib/generating_alias"
+	"url-shortener/internal/storage"
+)
+
+var _ storage.Store = (*Storage)(nil)
+
+// Storage keeps links in process memory. It is safe for concurrent use and
+// loses everything on restart, so it is meant for demos and tests.
+type Storage struct {
+	mu             sync.RWMutex
+	links          map[string]*storage.Link
+	clicks         map[string][]storage.Click
+	apiKeys        []apiKey
+	users          []storage.User
+	lastID         int64
+	counter        int64
+	aliasMaxLength int
+	reserved       customalias.Reserved
+}
+
+// New creates an empty storage. aliasMaxLength limits the length of
+// generated aliases, zero means no limit. Generated aliases skip
+// customalias.DefaultReserved and reserved.
+func New(aliasMaxLength int, reserved []string) *Storage {
+	return &Storage{
+		links:          make(map[string]*storage.Link),
+		clicks:         make(map[string][]storage.Click),
+		aliasMaxLength: aliasMaxLength,
+		reserved:       customalias.NewReserved(reserved),
+	}
+}
+
+// Ping always succeeds, it exists to satisfy storage.Store.
+func (s *Storage) Ping() error {
+	return nil
+}
+
+// Close is a no-op, it exists to satisfy storage.Store.
+func (s *Storage) Close() error {
+	return nil
+}
+
+func (s *Storage) SaveURL(urlToSave string, alias string, opts storage.LinkOptions) (int64, error) {
+	const op = "storage.memory.SaveURL"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, err := s.save(urlToSave, alias, opts)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) SaveGeneratedURL(urlToSave string, opts storage.LinkOptions) (string, int64, error) {
+	const op = "storage.memory.SaveGeneratedURL"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alias, id, err := s.saveGenerated(urlToSave, opts)
+	if err != nil {
+		return "", 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return alias, id, nil
+}
+
+func (s *Storage) SaveURLBatch(links []storage.NewLink) ([]storage.BatchResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := make([]storage.BatchResult, len(links))
+	for i, link := range links {
+		res := storage.BatchResult{Alias: link.Alias}
+		if link.Alias == "" {
+			res.Alias, res.ID, res.Err = s.saveGenerated(link.URL, link.LinkOptions)
+		} else {
+			res.ID, res.Err = s.save(link.URL, link.Alias, link.LinkOptions)
+		}
+
+		results[i] = res
+	}
+
+	return results, nil
+}
+
+func (s *Storage) ImportLinks(links []storage.Link, onConflict storage.ConflictPolicy) (storage.ImportStats, error) {
+	const op = "storage.memory.ImportLinks"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Check every alias first, so a conflict leaves the storage untouched.
+	if onConflict != storage.ConflictSkip && onConflict != storage.ConflictOverwrite {
+		seen := make(map[string]struct{}, len(links))
+		for _, link := range links {
+			_, taken := s.links[link.Alias]
+			if _, dup := seen[link.Alias]; taken || dup {
+				return storage.ImportStats{}, fmt.Errorf("%s: alias %q: %w", op, link.Alias, storage.ErrURLExists)
+			}
+			seen[link.Alias] = struct{}{}
+		}
+	}
+
+	var stats storage.ImportStats
+	now := time.Now().UTC()
+	for _, link := range links {
+		if link.CreatedAt.IsZero() {
+			link.CreatedAt = now
+		}
+		if link.OwnerID < 0 || link.OwnerID > int64(len(s.users)) {
+			link.OwnerID = 0
+		}
+		link.Variants = cloneVariants(link.Variants)
+
+		existing, ok := s.links[link.Alias]
+		switch {
+		case !ok:
+			s.lastID++
+			link.ID = s.lastID
+			stats.Created++
+		case onConflict == storage.ConflictSkip:
+			stats.Skipped++
+			continue
+		default:
+			link.ID = existing.ID
+			stats.Overwritten++
+		}
+
+		s.links[link.Alias] = &link
+	}
+
+	return stats, nil
+}
+
+func (s *Storage) RaiseAliasCounter(counter int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counter = max(s.counter, counter)
+
+	return nil
+}
+
+// saveGenerated saves urlToSave under the next free alias, reserved aliases
+// and aliases already taken by custom links are skipped.
+func (s *Storage) saveGenerated(urlToSave string, opts storage.LinkOptions) (string, int64, error) {
+	for {
+		if s.counter >= generatingalias.Capacity(s.aliasMaxLength) {
+			return "", 0, storage.ErrAliasSpaceExhausted
+		}
+
+		alias := generatingalias.NewGeneratedAlias(s.counter)
+		s.counter++
+		if s.reserved.Contains(alias) {
+			continue
+		}
+
+		id, err := s.save(urlToSave, alias, opts)
+		if errors.Is(err, storage.ErrURLExists) {
+			continue
+		}
+		if err != nil {
+			return "", 0, err
+		}
+
+		return alias, id, nil
+	}
+}
+
+func (s *Storage) AliasCounter() (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.counter, nil
+}
+
+type apiKey struct {
+	key  storage.APIKey
+	hash string
+}
+
+func (s *Storage) SaveAPIKey(key storage.APIKey, hash string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key.ID = int64(len(s.apiKeys) + 1)
+	s.apiKeys = append(s.apiKeys, apiKey{key: key, hash: hash})
+
+	return key.ID, nil
+}
+
+func (s *Storage) GetAPIKeyByHash(hash string) (storage.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, k := range s.apiKeys {
+		if k.hash == hash {
+			return k.key, nil
+		}
+	}
+
+	return storage.APIKey{}, storage.ErrAPIKeyNotFound
+}
+
+func (s *Storage) ListAPIKeys() ([]storage.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]storage.APIKey, 0, len(s.apiKeys))
+	for _, k := range s.apiKeys {
+		keys = append(keys, k.key)
+	}
+
+	return keys, nil
+}
+
+func (s *Storage) TouchAPIKey(id int64, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id < 1 || id > int64(len(s.apiKeys)) {
+		return nil
+	}
+	s.apiKeys[id-1].key.LastUsedAt = &usedAt
+
+	return nil
+}
+
+func (s *Storage) RevokeAPIKey(id int64, revokedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id < 1 || id > int64(len(s.apiKeys)) {
+		return storage.ErrAPIKeyNotFound
+	}
+
+	key := &s.apiKeys[id-1].key
+	if key.RevokedAt == nil {
+		key.RevokedAt = &revokedAt
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveUser(user storage.User) (int64, error) {
+	const op = "storage.memory.SaveUser"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Name == user.Name {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+		}
+	}
+
+	user.ID = int64(len(s.users) + 1)
+	s.users = append(s.users, user)
+
+	return user.ID, nil
+}
+
+func (s *Storage) GetUserByName(name string) (storage.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u := range s.users {
+		if u.Name == name {
+			return u, nil
+		}
+	}
+
+	return storage.User{}, storage.ErrUserNotFound
+}
+
+func (s *Storage) ListUsers() ([]storage.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]storage.User, len(s.users))
+	copy(users, s.users)
+
+	return users, nil
+}
+
+// save must be called with s.mu held for writing.
+func (s *Storage) save(urlToSave string, alias string, opts storage.LinkOptions) (int64, error) {
+	if _, ok := s.links[alias]; ok {
+		return 0, storage.ErrURLExists
+	}
+
+	opts.Variants = cloneVariants(opts.Variants)
+
+	s.lastID++
+	s.links[alias] = &storage.Link{
+		ID:          s.lastID,
+		Alias:       alias,
+		URL:         urlToSave,
+		CreatedAt:   time.Now().UTC(),
+		LinkOptions: opts,
+	}
+
+	return s.lastID, nil
+}
+
+func (s *Storage) GetURL(alias string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	link, ok := s.links[alias]
+	if !ok {
+		return "", storage.ErrURLNotFound
+	}
+
+	return link.URL, nil
+}
+
+func (s *Storage) GetLink(alias string) (storage.Link, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	link, ok := s.links[alias]
+	if !ok {
+		return storage.Link{}, storage.ErrURLNotFound
+	}
+
+	return *link, nil
+}
+
+func (s *Storage) UseClick(alias string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.links[alias]
+	if !ok {
+		return 0, storage.ErrURLNotFound
+	}
+	if link.MaxClicks == 0 {
+		return 0, nil
+	}
+	if link.UsedUp() {
+		return 0, storage.ErrClicksExhausted
+	}
+
+	link.UsedClicks++
+
+	return link.ClicksLeft(), nil
+}
+
+func (s *Storage) ListURLs(limit, offset int, ownerID int64) ([]storage.Link, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	all := make([]storage.Link, 0, len(s.links))
+	for _, link := range s.links {
+		if link.OwnedBy(ownerID) {
+			all = append(all, *link)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+
+	links := make([]storage.Link, 0, limit)
+	for i := offset; i < len(all) && len(links) < limit; i++ {
+		links = append(links, all[i])
+	}
+
+	return links, nil
+}
+
+func (s *Storage) CountURLs(ownerID int64) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, link := range s.links {
+		if link.OwnedBy(ownerID) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (s *Storage) DeleteExpiredURLs(before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for alias, link := range s.links {
+		if link.ExpiresAt != nil && link.ExpiresAt.Before(before) {
+			delete(s.links, alias)
+			delete(s.clicks, alias)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+func (s *Storage) UpdateURL(alias string, newURL string, ownerID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.links[alias]
+	if !ok || !link.OwnedBy(ownerID) {
+		return storage.ErrURLNotFound
+	}
+
+	link.URL = newURL
+
+	return nil
+}
+
+func (s *Storage) UpdateLink(alias string, update storage.LinkUpdate, ownerID int64) (storage.Link, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.links[alias]
+	if !ok || !link.OwnedBy(ownerID) {
+		return storage.Link{}, storage.ErrURLNotFound
+	}
+
+	variants := link.Variants
+	if update.Variants != nil {
+		variants = update.Variants
+	}
+	if update.StickyVariants != nil && *update.StickyVariants && len(variants) == 0 {
+		return storage.Link{}, storage.ErrNoVariants
+	}
+
+	if update.URL != "" {
+		link.URL = update.URL
+	}
+	if update.Targets != nil {
+		// Keep a copy, the caller may reuse the targets.
+		link.Targets = nil
+		if !update.Targets.IsZero() {
+			t := *update.Targets
+			link.Targets = &t
+		}
+	}
+	if update.StickyVariants != nil {
+		link.StickyVariants = *update.StickyVariants
+	}
+	if update.Variants != nil {
+		link.Variants = storage.ResetClicks(update.Variants)
+	}
+
+	return *link, nil
+}
+
+func (s *Storage) DeleteURL(alias string, ownerID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.links[alias]
+	if !ok || !link.OwnedBy(ownerID) {
+		return storage.ErrURLNotFound
+	}
+
+	delete(s.links, alias)
+	delete(s.clicks, alias)
+
+	return nil
+}
+
+func (s *Storage) SaveClicks(clicks []storage.Click) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, click := range clicks {
+		link, ok := s.links[click.Alias]
+		if !ok {
+			continue
+		}
+		click.ClickedAt = click.ClickedAt.UTC()
+		s.clicks[click.Alias] = append(s.clicks[click.Alias], click)
+
+		if click.Variant > 0 && click.Variant <= len(link.Variants) {
+			// Links handed out share the slice, so count in a copy.
+			link.Variants = slices.Clone(link.Variants)
+			link.Variants[click.Variant-1].Clicks++
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) CountClicks(alias string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.links[alias]; !ok {
+		return 0, storage.ErrURLNotFound
+	}
+
+	return len(s.clicks[alias]), nil
+}
+
+func (s *Storage) ClickStats(alias string, since time.Time, ownerID int64) (storage.ClickStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	link, ok := s.links[alias]
+	if !ok || !link.OwnedBy(ownerID) {
+		return storage.ClickStats{}, storage.ErrURLNotFound
+	}
+
+	clicks := s.clicks[alias]
+	visitors := make(map[string]struct{})
+	perDay := make(map[string]int)
+	for _, click := range clicks {
+		visitors[click.IPHash] = struct{}{}
+		if !click.ClickedAt.Before(since) {
+			perDay[click.ClickedAt.Format(time.DateOnly)]++
+		}
+	}
+
+	stats := storage.ClickStats{
+		Total:          len(clicks),
+		UniqueVisitors: len(visitors),
+		Daily:          make([]storage.DailyClicks, 0, len(perDay)),
+		Variants:       link.Variants,
+	}
+	for date, n := range perDay {
+		stats.Daily = append(stats.Daily, storage.DailyClicks{Date: date, Clicks: n})
+	}
+	sort.Slice(stats.Daily, func(i, j int) bool { return stats.Daily[i].Date < stats.Daily[j].Date })
+
+	return stats, nil
+}
+
+// cloneVariants copies variants, so the caller cannot change stored links
+// through them. An empty slice yields nil.
+func cloneVariants(variants []storage.Variant) []storage.Variant {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	return slices.Clone(variants)
+}
@@ -0,0 +1,329 @@
+package migrate
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotMigrated      = errors.New("database schema is not up to date")
+	ErrNoDown           = errors.New("migration has no down script")
+	ErrNothingToUndo    = errors.New("no applied migrations")
+	ErrUnknownMigration = errors.New("database has migrations unknown to this binary")
+)
+
+// Migration is one versioned schema change. Its scripts are read from files
+// named <version>_<name>.up.sql and <version>_<name>.down.sql.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes a known migration and whether it has been applied.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies migrations and records them in the schema_version table.
+// Queries use $N placeholders, which both SQLite and PostgreSQL understand.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// New loads the *.sql files from the root of fsys.
+func New(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	const op = "storage.migrate.New"
+
+	migrations, err := load(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+func load(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, file := range files {
+		base, direction, ok := cutDirection(file)
+		if !ok {
+			return nil, fmt.Errorf("%s: expected .up.sql or .down.sql suffix", file)
+		}
+
+		rawVersion, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("%s: expected <version>_<name> prefix", file)
+		}
+
+		version, err := strconv.Atoi(rawVersion)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid version: %w", file, err)
+		}
+
+		script, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("%s: version %d is already used by %q", file, version, m.Name)
+		}
+
+		if direction == "up" {
+			m.Up = string(script)
+		} else {
+			m.Down = string(script)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+func cutDirection(file string) (string, string, bool) {
+	file = path.Base(file)
+
+	if base, ok := strings.CutSuffix(file, ".up.sql"); ok {
+		return base, "up", true
+	}
+	if base, ok := strings.CutSuffix(file, ".down.sql"); ok {
+		return base, "down", true
+	}
+
+	return "", "", false
+}
+
+// Up applies all pending migrations in order, each in its own transaction,
+// and returns the ones it applied.
+func (m *Migrator) Up() ([]Migration, error) {
+	const op = "storage.migrate.Up"
+
+	applied, err := m.applied()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var done []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		err := m.inTx(func(tx *sql.Tx) error {
+			if _, err := tx.Exec(migration.Up); err != nil {
+				return err
+			}
+
+			_, err := tx.Exec(
+				"INSERT INTO schema_version(version, name, applied_at) VALUES($1, $2, $3)",
+				migration.Version, migration.Name, time.Now().UTC(),
+			)
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("%s: apply %d_%s: %w", op, migration.Version, migration.Name, err)
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Down rolls back the most recently applied migration and returns it.
+func (m *Migrator) Down() (Migration, error) {
+	const op = "storage.migrate.Down"
+
+	applied, err := m.applied()
+	if err != nil {
+		return Migration{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+
+		if migration.Down == "" {
+			return Migration{}, fmt.Errorf("%s: %d_%s: %w", op, migration.Version, migration.Name, ErrNoDown)
+		}
+
+		err := m.inTx(func(tx *sql.Tx) error {
+			if _, err := tx.Exec(migration.Down); err != nil {
+				return err
+			}
+
+			_, err := tx.Exec("DELETE FROM schema_version WHERE version = $1", migration.Version)
+			return err
+		})
+		if err != nil {
+			return Migration{}, fmt.Errorf("%s: revert %d_%s: %w", op, migration.Version, migration.Name, err)
+		}
+
+		return migration, nil
+	}
+
+	return Migration{}, fmt.Errorf("%s: %w", op, ErrNothingToUndo)
+}
+
+// Status lists all known migrations in order.
+func (m *Migrator) Status() ([]Status, error) {
+	const op = "storage.migrate.Status"
+
+	applied, err := m.applied()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses = append(statuses, Status{
+			Migration: migration,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+
+	return statuses, nil
+}
+
+// Check returns ErrNotMigrated if any known migration is not applied yet.
+func (m *Migrator) Check() error {
+	const op = "storage.migrate.Check"
+
+	applied, err := m.applied()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	pending := 0
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%s: %w: %d pending migration(s)", op, ErrNotMigrated, pending)
+	}
+
+	return nil
+}
+
+// CheckLatest returns ErrNotMigrated if the newest known migration is not
+// applied. Unlike Check it only reads schema_version, so it is cheap enough
+// for readiness probes and never takes a write lock.
+func (m *Migrator) CheckLatest() error {
+	const op = "storage.migrate.CheckLatest"
+
+	if len(m.migrations) == 0 {
+		return nil
+	}
+
+	var latest int
+	if err := m.db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&latest); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if want := m.migrations[len(m.migrations)-1].Version; latest < want {
+		return fmt.Errorf("%s: %w: at version %d, want %d", op, ErrNotMigrated, latest, want)
+	}
+
+	return nil
+}
+
+// CheckKnown returns ErrUnknownMigration if the database has applied
+// migrations this binary does not know, i.e. it was written by a newer version.
+func (m *Migrator) CheckKnown() error {
+	const op = "storage.migrate.CheckKnown"
+
+	applied, err := m.applied()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, migration := range m.migrations {
+		delete(applied, migration.Version)
+	}
+	if len(applied) > 0 {
+		return fmt.Errorf("%s: %w: %d unknown migration(s)", op, ErrUnknownMigration, len(applied))
+	}
+
+	return nil
+}
+
+// applied returns the applied versions together with the time they were applied.
+func (m *Migrator) applied() (map[int]time.Time, error) {
+	_, err := m.db.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_version(
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL);
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("create schema_version: %w", err)
+	}
+
+	rows, err := m.db.Query("SELECT version, applied_at FROM schema_version")
+	if err != nil {
+		return nil, fmt.Errorf("select schema_version: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("scan schema_version: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+func (m *Migrator) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,108 @@
+package migrate_test
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/storage/migrate"
+)
+
+var migrations = fstest.MapFS{
+	"0001_init.up.sql":        {Data: []byte("CREATE TABLE url(id INTEGER PRIMARY KEY, url TEXT NOT NULL);")},
+	"0001_init.down.sql":      {Data: []byte("DROP TABLE url;")},
+	"0002_add_alias.up.sql":   {Data: []byte("ALTER TABLE url ADD COLUMN alias TEXT;")},
+	"0002_add_alias.down.sql": {Data: []byte("ALTER TABLE url DROP COLUMN alias;")},
+}
+
+func newMigrator(t *testing.T, fsys fstest.MapFS) (*migrate.Migrator, *sql.DB) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	m, err := migrate.New(db, fsys)
+	require.NoError(t, err)
+
+	return m, db
+}
+
+func TestMigrator_UpDown(t *testing.T) {
+	m, db := newMigrator(t, migrations)
+
+	require.ErrorIs(t, m.Check(), migrate.ErrNotMigrated)
+	require.Error(t, m.CheckLatest(), "schema_version does not exist yet")
+
+	applied, err := m.Up()
+	require.NoError(t, err)
+	require.Len(t, applied, 2)
+	require.NoError(t, m.Check())
+	require.NoError(t, m.CheckLatest())
+
+	_, err = db.Exec("INSERT INTO url(url, alias) VALUES('https://google.com', 'g')")
+	require.NoError(t, err)
+
+	applied, err = m.Up()
+	require.NoError(t, err)
+	require.Empty(t, applied)
+
+	reverted, err := m.Down()
+	require.NoError(t, err)
+	require.Equal(t, 2, reverted.Version)
+	require.ErrorIs(t, m.Check(), migrate.ErrNotMigrated)
+	require.ErrorIs(t, m.CheckLatest(), migrate.ErrNotMigrated)
+
+	statuses, err := m.Status()
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	require.True(t, statuses[0].Applied)
+	require.False(t, statuses[1].Applied)
+
+	_, err = m.Down()
+	require.NoError(t, err)
+
+	_, err = m.Down()
+	require.ErrorIs(t, err, migrate.ErrNothingToUndo)
+}
+
+func TestMigrator_FailedMigrationIsRolledBack(t *testing.T) {
+	m, db := newMigrator(t, fstest.MapFS{
+		"0001_init.up.sql":   migrations["0001_init.up.sql"],
+		"0002_broken.up.sql": {Data: []byte("CREATE TABLE extra(id INTEGER); SELECT * FROM missing;")},
+	})
+
+	applied, err := m.Up()
+	require.Error(t, err)
+	require.Len(t, applied, 1)
+
+	var n int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'extra'").Scan(&n))
+	require.Zero(t, n)
+}
+
+func TestMigrator_CheckKnown(t *testing.T) {
+	m, db := newMigrator(t, migrations)
+
+	_, err := m.Up()
+	require.NoError(t, err)
+	require.NoError(t, m.CheckKnown())
+
+	older, err := migrate.New(db, fstest.MapFS{
+		"0001_init.up.sql": migrations["0001_init.up.sql"],
+	})
+	require.NoError(t, err)
+	require.ErrorIs(t, older.CheckKnown(), migrate.ErrUnknownMigration)
+}
+
+func TestNew_InvalidFiles(t *testing.T) {
+	_, err := migrate.New(nil, fstest.MapFS{"init.up.sql": {Data: []byte("SELECT 1;")}})
+	require.Error(t, err)
+
+	_, err = migrate.New(nil, fstest.MapFS{"0001_init.down.sql": {Data: []byte("SELECT 1;")}})
+	require.Error(t, err)
+}
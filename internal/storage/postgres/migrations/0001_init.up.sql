@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS url(
+	id BIGSERIAL PRIMARY KEY,
+	alias TEXT NOT NULL UNIQUE,
+	url TEXT NOT NULL);
+
+CREATE TABLE IF NOT EXISTS alias_value(
+	id SERIAL PRIMARY KEY,
+	value BIGINT NOT NULL,
+	name TEXT NOT NULL UNIQUE);
+
+INSERT INTO alias_value(value, name) VALUES(0, 'Counter') ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,9 @@
+CREATE TABLE clicks(
+	id BIGSERIAL PRIMARY KEY,
+	url_id BIGINT NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+	clicked_at TIMESTAMPTZ NOT NULL,
+	referrer TEXT NOT NULL,
+	user_agent TEXT NOT NULL,
+	ip_hash TEXT NOT NULL,
+	request_id TEXT NOT NULL);
+CREATE INDEX idx_clicks_url_id_clicked_at ON clicks(url_id, clicked_at);
@@ -0,0 +1,3 @@
+ALTER TABLE url ADD COLUMN active_from TIMESTAMPTZ;
+ALTER TABLE url ADD COLUMN expires_at TIMESTAMPTZ;
+CREATE INDEX idx_url_expires_at ON url(expires_at);
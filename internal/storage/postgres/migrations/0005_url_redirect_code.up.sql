@@ -0,0 +1 @@
+ALTER TABLE url ADD COLUMN redirect_code INTEGER NOT NULL DEFAULT 0;
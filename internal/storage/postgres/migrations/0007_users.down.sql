@@ -0,0 +1,4 @@
+ALTER TABLE api_keys DROP COLUMN user_id;
+DROP INDEX idx_url_owner_id;
+ALTER TABLE url DROP COLUMN owner_id;
+DROP TABLE users;
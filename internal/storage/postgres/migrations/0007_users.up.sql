@@ -0,0 +1,9 @@
+CREATE TABLE users(
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	password_hash TEXT NOT NULL,
+	role TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL);
+ALTER TABLE url ADD COLUMN owner_id BIGINT REFERENCES users(id) ON DELETE SET NULL;
+CREATE INDEX idx_url_owner_id ON url(owner_id);
+ALTER TABLE api_keys ADD COLUMN user_id BIGINT REFERENCES users(id) ON DELETE CASCADE;
@@ -0,0 +1,3 @@
+ALTER TABLE url DROP COLUMN target_desktop;
+ALTER TABLE url DROP COLUMN target_android;
+ALTER TABLE url DROP COLUMN target_ios;
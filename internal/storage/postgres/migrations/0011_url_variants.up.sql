@@ -0,0 +1,8 @@
+CREATE TABLE url_variants(
+	url_id BIGINT NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	url TEXT NOT NULL,
+	weight INTEGER NOT NULL,
+	clicks BIGINT NOT NULL DEFAULT 0,
+	PRIMARY KEY (url_id, position));
+ALTER TABLE url ADD COLUMN sticky_variants BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,897 @@
+package postgres
+
+import (
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	customalias "url-shortener/internal/lib/custom_alias"
+	generatingalias "url-shortener/internal/lib/generating_alias"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/migrate"
+)
+
+// uniqueViolation is the PostgreSQL error code for unique constraint violations.
+const uniqueViolation = "23505"
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+var _ storage.Store = (*Storage)(nil)
+
+type Storage struct {
+	db             *sql.DB
+	aliasMaxLength int
+	reserved       customalias.Reserved
+}
+
+// New connects to the database described by dsn. The schema is managed by
+// Migrator. aliasMaxLength limits the length of generated aliases, zero means
+// no limit. Generated aliases skip customalias.DefaultReserved and reserved.
+func New(dsn string, aliasMaxLength int, reserved []string) (*Storage, error) {
+	const op = "storage.postgres.New"
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Storage{db: db, aliasMaxLength: aliasMaxLength, reserved: customalias.NewReserved(reserved)}, nil
+}
+
+// Migrator returns the schema migrator for this database.
+func (s *Storage) Migrator() (*migrate.Migrator, error) {
+	const op = "storage.postgres.Migrator"
+
+	fsys, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	m, err := migrate.New(s.db, fsys)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return m, nil
+}
+
+func (s *Storage) Ping() error {
+	return s.db.Ping()
+}
+
+func (s *Storage) Close() error {
+	return s.db.Close()
+}
+
+func (s *Storage) SaveURL(urlToSave string, alias string, opts storage.LinkOptions) (int64, error) {
+	const op = "storage.postgres.SaveURL"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	id, err := insertURL(tx, urlToSave, alias, opts)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// SaveGeneratedURL reserves the next free alias from the alias counter and
+// saves urlToSave under it. The counter row stays locked until the
+// transaction ends, so concurrent instances never get the same alias.
+// Aliases already taken by custom links are skipped.
+func (s *Storage) SaveGeneratedURL(urlToSave string, opts storage.LinkOptions) (string, int64, error) {
+	const op = "storage.postgres.SaveGeneratedURL"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return "", 0, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	alias, id, err := s.saveGenerated(tx, urlToSave, opts)
+	if err != nil {
+		return "", 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", 0, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return alias, id, nil
+}
+
+// SaveURLBatch saves links in one transaction. Taken custom aliases and an
+// exhausted alias space are reported per link, any other error rolls back
+// the whole batch.
+func (s *Storage) SaveURLBatch(links []storage.NewLink) ([]storage.BatchResult, error) {
+	const op = "storage.postgres.SaveURLBatch"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	results := make([]storage.BatchResult, len(links))
+	for i, link := range links {
+		res := storage.BatchResult{Alias: link.Alias}
+		if link.Alias == "" {
+			res.Alias, res.ID, err = s.saveGenerated(tx, link.URL, link.LinkOptions)
+		} else {
+			res.ID, err = insertURL(tx, link.URL, link.Alias, link.LinkOptions)
+		}
+		if errors.Is(err, storage.ErrURLExists) || errors.Is(err, storage.ErrAliasSpaceExhausted) {
+			res.Err, err = err, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		results[i] = res
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return results, nil
+}
+
+// ImportLinks saves links in one transaction, see storage.Store.
+func (s *Storage) ImportLinks(links []storage.Link, onConflict storage.ConflictPolicy) (storage.ImportStats, error) {
+	const op = "storage.postgres.ImportLinks"
+
+	var stats storage.ImportStats
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return stats, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().UTC()
+	for _, link := range links {
+		createdAt := link.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = now
+		}
+
+		// The owner subquery yields NULL for users missing from this database.
+		targets := targetValues(link.Targets)
+		var id int64
+		err := tx.QueryRow(`
+		INSERT INTO url(url, alias, created_at, active_from, expires_at, redirect_code, owner_id, password_hash, max_clicks, used_clicks,
+			target_ios, target_android, target_desktop, sticky_variants)
+		VALUES($1, $2, $3, $4, $5, $6, (SELECT id FROM users WHERE id = $7), $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT (alias) DO NOTHING
+		RETURNING id`,
+			link.URL, link.Alias, createdAt, link.ActiveFrom, link.ExpiresAt, link.RedirectCode, nullID(link.OwnerID), link.PasswordHash,
+			link.MaxClicks, link.UsedClicks, targets.IOS, targets.Android, targets.Desktop, link.StickyVariants,
+		).Scan(&id)
+		if err == nil {
+			if err := saveVariants(tx, id, link.Variants); err != nil {
+				return stats, fmt.Errorf("%s: %w", op, err)
+			}
+			stats.Created++
+			continue
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return stats, fmt.Errorf("%s: insert url: %w", op, err)
+		}
+
+		switch onConflict {
+		case storage.ConflictSkip:
+			stats.Skipped++
+		case storage.ConflictOverwrite:
+			err := tx.QueryRow(`
+			UPDATE url SET url = $1, created_at = $2, active_from = $3, expires_at = $4, redirect_code = $5,
+				owner_id = (SELECT id FROM users WHERE id = $6), password_hash = $7, max_clicks = $8, used_clicks = $9,
+				target_ios = $10, target_android = $11, target_desktop = $12, sticky_variants = $13
+			WHERE alias = $14
+			RETURNING id`,
+				link.URL, createdAt, link.ActiveFrom, link.ExpiresAt, link.RedirectCode, nullID(link.OwnerID), link.PasswordHash,
+				link.MaxClicks, link.UsedClicks, targets.IOS, targets.Android, targets.Desktop, link.StickyVariants, link.Alias,
+			).Scan(&id)
+			if err != nil {
+				return stats, fmt.Errorf("%s: update url: %w", op, err)
+			}
+			if err := saveVariants(tx, id, link.Variants); err != nil {
+				return stats, fmt.Errorf("%s: %w", op, err)
+			}
+			stats.Overwritten++
+		default:
+			return storage.ImportStats{}, fmt.Errorf("%s: alias %q: %w", op, link.Alias, storage.ErrURLExists)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return storage.ImportStats{}, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return stats, nil
+}
+
+func (s *Storage) RaiseAliasCounter(counter int64) error {
+	const op = "storage.postgres.RaiseAliasCounter"
+
+	_, err := s.db.Exec("UPDATE alias_value SET value = $1 WHERE name = 'Counter' AND value < $1", counter)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// saveGenerated advances the alias counter until an alias that is neither
+// reserved nor taken by a custom link is found and saves urlToSave under it.
+// The counter is never advanced past the capacity of the alias space.
+func (s *Storage) saveGenerated(tx *sql.Tx, urlToSave string, opts storage.LinkOptions) (string, int64, error) {
+	capacity := generatingalias.Capacity(s.aliasMaxLength)
+
+	for {
+		var counter int64
+		err := tx.QueryRow("UPDATE alias_value SET value = value + 1 WHERE name = 'Counter' AND value < $1 RETURNING value - 1", capacity).
+			Scan(&counter)
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", 0, storage.ErrAliasSpaceExhausted
+		}
+		if err != nil {
+			return "", 0, fmt.Errorf("advance alias counter: %w", err)
+		}
+
+		alias := generatingalias.NewGeneratedAlias(counter)
+		if s.reserved.Contains(alias) {
+			continue
+		}
+
+		id, err := insertURL(tx, urlToSave, alias, opts)
+		if errors.Is(err, storage.ErrURLExists) {
+			continue
+		}
+		if err != nil {
+			return "", 0, err
+		}
+
+		return alias, id, nil
+	}
+}
+
+// saveVariants replaces the variants of the link with urlID.
+func saveVariants(tx *sql.Tx, urlID int64, variants []storage.Variant) error {
+	if _, err := tx.Exec("DELETE FROM url_variants WHERE url_id = $1", urlID); err != nil {
+		return fmt.Errorf("delete variants: %w", err)
+	}
+
+	for i, variant := range variants {
+		_, err := tx.Exec("INSERT INTO url_variants(url_id, position, url, weight, clicks) VALUES($1, $2, $3, $4, $5)",
+			urlID, i+1, variant.URL, variant.Weight, variant.Clicks)
+		if err != nil {
+			return fmt.Errorf("insert variant: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// insertURL saves urlToSave under alias, a taken alias is reported as
+// storage.ErrURLExists without aborting the transaction.
+func insertURL(tx *sql.Tx, urlToSave, alias string, opts storage.LinkOptions) (int64, error) {
+	var id int64
+	targets := targetValues(opts.Targets)
+	err := tx.QueryRow(`
+	INSERT INTO url(url, alias, active_from, expires_at, redirect_code, owner_id, password_hash, max_clicks,
+		target_ios, target_android, target_desktop, sticky_variants)
+	VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	ON CONFLICT (alias) DO NOTHING
+	RETURNING id`,
+		urlToSave, alias, opts.ActiveFrom, opts.ExpiresAt, opts.RedirectCode, nullID(opts.OwnerID), opts.PasswordHash, opts.MaxClicks,
+		targets.IOS, targets.Android, targets.Desktop, opts.StickyVariants,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, storage.ErrURLExists
+	}
+	if err != nil {
+		return 0, fmt.Errorf("insert url: %w", err)
+	}
+
+	if err := saveVariants(tx, id, opts.Variants); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (s *Storage) AliasCounter() (int64, error) {
+	const op = "storage.postgres.AliasCounter"
+
+	var counter int64
+	if err := s.db.QueryRow("SELECT value FROM alias_value WHERE name = 'Counter'").Scan(&counter); err != nil {
+		return 0, fmt.Errorf("%s: select statement: %w", op, err)
+	}
+
+	return counter, nil
+}
+
+func (s *Storage) GetURL(alias string) (string, error) {
+	const op = "storage.postgres.GetURL"
+
+	var resURL string
+
+	err := s.db.QueryRow("SELECT url FROM url WHERE alias = $1", alias).Scan(&resURL)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", storage.ErrURLNotFound
+		}
+
+		return "", fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return resURL, nil
+}
+
+func (s *Storage) GetLink(alias string) (storage.Link, error) {
+	const op = "storage.postgres.GetLink"
+
+	link, err := scanLink(s.db.QueryRow("SELECT "+linkColumns+" FROM url WHERE alias = $1", alias))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.Link{}, storage.ErrURLNotFound
+		}
+
+		return storage.Link{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return link, nil
+}
+
+func (s *Storage) UseClick(alias string) (int64, error) {
+	const op = "storage.postgres.UseClick"
+
+	// The check and the increment are one statement, so concurrent redirects
+	// cannot both take the last click.
+	var left int64
+	err := s.db.QueryRow(`
+	UPDATE url SET used_clicks = used_clicks + 1
+	WHERE alias = $1 AND max_clicks > 0 AND used_clicks < max_clicks
+	RETURNING max_clicks - used_clicks`, alias).Scan(&left)
+	if err == nil {
+		return left, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%s: update statement: %w", op, err)
+	}
+
+	// Nothing was counted, the link is unknown, used up or not limited.
+	var maxClicks int64
+	err = s.db.QueryRow("SELECT max_clicks FROM url WHERE alias = $1", alias).Scan(&maxClicks)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, storage.ErrURLNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: select statement: %w", op, err)
+	}
+	if maxClicks > 0 {
+		return 0, storage.ErrClicksExhausted
+	}
+
+	return 0, nil
+}
+
+func (s *Storage) ListURLs(limit, offset int, ownerID int64) ([]storage.Link, error) {
+	const op = "storage.postgres.ListURLs"
+
+	rows, err := s.db.Query("SELECT "+linkColumns+" FROM url WHERE "+ownedBy(3)+" ORDER BY id LIMIT $1 OFFSET $2",
+		limit, offset, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: select statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	links := make([]storage.Link, 0, limit)
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: iterate rows: %w", op, err)
+	}
+
+	return links, nil
+}
+
+func (s *Storage) CountURLs(ownerID int64) (int, error) {
+	const op = "storage.postgres.CountURLs"
+
+	var count int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM url WHERE "+ownedBy(1), ownerID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("%s: select statement: %w", op, err)
+	}
+
+	return count, nil
+}
+
+func (s *Storage) DeleteExpiredURLs(before time.Time) (int64, error) {
+	const op = "storage.postgres.DeleteExpiredURLs"
+
+	res, err := s.db.Exec("DELETE FROM url WHERE expires_at < $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("%s: delete statement: %w", op, err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	return deleted, nil
+}
+
+func (s *Storage) UpdateURL(alias string, newURL string, ownerID int64) error {
+	const op = "storage.postgres.UpdateURL"
+
+	res, err := s.db.Exec("UPDATE url SET url = $1 WHERE alias = $2 AND "+ownedBy(3), newURL, alias, ownerID)
+	if err != nil {
+		return fmt.Errorf("%s: update statement: %w", op, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+	if rowsAffected == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) UpdateLink(alias string, update storage.LinkUpdate, ownerID int64) (storage.Link, error) {
+	const op = "storage.postgres.UpdateLink"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// FOR UPDATE keeps concurrent updates of the link from interleaving.
+	var id int64
+	err = tx.QueryRow("SELECT id FROM url WHERE alias = $1 AND "+ownedBy(2)+" FOR UPDATE", alias, ownerID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.Link{}, storage.ErrURLNotFound
+	}
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("%s: select statement: %w", op, err)
+	}
+
+	if update.URL != "" {
+		if _, err := tx.Exec("UPDATE url SET url = $1 WHERE id = $2", update.URL, id); err != nil {
+			return storage.Link{}, fmt.Errorf("%s: update url: %w", op, err)
+		}
+	}
+
+	if update.Targets != nil {
+		t := *update.Targets
+		_, err := tx.Exec("UPDATE url SET target_ios = $1, target_android = $2, target_desktop = $3 WHERE id = $4",
+			t.IOS, t.Android, t.Desktop, id)
+		if err != nil {
+			return storage.Link{}, fmt.Errorf("%s: update targets: %w", op, err)
+		}
+	}
+
+	if update.StickyVariants != nil {
+		if _, err := tx.Exec("UPDATE url SET sticky_variants = $1 WHERE id = $2", *update.StickyVariants, id); err != nil {
+			return storage.Link{}, fmt.Errorf("%s: update sticky variants: %w", op, err)
+		}
+	}
+
+	if update.Variants != nil {
+		if err := saveVariants(tx, id, storage.ResetClicks(update.Variants)); err != nil {
+			return storage.Link{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	link, err := scanLink(tx.QueryRow("SELECT "+linkColumns+" FROM url WHERE id = $1", id))
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("%s: select updated link: %w", op, err)
+	}
+
+	if update.StickyVariants != nil && *update.StickyVariants && len(link.Variants) == 0 {
+		return storage.Link{}, storage.ErrNoVariants
+	}
+
+	if err := tx.Commit(); err != nil {
+		return storage.Link{}, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return link, nil
+}
+
+func (s *Storage) DeleteURL(alias string, ownerID int64) error {
+	const op = "storage.postgres.DeleteURL"
+
+	res, err := s.db.Exec("DELETE FROM url WHERE alias = $1 AND "+ownedBy(2), alias, ownerID)
+	if err != nil {
+		return fmt.Errorf("%s: delete statement: %w", op, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+	if rowsAffected == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveClicks(clicks []storage.Click) error {
+	const op = "storage.postgres.SaveClicks"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.Prepare(`
+	INSERT INTO clicks(url_id, clicked_at, referrer, user_agent, ip_hash, request_id)
+	SELECT id, $1, $2, $3, $4, $5 FROM url WHERE alias = $6`)
+	if err != nil {
+		return fmt.Errorf("%s: prepare statement: %w", op, err)
+	}
+	defer stmt.Close()
+
+	variantStmt, err := tx.Prepare(`
+	UPDATE url_variants SET clicks = clicks + 1
+	WHERE url_id = (SELECT id FROM url WHERE alias = $1) AND position = $2`)
+	if err != nil {
+		return fmt.Errorf("%s: prepare statement: %w", op, err)
+	}
+	defer variantStmt.Close()
+
+	for _, click := range clicks {
+		_, err := stmt.Exec(click.ClickedAt.UTC(), click.Referrer, click.UserAgent, click.IPHash, click.RequestID, click.Alias)
+		if err != nil {
+			return fmt.Errorf("%s: insert click: %w", op, err)
+		}
+
+		if click.Variant == 0 {
+			continue
+		}
+		if _, err := variantStmt.Exec(click.Alias, click.Variant); err != nil {
+			return fmt.Errorf("%s: count variant click: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) CountClicks(alias string) (int, error) {
+	const op = "storage.postgres.CountClicks"
+
+	var n int
+	err := s.db.QueryRow("SELECT (SELECT COUNT(*) FROM clicks WHERE url_id = url.id) FROM url WHERE alias = $1", alias).Scan(&n)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, storage.ErrURLNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: select statement: %w", op, err)
+	}
+
+	return n, nil
+}
+
+func (s *Storage) ClickStats(alias string, since time.Time, ownerID int64) (storage.ClickStats, error) {
+	const op = "storage.postgres.ClickStats"
+
+	var urlID int64
+	err := s.db.QueryRow("SELECT id FROM url WHERE alias = $1 AND "+ownedBy(2), alias, ownerID).Scan(&urlID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.ClickStats{}, storage.ErrURLNotFound
+	} else if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: select url: %w", op, err)
+	}
+
+	stats := storage.ClickStats{Daily: []storage.DailyClicks{}}
+
+	err = s.db.QueryRow("SELECT COUNT(*), COUNT(DISTINCT ip_hash) FROM clicks WHERE url_id = $1", urlID).
+		Scan(&stats.Total, &stats.UniqueVisitors)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: select totals: %w", op, err)
+	}
+
+	rows, err := s.db.Query(`
+	SELECT to_char(clicked_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS day, COUNT(*) FROM clicks
+	WHERE url_id = $1 AND clicked_at >= $2
+	GROUP BY day ORDER BY day`, urlID, since.UTC())
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: select daily: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var day storage.DailyClicks
+		if err := rows.Scan(&day.Date, &day.Clicks); err != nil {
+			return storage.ClickStats{}, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+		stats.Daily = append(stats.Daily, day)
+	}
+	if err := rows.Err(); err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: iterate rows: %w", op, err)
+	}
+
+	var variants string
+	if err := s.db.QueryRow("SELECT "+variantsColumn+" FROM url WHERE id = $1", urlID).Scan(&variants); err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: select variants: %w", op, err)
+	}
+	if stats.Variants, err = parseVariants(variants); err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return stats, nil
+}
+
+func (s *Storage) SaveAPIKey(key storage.APIKey, hash string) (int64, error) {
+	const op = "storage.postgres.SaveAPIKey"
+
+	var id int64
+	err := s.db.QueryRow(`
+	INSERT INTO api_keys(name, prefix, key_hash, scopes, created_at, user_id) VALUES($1, $2, $3, $4, $5, $6)
+	RETURNING id`, key.Name, key.Prefix, hash, strings.Join(key.Scopes, ","), key.CreatedAt, nullID(key.UserID)).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetAPIKeyByHash(hash string) (storage.APIKey, error) {
+	const op = "storage.postgres.GetAPIKeyByHash"
+
+	key, err := scanAPIKey(s.db.QueryRow("SELECT "+apiKeyColumns+" FROM api_keys WHERE key_hash = $1", hash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.APIKey{}, storage.ErrAPIKeyNotFound
+		}
+
+		return storage.APIKey{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return key, nil
+}
+
+func (s *Storage) ListAPIKeys() ([]storage.APIKey, error) {
+	const op = "storage.postgres.ListAPIKeys"
+
+	rows, err := s.db.Query("SELECT " + apiKeyColumns + " FROM api_keys ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("%s: select statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	keys := []storage.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: iterate rows: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+func (s *Storage) TouchAPIKey(id int64, usedAt time.Time) error {
+	const op = "storage.postgres.TouchAPIKey"
+
+	if _, err := s.db.Exec("UPDATE api_keys SET last_used_at = $1 WHERE id = $2", usedAt, id); err != nil {
+		return fmt.Errorf("%s: update statement: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) RevokeAPIKey(id int64, revokedAt time.Time) error {
+	const op = "storage.postgres.RevokeAPIKey"
+
+	res, err := s.db.Exec("UPDATE api_keys SET revoked_at = COALESCE(revoked_at, $1) WHERE id = $2", revokedAt, id)
+	if err != nil {
+		return fmt.Errorf("%s: update statement: %w", op, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+	if rowsAffected == 0 {
+		return storage.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveUser(user storage.User) (int64, error) {
+	const op = "storage.postgres.SaveUser"
+
+	var id int64
+	err := s.db.QueryRow(`
+	INSERT INTO users(name, password_hash, role, created_at) VALUES($1, $2, $3, $4)
+	RETURNING id`, user.Name, user.PasswordHash, user.Role, user.CreatedAt).Scan(&id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+		}
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetUserByName(name string) (storage.User, error) {
+	const op = "storage.postgres.GetUserByName"
+
+	var user storage.User
+	err := s.db.QueryRow("SELECT "+userColumns+" FROM users WHERE name = $1", name).
+		Scan(&user.ID, &user.Name, &user.PasswordHash, &user.Role, &user.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.User{}, storage.ErrUserNotFound
+		}
+
+		return storage.User{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return user, nil
+}
+
+func (s *Storage) ListUsers() ([]storage.User, error) {
+	const op = "storage.postgres.ListUsers"
+
+	rows, err := s.db.Query("SELECT " + userColumns + " FROM users ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("%s: select statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	users := []storage.User{}
+	for rows.Next() {
+		var user storage.User
+		if err := rows.Scan(&user.ID, &user.Name, &user.PasswordHash, &user.Role, &user.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: iterate rows: %w", op, err)
+	}
+
+	return users, nil
+}
+
+// ownedBy restricts a url query to the links of the owner passed as
+// parameter n, storage.AnyOwner matches every link and storage.NoOwner none.
+func ownedBy(n int) string {
+	return fmt.Sprintf("($%d::BIGINT = 0 OR owner_id = $%d)", n, n)
+}
+
+// linkColumns lists the url columns in the order scanLink expects them.
+const linkColumns = "id, alias, url, created_at, active_from, expires_at, redirect_code, owner_id, password_hash, max_clicks, used_clicks, " +
+	"target_ios, target_android, target_desktop, sticky_variants, " + variantsColumn
+
+// variantsColumn selects the variants of a url row as a JSON array in
+// position order.
+const variantsColumn = `COALESCE((SELECT json_agg(json_build_object('url', v.url, 'weight', v.weight, 'clicks', v.clicks) ORDER BY v.position)
+	FROM url_variants v WHERE v.url_id = url.id), '[]')`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanLink(row scanner) (storage.Link, error) {
+	var link storage.Link
+	var ownerID sql.NullInt64
+	var targets storage.Targets
+	var variants string
+
+	err := row.Scan(
+		&link.ID, &link.Alias, &link.URL, &link.CreatedAt, &link.ActiveFrom, &link.ExpiresAt, &link.RedirectCode, &ownerID, &link.PasswordHash,
+		&link.MaxClicks, &link.UsedClicks, &targets.IOS, &targets.Android, &targets.Desktop, &link.StickyVariants, &variants,
+	)
+	if err != nil {
+		return link, err
+	}
+
+	link.OwnerID = ownerID.Int64
+	if !targets.IsZero() {
+		link.Targets = &targets
+	}
+	if link.Variants, err = parseVariants(variants); err != nil {
+		return link, err
+	}
+
+	return link, err
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
+
+// apiKeyColumns lists the api_keys columns in the order scanAPIKey expects them.
+const apiKeyColumns = "id, name, prefix, scopes, created_at, last_used_at, revoked_at, user_id"
+
+func scanAPIKey(row scanner) (storage.APIKey, error) {
+	var key storage.APIKey
+	var scopes string
+	var userID sql.NullInt64
+
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, &scopes, &key.CreatedAt, &key.LastUsedAt, &key.RevokedAt, &userID)
+	key.UserID = userID.Int64
+	if scopes != "" {
+		key.Scopes = strings.Split(scopes, ",")
+	}
+
+	return key, err
+}
+
+// userColumns lists the users columns in the order they are scanned.
+const userColumns = "id, name, password_hash, role, created_at"
+
+// nullID stores a zero id as NULL.
+func nullID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
+
+// parseVariants decodes the JSON array selected by variantsColumn, an empty
+// array yields nil.
+func parseVariants(s string) ([]storage.Variant, error) {
+	var variants []storage.Variant
+	if err := json.Unmarshal([]byte(s), &variants); err != nil {
+		return nil, fmt.Errorf("decode variants: %w", err)
+	}
+	if len(variants) == 0 {
+		return nil, nil
+	}
+
+	return variants, nil
+}
+
+// targetValues returns the column values of targets, empty for nil.
+func targetValues(targets *storage.Targets) storage.Targets {
+	if targets == nil {
+		return storage.Targets{}
+	}
+
+	return *targets
+}
@@ -0,0 +1,582 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/random"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/postgres"
+)
+
+// Run with: POSTGRES_DSN=postgres://... go test -tags integration ./internal/storage/postgres/
+func newStorage(t *testing.T) *postgres.Storage {
+	t.Helper()
+
+	dsn := os.Getenv("POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_DSN is not set")
+	}
+
+	s, err := postgres.New(dsn, 0, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+
+	m, err := s.Migrator()
+	require.NoError(t, err)
+	_, err = m.Up()
+	require.NoError(t, err)
+
+	return s
+}
+
+func TestStorage_CRUD(t *testing.T) {
+	s := newStorage(t)
+
+	alias := random.NewRandomString(12)
+
+	_, err := s.SaveURL("https://google.com", alias, storage.LinkOptions{})
+	require.NoError(t, err)
+
+	_, err = s.SaveURL("https://google.com", alias, storage.LinkOptions{})
+	require.ErrorIs(t, err, storage.ErrURLExists)
+
+	got, err := s.GetURL(alias)
+	require.NoError(t, err)
+	require.Equal(t, "https://google.com", got)
+
+	require.NoError(t, s.UpdateURL(alias, "https://google.de", storage.AnyOwner))
+
+	link, err := s.GetLink(alias)
+	require.NoError(t, err)
+	require.Equal(t, "https://google.de", link.URL)
+	require.False(t, link.CreatedAt.IsZero())
+
+	require.NoError(t, s.DeleteURL(alias, storage.AnyOwner))
+	require.ErrorIs(t, s.DeleteURL(alias, storage.AnyOwner), storage.ErrURLNotFound)
+
+	_, err = s.GetURL(alias)
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestStorage_SaveGeneratedURL_Concurrent(t *testing.T) {
+	s := newStorage(t)
+
+	const n = 50
+
+	var wg sync.WaitGroup
+	aliases := make(chan string, n)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			alias, _, err := s.SaveGeneratedURL(fmt.Sprintf("https://example.com/%d", i), storage.LinkOptions{})
+			require.NoError(t, err)
+			aliases <- alias
+		}(i)
+	}
+
+	wg.Wait()
+	close(aliases)
+
+	seen := make(map[string]bool, n)
+	for alias := range aliases {
+		require.False(t, seen[alias], "alias %q handed out twice", alias)
+		seen[alias] = true
+	}
+	require.Len(t, seen, n)
+}
+
+func TestStorage_SaveURLBatch(t *testing.T) {
+	s := newStorage(t)
+
+	alias := random.NewRandomString(12)
+
+	results, err := s.SaveURLBatch([]storage.NewLink{
+		{URL: "https://google.com", Alias: alias},
+		{URL: "https://go.dev"},
+		{URL: "https://google.de", Alias: alias},
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	require.NoError(t, results[0].Err)
+	require.NoError(t, results[1].Err)
+	require.NotEmpty(t, results[1].Alias)
+	require.ErrorIs(t, results[2].Err, storage.ErrURLExists)
+
+	got, err := s.GetURL(results[1].Alias)
+	require.NoError(t, err)
+	require.Equal(t, "https://go.dev", got)
+
+	got, err = s.GetURL(alias)
+	require.NoError(t, err)
+	require.Equal(t, "https://google.com", got)
+}
+
+func TestStorage_ImportLinks(t *testing.T) {
+	s := newStorage(t)
+
+	taken := random.NewRandomString(12)
+	fresh := random.NewRandomString(12)
+
+	_, err := s.SaveURL("https://google.com", taken, storage.LinkOptions{})
+	require.NoError(t, err)
+
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	links := []storage.Link{
+		{Alias: taken, URL: "https://google.de", CreatedAt: createdAt, LinkOptions: storage.LinkOptions{PasswordHash: "$2a$10$hash"}},
+		{Alias: fresh, URL: "https://go.dev", CreatedAt: createdAt, LinkOptions: storage.LinkOptions{OwnerID: 1 << 40}},
+	}
+
+	_, err = s.ImportLinks(links, storage.ConflictFail)
+	require.ErrorIs(t, err, storage.ErrURLExists)
+	_, err = s.GetLink(fresh)
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	stats, err := s.ImportLinks(links, storage.ConflictSkip)
+	require.NoError(t, err)
+	require.Equal(t, storage.ImportStats{Created: 1, Skipped: 1}, stats)
+
+	link, err := s.GetLink(fresh)
+	require.NoError(t, err)
+	require.True(t, createdAt.Equal(link.CreatedAt))
+	require.Zero(t, link.OwnerID, "unknown owners are dropped")
+
+	stats, err = s.ImportLinks(links, storage.ConflictOverwrite)
+	require.NoError(t, err)
+	require.Equal(t, storage.ImportStats{Overwritten: 2}, stats)
+
+	link, err = s.GetLink(taken)
+	require.NoError(t, err)
+	require.Equal(t, "https://google.de", link.URL)
+	require.Equal(t, "$2a$10$hash", link.PasswordHash)
+
+	counter, err := s.AliasCounter()
+	require.NoError(t, err)
+	require.NoError(t, s.RaiseAliasCounter(counter+5))
+	require.NoError(t, s.RaiseAliasCounter(counter))
+
+	raised, err := s.AliasCounter()
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, raised, counter+5)
+}
+
+func TestStorage_Clicks(t *testing.T) {
+	s := newStorage(t)
+
+	alias := random.NewRandomString(12)
+	missing := random.NewRandomString(12)
+
+	_, err := s.SaveURL("https://google.com", alias, storage.LinkOptions{})
+	require.NoError(t, err)
+
+	day := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	err = s.SaveClicks([]storage.Click{
+		{Alias: alias, ClickedAt: day.AddDate(0, 0, -10), IPHash: "a"},
+		{Alias: alias, ClickedAt: day, IPHash: "a"},
+		{Alias: alias, ClickedAt: day.Add(time.Hour), IPHash: "b"},
+		{Alias: alias, ClickedAt: day.AddDate(0, 0, 1), IPHash: "c"},
+		{Alias: missing, ClickedAt: day, IPHash: "d"},
+	})
+	require.NoError(t, err)
+
+	stats, err := s.ClickStats(alias, day.AddDate(0, 0, -1), storage.AnyOwner)
+	require.NoError(t, err)
+	require.Equal(t, 4, stats.Total)
+	require.Equal(t, 3, stats.UniqueVisitors)
+	require.Equal(t, []storage.DailyClicks{
+		{Date: "2024-05-01", Clicks: 2},
+		{Date: "2024-05-02", Clicks: 1},
+	}, stats.Daily)
+
+	count, err := s.CountClicks(alias)
+	require.NoError(t, err)
+	require.Equal(t, 4, count)
+
+	_, err = s.ClickStats(missing, day, storage.AnyOwner)
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+	_, err = s.CountClicks(missing)
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	require.NoError(t, s.DeleteURL(alias, storage.AnyOwner))
+	_, err = s.SaveURL("https://google.com", alias, storage.LinkOptions{})
+	require.NoError(t, err)
+
+	stats, err = s.ClickStats(alias, day, storage.AnyOwner)
+	require.NoError(t, err)
+	require.Zero(t, stats.Total)
+	require.Empty(t, stats.Daily)
+
+	count, err = s.CountClicks(alias)
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
+
+func TestStorage_LinkOptions(t *testing.T) {
+	s := newStorage(t)
+
+	alias := random.NewRandomString(12)
+	expired := random.NewRandomString(12)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	activeFrom := now.Add(time.Hour)
+	expiresAt := now.Add(2 * time.Hour)
+	expiredAt := now.Add(-time.Hour)
+
+	_, err := s.SaveURL("https://google.com", alias, storage.LinkOptions{
+		ActiveFrom:   &activeFrom,
+		ExpiresAt:    &expiresAt,
+		RedirectCode: http.StatusPermanentRedirect,
+		PasswordHash: "$2a$10$hash",
+		MaxClicks:    3,
+	})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://google.com", expired, storage.LinkOptions{ExpiresAt: &expiredAt})
+	require.NoError(t, err)
+
+	link, err := s.GetLink(alias)
+	require.NoError(t, err)
+	require.True(t, activeFrom.Equal(*link.ActiveFrom))
+	require.True(t, expiresAt.Equal(*link.ExpiresAt))
+	require.Equal(t, http.StatusPermanentRedirect, link.RedirectCode)
+	require.Equal(t, "$2a$10$hash", link.PasswordHash)
+	require.True(t, link.Protected())
+	require.EqualValues(t, 3, link.MaxClicks)
+	require.EqualValues(t, 3, link.ClicksLeft())
+	require.True(t, link.Pending(now))
+	require.False(t, link.Expired(now))
+
+	deleted, err := s.DeleteExpiredURLs(now)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, deleted, int64(1))
+
+	_, err = s.GetLink(expired)
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+	_, err = s.GetLink(alias)
+	require.NoError(t, err)
+}
+
+func TestStorage_APIKeys(t *testing.T) {
+	s := newStorage(t)
+
+	hash := random.NewRandomString(32)
+	createdAt := time.Now().UTC().Truncate(time.Second)
+
+	id, err := s.SaveAPIKey(storage.APIKey{
+		Name:      "billing",
+		Prefix:    "usk_1234",
+		Scopes:    []string{"create", "read"},
+		CreatedAt: createdAt,
+	}, hash)
+	require.NoError(t, err)
+
+	key, err := s.GetAPIKeyByHash(hash)
+	require.NoError(t, err)
+	require.Equal(t, id, key.ID)
+	require.Equal(t, "billing", key.Name)
+	require.Equal(t, []string{"create", "read"}, key.Scopes)
+	require.True(t, createdAt.Equal(key.CreatedAt))
+	require.Nil(t, key.LastUsedAt)
+	require.Nil(t, key.RevokedAt)
+
+	_, err = s.GetAPIKeyByHash("unknown")
+	require.ErrorIs(t, err, storage.ErrAPIKeyNotFound)
+
+	usedAt := createdAt.Add(time.Minute)
+	require.NoError(t, s.TouchAPIKey(id, usedAt))
+
+	revokedAt := createdAt.Add(time.Hour)
+	require.NoError(t, s.RevokeAPIKey(id, revokedAt))
+	require.NoError(t, s.RevokeAPIKey(id, revokedAt.Add(time.Hour)))
+	require.ErrorIs(t, s.RevokeAPIKey(id+1000, revokedAt), storage.ErrAPIKeyNotFound)
+
+	key, err = s.GetAPIKeyByHash(hash)
+	require.NoError(t, err)
+	require.True(t, usedAt.Equal(*key.LastUsedAt))
+	require.True(t, revokedAt.Equal(*key.RevokedAt), "revoking again keeps the first time")
+
+	keys, err := s.ListAPIKeys()
+	require.NoError(t, err)
+	require.NotEmpty(t, keys)
+}
+
+func TestStorage_Ownership(t *testing.T) {
+	s := newStorage(t)
+
+	aliceName := random.NewRandomString(12)
+	bobName := random.NewRandomString(12)
+	alias := random.NewRandomString(12)
+	keyHash := random.NewRandomString(32)
+
+	alice, err := s.SaveUser(storage.User{Name: aliceName, PasswordHash: "hash", Role: "user", CreatedAt: time.Now()})
+	require.NoError(t, err)
+	bob, err := s.SaveUser(storage.User{Name: bobName, PasswordHash: "hash", Role: "user", CreatedAt: time.Now()})
+	require.NoError(t, err)
+
+	_, err = s.SaveUser(storage.User{Name: aliceName, PasswordHash: "hash", Role: "user", CreatedAt: time.Now()})
+	require.ErrorIs(t, err, storage.ErrUserExists)
+
+	user, err := s.GetUserByName(aliceName)
+	require.NoError(t, err)
+	require.Equal(t, alice, user.ID)
+	require.Equal(t, "hash", user.PasswordHash)
+
+	_, err = s.GetUserByName(random.NewRandomString(12))
+	require.ErrorIs(t, err, storage.ErrUserNotFound)
+
+	_, err = s.SaveURL("https://google.com", alias, storage.LinkOptions{OwnerID: alice})
+	require.NoError(t, err)
+
+	link, err := s.GetLink(alias)
+	require.NoError(t, err)
+	require.Equal(t, alice, link.OwnerID)
+
+	links, err := s.ListURLs(10, 0, alice)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	links, err = s.ListURLs(10, 0, bob)
+	require.NoError(t, err)
+	require.Empty(t, links)
+
+	count, err := s.CountURLs(bob)
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	_, err = s.ClickStats(alias, time.Now(), bob)
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+	require.ErrorIs(t, s.UpdateURL(alias, "https://google.de", bob), storage.ErrURLNotFound)
+	require.ErrorIs(t, s.DeleteURL(alias, bob), storage.ErrURLNotFound)
+
+	links, err = s.ListURLs(10, 0, storage.NoOwner)
+	require.NoError(t, err)
+	require.Empty(t, links)
+	require.ErrorIs(t, s.UpdateURL(alias, "https://google.de", storage.NoOwner), storage.ErrURLNotFound)
+	require.ErrorIs(t, s.DeleteURL(alias, storage.NoOwner), storage.ErrURLNotFound)
+
+	require.NoError(t, s.UpdateURL(alias, "https://google.de", alice))
+	require.NoError(t, s.DeleteURL(alias, storage.AnyOwner))
+
+	keyID, err := s.SaveAPIKey(storage.APIKey{Name: "ci", Prefix: "usk_1", Scopes: []string{"read"}, CreatedAt: time.Now(), UserID: bob}, keyHash)
+	require.NoError(t, err)
+	key, err := s.GetAPIKeyByHash(keyHash)
+	require.NoError(t, err)
+	require.Equal(t, keyID, key.ID)
+	require.Equal(t, bob, key.UserID)
+
+	users, err := s.ListUsers()
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(users), 2)
+}
+
+func TestStorage_UseClick(t *testing.T) {
+	s := newStorage(t)
+
+	limited, unlimited := random.NewRandomString(12), random.NewRandomString(12)
+
+	_, err := s.SaveURL("https://google.com", limited, storage.LinkOptions{MaxClicks: 5})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://google.com", unlimited, storage.LinkOptions{})
+	require.NoError(t, err)
+
+	left, err := s.UseClick(limited)
+	require.NoError(t, err)
+	require.EqualValues(t, 4, left)
+
+	// More redirects than clicks left race for them.
+	const n = 20
+
+	var wg sync.WaitGroup
+	var used, exhausted atomic.Int64
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := s.UseClick(limited)
+			if errors.Is(err, storage.ErrClicksExhausted) {
+				exhausted.Add(1)
+				return
+			}
+			require.NoError(t, err)
+			used.Add(1)
+		}()
+	}
+
+	wg.Wait()
+	require.EqualValues(t, 4, used.Load())
+	require.EqualValues(t, n-4, exhausted.Load())
+
+	link, err := s.GetLink(limited)
+	require.NoError(t, err)
+	require.EqualValues(t, 5, link.UsedClicks)
+	require.True(t, link.UsedUp())
+	require.Zero(t, link.ClicksLeft())
+
+	left, err = s.UseClick(unlimited)
+	require.NoError(t, err)
+	require.Zero(t, left)
+
+	link, err = s.GetLink(unlimited)
+	require.NoError(t, err)
+	require.Zero(t, link.UsedClicks)
+	require.False(t, link.UsedUp())
+
+	_, err = s.UseClick(random.NewRandomString(12))
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestStorage_UpdateLink(t *testing.T) {
+	s := newStorage(t)
+
+	owner, err := s.SaveUser(storage.User{Name: random.NewRandomString(12), PasswordHash: "hash", Role: "user", CreatedAt: time.Now()})
+	require.NoError(t, err)
+
+	alias, plain := random.NewRandomString(12), random.NewRandomString(12)
+	targets := &storage.Targets{
+		IOS:     "https://apps.apple.com/app/id1",
+		Android: "https://play.google.com/store/apps/details?id=app",
+	}
+
+	_, err = s.SaveURL("https://google.com", alias, storage.LinkOptions{OwnerID: owner, Targets: targets})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://google.com", plain, storage.LinkOptions{})
+	require.NoError(t, err)
+
+	link, err := s.GetLink(alias)
+	require.NoError(t, err)
+	require.Equal(t, targets, link.Targets)
+
+	link, err = s.GetLink(plain)
+	require.NoError(t, err)
+	require.Nil(t, link.Targets)
+
+	desktop := &storage.Targets{Desktop: "https://example.com/download"}
+	updated, err := s.UpdateLink(alias, storage.LinkUpdate{Targets: desktop}, owner)
+	require.NoError(t, err)
+	require.Equal(t, desktop, updated.Targets)
+	require.Equal(t, "https://google.com", updated.URL)
+
+	link, err = s.GetLink(alias)
+	require.NoError(t, err)
+	require.Equal(t, updated, link)
+
+	// Only links with variants can be sticky, a rejected update changes nothing.
+	sticky := true
+	_, err = s.UpdateLink(alias, storage.LinkUpdate{URL: "https://google.de", StickyVariants: &sticky}, owner)
+	require.ErrorIs(t, err, storage.ErrNoVariants)
+
+	link, err = s.GetLink(alias)
+	require.NoError(t, err)
+	require.Equal(t, "https://google.com", link.URL)
+	require.False(t, link.StickyVariants)
+
+	// Fields left out of the update stay as they are.
+	updated, err = s.UpdateLink(alias, storage.LinkUpdate{URL: "https://google.de"}, owner)
+	require.NoError(t, err)
+	require.Equal(t, "https://google.de", updated.URL)
+	require.Equal(t, desktop, updated.Targets)
+
+	_, err = s.UpdateLink(alias, storage.LinkUpdate{URL: "https://evil.com"}, owner+1)
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+	_, err = s.UpdateLink(random.NewRandomString(12), storage.LinkUpdate{URL: "https://evil.com"}, storage.AnyOwner)
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	_, err = s.UpdateLink(alias, storage.LinkUpdate{Targets: &storage.Targets{}}, storage.AnyOwner)
+	require.NoError(t, err)
+
+	link, err = s.GetLink(alias)
+	require.NoError(t, err)
+	require.Nil(t, link.Targets)
+}
+
+func TestStorage_Variants(t *testing.T) {
+	s := newStorage(t)
+
+	owner, err := s.SaveUser(storage.User{Name: random.NewRandomString(12), PasswordHash: "hash", Role: "user", CreatedAt: time.Now()})
+	require.NoError(t, err)
+
+	alias, copied := random.NewRandomString(12), random.NewRandomString(12)
+	variants := []storage.Variant{
+		{URL: "https://example.com/a", Weight: 3},
+		{URL: "https://example.com/b", Weight: 1},
+	}
+
+	_, err = s.SaveURL("https://example.com/a", alias, storage.LinkOptions{OwnerID: owner, Variants: variants, StickyVariants: true})
+	require.NoError(t, err)
+
+	link, err := s.GetLink(alias)
+	require.NoError(t, err)
+	require.Equal(t, variants, link.Variants)
+	require.True(t, link.StickyVariants)
+
+	now := time.Now()
+	require.NoError(t, s.SaveClicks([]storage.Click{
+		{Alias: alias, ClickedAt: now, Variant: 2},
+		{Alias: alias, ClickedAt: now, Variant: 2},
+		{Alias: alias, ClickedAt: now, Variant: 1},
+		{Alias: alias, ClickedAt: now},
+	}))
+
+	stats, err := s.ClickStats(alias, now.Add(-time.Hour), owner)
+	require.NoError(t, err)
+	require.Equal(t, 4, stats.Total)
+	require.Equal(t, []storage.Variant{
+		{URL: "https://example.com/a", Weight: 3, Clicks: 1},
+		{URL: "https://example.com/b", Weight: 1, Clicks: 2},
+	}, stats.Variants)
+
+	link, err = s.GetLink(alias)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, link.Variants[1].Clicks)
+
+	// Imports keep the counts.
+	link.Alias = copied
+	_, err = s.ImportLinks([]storage.Link{link}, storage.ConflictFail)
+	require.NoError(t, err)
+	imported, err := s.GetLink(copied)
+	require.NoError(t, err)
+	require.Equal(t, link.Variants, imported.Variants)
+
+	_, err = s.UpdateLink(alias, storage.LinkUpdate{Variants: []storage.Variant{}}, owner+1)
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	replaced := []storage.Variant{{URL: "https://example.com/c", Weight: 1, Clicks: 7}}
+	sticky := false
+	_, err = s.UpdateLink(alias, storage.LinkUpdate{Variants: replaced, StickyVariants: &sticky}, owner)
+	require.NoError(t, err)
+
+	link, err = s.GetLink(alias)
+	require.NoError(t, err)
+	require.Equal(t, []storage.Variant{{URL: "https://example.com/c", Weight: 1}}, link.Variants)
+	require.False(t, link.StickyVariants)
+
+	// Variants cannot be removed from a link that is made sticky.
+	sticky = true
+	_, err = s.UpdateLink(alias, storage.LinkUpdate{Variants: []storage.Variant{}, StickyVariants: &sticky}, owner)
+	require.ErrorIs(t, err, storage.ErrNoVariants)
+
+	_, err = s.UpdateLink(alias, storage.LinkUpdate{Variants: []storage.Variant{}}, storage.AnyOwner)
+	require.NoError(t, err)
+
+	link, err = s.GetLink(alias)
+	require.NoError(t, err)
+	require.Nil(t, link.Variants)
+
+	stats, err = s.ClickStats(alias, now.Add(-time.Hour), owner)
+	require.NoError(t, err)
+	require.Nil(t, stats.Variants)
+}
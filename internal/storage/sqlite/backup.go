@@ -0,0 +1,121 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+const (
+	// backupStepPages is the number of pages copied while the database is
+	// locked, writers wait for at most one step.
+	backupStepPages = 256
+	// backupStepPause lets writers in between two steps.
+	backupStepPause = 5 * time.Millisecond
+)
+
+var (
+	// ErrCorrupt is returned by Verify for a database that fails the integrity check.
+	ErrCorrupt = errors.New("database is corrupt")
+	// ErrNoSchema is returned by Verify for a database without the url table.
+	ErrNoSchema = errors.New("database has no url table")
+)
+
+// Backup writes a consistent copy of the database to path using the SQLite
+// online backup API, so the database stays usable while it runs. An
+// existing file at path is overwritten.
+func (s *Storage) Backup(ctx context.Context, path string) error {
+	const op = "storage.sqlite.Backup"
+
+	dst, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer dst.Close()
+
+	dstConn, err := dst.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: open snapshot: %w", op, err)
+	}
+	defer dstConn.Close()
+
+	srcConn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: open database: %w", op, err)
+	}
+	defer srcConn.Close()
+
+	err = dstConn.Raw(func(dstDriverConn any) error {
+		return srcConn.Raw(func(srcDriverConn any) error {
+			return copyDatabase(ctx, dstDriverConn.(*sqlite3.SQLiteConn), srcDriverConn.(*sqlite3.SQLiteConn))
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// copyDatabase copies src to dst a few pages at a time. A write to src in
+// between two steps makes SQLite restart the copy.
+func copyDatabase(ctx context.Context, dst, src *sqlite3.SQLiteConn) error {
+	backup, err := dst.Backup("main", src, "main")
+	if err != nil {
+		return err
+	}
+
+	for {
+		done, err := backup.Step(backupStepPages)
+		if err != nil {
+			_ = backup.Finish()
+			return err
+		}
+		if done {
+			return backup.Finish()
+		}
+
+		select {
+		case <-ctx.Done():
+			_ = backup.Finish()
+			return ctx.Err()
+		case <-time.After(backupStepPause):
+		}
+	}
+}
+
+// Verify checks that the database is intact and was not written by a newer
+// version of the schema. It is meant for snapshots about to be restored.
+func (s *Storage) Verify() error {
+	const op = "storage.sqlite.Verify"
+
+	var result string
+	if err := s.db.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		return fmt.Errorf("%s: integrity check: %w", op, err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("%s: %w: %s", op, ErrCorrupt, result)
+	}
+
+	var tables int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'url'").Scan(&tables); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if tables == 0 {
+		return fmt.Errorf("%s: %w", op, ErrNoSchema)
+	}
+
+	m, err := s.Migrator()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := m.CheckKnown(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package sqlite_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/sqlite"
+)
+
+func TestStorage_Backup(t *testing.T) {
+	s := newStorage(t)
+
+	for i := 0; i < 100; i++ {
+		_, _, err := s.SaveGeneratedURL(fmt.Sprintf("https://example.com/%d", i), storage.LinkOptions{})
+		require.NoError(t, err)
+	}
+
+	snapshotPath := filepath.Join(t.TempDir(), "snapshot.db")
+	require.NoError(t, s.Backup(context.Background(), snapshotPath))
+
+	// Writes after the backup do not show up in the snapshot.
+	_, err := s.SaveURL("https://google.com", "later", storage.LinkOptions{})
+	require.NoError(t, err)
+
+	snapshot, err := sqlite.New(snapshotPath, 0, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = snapshot.Close() })
+
+	require.NoError(t, snapshot.Verify())
+
+	count, err := snapshot.CountURLs(storage.AnyOwner)
+	require.NoError(t, err)
+	require.Equal(t, 100, count)
+
+	counter, err := snapshot.AliasCounter()
+	require.NoError(t, err)
+	require.EqualValues(t, 100, counter)
+
+	_, err = snapshot.GetURL("later")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestStorage_Backup_Canceled(t *testing.T) {
+	s := newStorage(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.Error(t, s.Backup(ctx, filepath.Join(t.TempDir(), "snapshot.db")))
+}
+
+func TestStorage_Verify(t *testing.T) {
+	dir := t.TempDir()
+
+	empty, err := sqlite.New(filepath.Join(dir, "empty.db"), 0, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = empty.Close() })
+	require.ErrorIs(t, empty.Verify(), sqlite.ErrNoSchema)
+
+	garbagePath := filepath.Join(dir, "garbage.db")
+	require.NoError(t, os.WriteFile(garbagePath, []byte("definitely not an sqlite database, but long enough to look like one"), 0o600))
+
+	garbage, err := sqlite.New(garbagePath, 0, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = garbage.Close() })
+	require.Error(t, garbage.Verify())
+}
@@ -0,0 +1,2 @@
+DROP TABLE alias_value;
+DROP TABLE url;
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS url(
+	id INTEGER PRIMARY KEY,
+	alias TEXT NOT NULL UNIQUE,
+	url TEXT NOT NULL);
+CREATE INDEX IF NOT EXISTS idx_alias ON url(alias);
+
+CREATE TABLE IF NOT EXISTS alias_value(
+	id INTEGER PRIMARY KEY,
+	value INT NOT NULL,
+	name TEXT NOT NULL UNIQUE);
+CREATE INDEX IF NOT EXISTS idx_name ON alias_value(name);
+
+-- Databases created by the old per-length generator keep its state in the
+-- AliasLength and Pointer* rows; turn it into the single alias counter.
+INSERT INTO alias_value(value, name)
+SELECT COALESCE((
+	SELECT CASE l.value
+		WHEN 1 THEN p1.value
+		WHEN 2 THEN 62 + p1.value * 62 + p2.value
+		WHEN 3 THEN 3906 + p1.value * 3844 + p2.value * 62 + p3.value
+		WHEN 4 THEN 242234 + p1.value * 238328 + p2.value * 3844 + p3.value * 62 + p4.value
+		ELSE 15018570
+	END
+	FROM alias_value l, alias_value p1, alias_value p2, alias_value p3, alias_value p4
+	WHERE l.name = 'AliasLength' AND p1.name = 'PointerOne' AND p2.name = 'PointerTwo'
+		AND p3.name = 'PointerThree' AND p4.name = 'PointerFour'
+), 0), 'Counter'
+WHERE NOT EXISTS (SELECT 1 FROM alias_value WHERE name = 'Counter');
+
+DELETE FROM alias_value WHERE name <> 'Counter';
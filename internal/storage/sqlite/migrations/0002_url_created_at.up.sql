@@ -0,0 +1,4 @@
+-- SQLite cannot add a column defaulting to CURRENT_TIMESTAMP, so links
+-- created before this migration get the time it was applied.
+ALTER TABLE url ADD COLUMN created_at TIMESTAMP;
+UPDATE url SET created_at = CURRENT_TIMESTAMP WHERE created_at IS NULL;
@@ -0,0 +1,3 @@
+DROP INDEX idx_url_expires_at;
+ALTER TABLE url DROP COLUMN expires_at;
+ALTER TABLE url DROP COLUMN active_from;
@@ -0,0 +1,3 @@
+ALTER TABLE url ADD COLUMN active_from TIMESTAMP;
+ALTER TABLE url ADD COLUMN expires_at TIMESTAMP;
+CREATE INDEX idx_url_expires_at ON url(expires_at);
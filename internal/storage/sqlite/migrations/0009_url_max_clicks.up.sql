@@ -0,0 +1,2 @@
+ALTER TABLE url ADD COLUMN max_clicks INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE url ADD COLUMN used_clicks INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+ALTER TABLE url ADD COLUMN target_ios TEXT NOT NULL DEFAULT '';
+ALTER TABLE url ADD COLUMN target_android TEXT NOT NULL DEFAULT '';
+ALTER TABLE url ADD COLUMN target_desktop TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE url DROP COLUMN sticky_variants;
+DROP TABLE url_variants;
@@ -0,0 +1,8 @@
+CREATE TABLE url_variants(
+	url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	url TEXT NOT NULL,
+	weight INTEGER NOT NULL,
+	clicks INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (url_id, position));
+ALTER TABLE url ADD COLUMN sticky_variants INTEGER NOT NULL DEFAULT 0;
@@ -2,125 +2,346 @@ package sqlite
 
 import (
 	"database/sql"
+	"embed"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
+	"strings"
+	"time"
 
 	"github.com/mattn/go-sqlite3"
 
+	customalias "url-shortener/internal/lib/custom_alias"
+	generatingalias "url-shortener/internal/lib/generating_alias"
 	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/migrate"
 )
 
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+var _ storage.Store = (*Storage)(nil)
+
 type Storage struct {
-	db *sql.DB
+	db             *sql.DB
+	aliasMaxLength int
+	reserved       customalias.Reserved
 }
 
-func New(storagePath string) (*Storage, error) {
+// New opens the database at storagePath. The schema is managed by Migrator.
+// aliasMaxLength limits the length of generated aliases, zero means no limit.
+// Generated aliases skip customalias.DefaultReserved and reserved.
+func New(storagePath string, aliasMaxLength int, reserved []string) (*Storage, error) {
 	const op = "storage.sqlite.New"
 
-	db, err := sql.Open("sqlite3", storagePath)
+	// _txlock=immediate makes every transaction take the write lock on BEGIN,
+	// so concurrent alias allocations are serialized instead of failing with SQLITE_BUSY.
+	// _foreign_keys=on lets deleting a link cascade to its clicks.
+	db, err := sql.Open("sqlite3", storagePath+"?_txlock=immediate&_foreign_keys=on")
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	stmt, err := db.Prepare(`
-	CREATE TABLE IF NOT EXISTS url(
-		id INTEGER PRIMARY KEY,
-		alias TEXT NOT NULL UNIQUE,
-		url TEXT NOT NULL);
-	CREATE INDEX IF NOT EXISTS idx_alias ON url(alias);
-	`)
+	return &Storage{db: db, aliasMaxLength: aliasMaxLength, reserved: customalias.NewReserved(reserved)}, nil
+}
+
+// Migrator returns the schema migrator for this database.
+func (s *Storage) Migrator() (*migrate.Migrator, error) {
+	const op = "storage.sqlite.Migrator"
+
+	fsys, err := fs.Sub(migrations, "migrations")
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	_, err = stmt.Exec()
+	m, err := migrate.New(s.db, fsys)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &Storage{db: db}, nil
+	return m, nil
 }
 
-func SaveGeneratingAlias(storagePath string) error {
-	const op = "storage.sqlite.SaveGeneratingAlias"
+func (s *Storage) Ping() error {
+	return s.db.Ping()
+}
 
-	db, err := sql.Open("sqlite3", storagePath)
+func (s *Storage) Close() error {
+	return s.db.Close()
+}
+
+func (s *Storage) SaveURL(urlToSave string, alias string, opts storage.LinkOptions) (int64, error) {
+	const op = "storage.sqlite.SaveURL"
+
+	tx, err := s.db.Begin()
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return 0, fmt.Errorf("%s: begin transaction: %w", op, err)
 	}
+	defer func() { _ = tx.Rollback() }()
 
-	stmt, err := db.Prepare(`
-	CREATE TABLE IF NOT EXISTS alias_value(
-		id INTEGER PRIMARY KEY,
-		value INT NOT NULL,
-		name TEXT NOT NULL UNIQUE);
-	CREATE INDEX IF NOT EXISTS idx_name ON alias_value(name);
-	`)
+	id, err := insertURL(tx, urlToSave, alias, opts, time.Now().UTC())
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
-	_, err = stmt.Exec()
-	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: commit transaction: %w", op, err)
 	}
 
-	stmt, err = db.Prepare(`
-	INSERT INTO alias_value(value, name) 
-		VALUES(1, 'AliasLength'),
-			  (0, 'PointerOne'),
-			  (0, 'PointerTwo'),
-			  (0, 'PointerThree'),
-			  (0, 'PointerFour');
-	`)
+	return id, nil
+}
+
+// SaveGeneratedURL reserves the next free alias from the alias counter and
+// saves urlToSave under it. Both happen in one transaction, so concurrent
+// callers never get the same alias and a failed insert does not advance the
+// counter. Aliases already taken by custom links are skipped.
+func (s *Storage) SaveGeneratedURL(urlToSave string, opts storage.LinkOptions) (string, int64, error) {
+	const op = "storage.sqlite.SaveGeneratedURL"
 
+	tx, err := s.db.Begin()
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return "", 0, fmt.Errorf("%s: begin transaction: %w", op, err)
 	}
+	defer func() { _ = tx.Rollback() }()
 
-	_, err = stmt.Exec()
+	alias, id, err := s.saveGenerated(tx, urlToSave, opts, time.Now().UTC())
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return "", 0, fmt.Errorf("%s: %w", op, err)
 	}
-	return nil
+
+	if err := tx.Commit(); err != nil {
+		return "", 0, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return alias, id, nil
 }
 
-func (s *Storage) SaveURL(urlToSave string, alias string) (int64, error) {
-	const op = "storage.sqlite.SaveURL"
+// SaveURLBatch saves links in one transaction. Taken custom aliases and an
+// exhausted alias space are reported per link, any other error rolls back
+// the whole batch.
+func (s *Storage) SaveURLBatch(links []storage.NewLink) ([]storage.BatchResult, error) {
+	const op = "storage.sqlite.SaveURLBatch"
 
-	stmt, err := s.db.Prepare("INSERT INTO url(url, alias) VALUES(?, ?)")
+	tx, err := s.db.Begin()
 	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: begin transaction: %w", op, err)
 	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().UTC()
+	results := make([]storage.BatchResult, len(links))
+	for i, link := range links {
+		res := storage.BatchResult{Alias: link.Alias}
+		if link.Alias == "" {
+			res.Alias, res.ID, err = s.saveGenerated(tx, link.URL, link.LinkOptions, now)
+		} else {
+			res.ID, err = insertURL(tx, link.URL, link.Alias, link.LinkOptions, now)
+		}
+		if errors.Is(err, storage.ErrURLExists) || errors.Is(err, storage.ErrAliasSpaceExhausted) {
+			res.Err, err = err, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
 
-	res, err := stmt.Exec(urlToSave, alias)
+		results[i] = res
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return results, nil
+}
+
+// ImportLinks saves links in one transaction, see storage.Store.
+func (s *Storage) ImportLinks(links []storage.Link, onConflict storage.ConflictPolicy) (storage.ImportStats, error) {
+	const op = "storage.sqlite.ImportLinks"
+
+	var stats storage.ImportStats
+
+	tx, err := s.db.Begin()
 	if err != nil {
-		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
-			return 0, fmt.Errorf("%s: %w", op, storage.ErrURLExists)
+		return stats, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().UTC()
+	for _, link := range links {
+		createdAt := link.CreatedAt.UTC()
+		if link.CreatedAt.IsZero() {
+			createdAt = now
 		}
 
-		return 0, fmt.Errorf("%s: %w", op, err)
+		// The owner subquery yields NULL for users missing from this database.
+		targets := targetValues(link.Targets)
+		res, err := tx.Exec(`
+		INSERT INTO url(url, alias, created_at, active_from, expires_at, redirect_code, owner_id, password_hash, max_clicks, used_clicks,
+			target_ios, target_android, target_desktop, sticky_variants)
+		VALUES(?, ?, ?, ?, ?, ?, (SELECT id FROM users WHERE id = ?), ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(alias) DO NOTHING`,
+			link.URL, link.Alias, createdAt, utc(link.ActiveFrom), utc(link.ExpiresAt), link.RedirectCode, nullID(link.OwnerID), link.PasswordHash,
+			link.MaxClicks, link.UsedClicks, targets.IOS, targets.Android, targets.Desktop, link.StickyVariants,
+		)
+		if err != nil {
+			return stats, fmt.Errorf("%s: insert url: %w", op, err)
+		}
+
+		if n, err := res.RowsAffected(); err != nil {
+			return stats, fmt.Errorf("%s: %w", op, err)
+		} else if n == 1 {
+			id, err := res.LastInsertId()
+			if err != nil {
+				return stats, fmt.Errorf("%s: failed to get last insert id: %w", op, err)
+			}
+			if err := saveVariants(tx, id, link.Variants); err != nil {
+				return stats, fmt.Errorf("%s: %w", op, err)
+			}
+			stats.Created++
+			continue
+		}
+
+		switch onConflict {
+		case storage.ConflictSkip:
+			stats.Skipped++
+		case storage.ConflictOverwrite:
+			var id int64
+			err := tx.QueryRow(`
+			UPDATE url SET url = ?, created_at = ?, active_from = ?, expires_at = ?, redirect_code = ?,
+				owner_id = (SELECT id FROM users WHERE id = ?), password_hash = ?, max_clicks = ?, used_clicks = ?,
+				target_ios = ?, target_android = ?, target_desktop = ?, sticky_variants = ?
+			WHERE alias = ?
+			RETURNING id`,
+				link.URL, createdAt, utc(link.ActiveFrom), utc(link.ExpiresAt), link.RedirectCode, nullID(link.OwnerID), link.PasswordHash,
+				link.MaxClicks, link.UsedClicks, targets.IOS, targets.Android, targets.Desktop, link.StickyVariants, link.Alias,
+			).Scan(&id)
+			if err != nil {
+				return stats, fmt.Errorf("%s: update url: %w", op, err)
+			}
+			if err := saveVariants(tx, id, link.Variants); err != nil {
+				return stats, fmt.Errorf("%s: %w", op, err)
+			}
+			stats.Overwritten++
+		default:
+			return storage.ImportStats{}, fmt.Errorf("%s: alias %q: %w", op, link.Alias, storage.ErrURLExists)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return storage.ImportStats{}, fmt.Errorf("%s: commit transaction: %w", op, err)
 	}
 
-	id, err := res.LastInsertId()
+	return stats, nil
+}
+
+func (s *Storage) RaiseAliasCounter(counter int64) error {
+	const op = "storage.sqlite.RaiseAliasCounter"
+
+	_, err := s.db.Exec("UPDATE alias_value SET value = ? WHERE name = 'Counter' AND value < ?", counter, counter)
 	if err != nil {
-		return 0, fmt.Errorf("%s: failed to get last insert id: %w", op, err)
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	return id, nil
+	return nil
 }
 
-func (s *Storage) GetURL(alias string) (string, error) {
-	const op = "storage.sqlite.GetURL"
+// saveGenerated advances the alias counter until an alias that is neither
+// reserved nor taken by a custom link is found and saves urlToSave under it.
+// The counter is never advanced past the capacity of the alias space.
+func (s *Storage) saveGenerated(tx *sql.Tx, urlToSave string, opts storage.LinkOptions, now time.Time) (string, int64, error) {
+	capacity := generatingalias.Capacity(s.aliasMaxLength)
+
+	for {
+		var counter int64
+		err := tx.QueryRow("UPDATE alias_value SET value = value + 1 WHERE name = 'Counter' AND value < ? RETURNING value - 1", capacity).
+			Scan(&counter)
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", 0, storage.ErrAliasSpaceExhausted
+		}
+		if err != nil {
+			return "", 0, fmt.Errorf("advance alias counter: %w", err)
+		}
 
-	stmt, err := s.db.Prepare("SELECT url FROM url WHERE alias = ?")
+		alias := generatingalias.NewGeneratedAlias(counter)
+		if s.reserved.Contains(alias) {
+			continue
+		}
+
+		id, err := insertURL(tx, urlToSave, alias, opts, now)
+		if errors.Is(err, storage.ErrURLExists) {
+			continue
+		}
+		if err != nil {
+			return "", 0, err
+		}
+
+		return alias, id, nil
+	}
+}
+
+// saveVariants replaces the variants of the link with urlID.
+func saveVariants(tx *sql.Tx, urlID int64, variants []storage.Variant) error {
+	if _, err := tx.Exec("DELETE FROM url_variants WHERE url_id = ?", urlID); err != nil {
+		return fmt.Errorf("delete variants: %w", err)
+	}
+
+	for i, variant := range variants {
+		_, err := tx.Exec("INSERT INTO url_variants(url_id, position, url, weight, clicks) VALUES(?, ?, ?, ?, ?)",
+			urlID, i+1, variant.URL, variant.Weight, variant.Clicks)
+		if err != nil {
+			return fmt.Errorf("insert variant: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// insertURL saves urlToSave under alias, a taken alias is reported as
+// storage.ErrURLExists without failing the transaction.
+func insertURL(tx *sql.Tx, urlToSave, alias string, opts storage.LinkOptions, now time.Time) (int64, error) {
+	var id int64
+	targets := targetValues(opts.Targets)
+	err := tx.QueryRow(`
+	INSERT INTO url(url, alias, created_at, active_from, expires_at, redirect_code, owner_id, password_hash, max_clicks,
+		target_ios, target_android, target_desktop, sticky_variants)
+	VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(alias) DO NOTHING
+	RETURNING id`,
+		urlToSave, alias, now, utc(opts.ActiveFrom), utc(opts.ExpiresAt), opts.RedirectCode, nullID(opts.OwnerID), opts.PasswordHash, opts.MaxClicks,
+		targets.IOS, targets.Android, targets.Desktop, opts.StickyVariants,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, storage.ErrURLExists
+	}
 	if err != nil {
-		return "", fmt.Errorf("%s: prepare statement: %w", op, err)
+		return 0, fmt.Errorf("insert url: %w", err)
+	}
+
+	if err := saveVariants(tx, id, opts.Variants); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (s *Storage) AliasCounter() (int64, error) {
+	const op = "storage.sqlite.AliasCounter"
+
+	var counter int64
+	if err := s.db.QueryRow("SELECT value FROM alias_value WHERE name = 'Counter'").Scan(&counter); err != nil {
+		return 0, fmt.Errorf("%s: select statement: %w", op, err)
 	}
 
+	return counter, nil
+}
+
+func (s *Storage) GetURL(alias string) (string, error) {
+	const op = "storage.sqlite.GetURL"
+
 	var resURL string
 
-	err = stmt.QueryRow(alias).Scan(&resURL)
+	err := s.db.QueryRow("SELECT url FROM url WHERE alias = ?", alias).Scan(&resURL)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return "", storage.ErrURLNotFound
@@ -132,62 +353,554 @@ func (s *Storage) GetURL(alias string) (string, error) {
 	return resURL, nil
 }
 
-func (s *Storage) UpdateURL(id int, newURL string) (string, error) {
-	const op = "storage.sqlite.UpdateURL"
+func (s *Storage) GetLink(alias string) (storage.Link, error) {
+	const op = "storage.sqlite.GetLink"
 
-	// Getting current URL
-	var resURL string
-	err := s.db.QueryRow("SELECT url FROM url WHERE id = ?", id).Scan(&resURL)
+	link, err := scanLink(s.db.QueryRow("SELECT "+linkColumns+" FROM url WHERE alias = ?", alias))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.Link{}, storage.ErrURLNotFound
+		}
+
+		return storage.Link{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return link, nil
+}
+
+func (s *Storage) UseClick(alias string) (int64, error) {
+	const op = "storage.sqlite.UseClick"
+
+	// The check and the increment are one statement, so concurrent redirects
+	// cannot both take the last click.
+	var left int64
+	err := s.db.QueryRow(`
+	UPDATE url SET used_clicks = used_clicks + 1
+	WHERE alias = ? AND max_clicks > 0 AND used_clicks < max_clicks
+	RETURNING max_clicks - used_clicks`, alias).Scan(&left)
+	if err == nil {
+		return left, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%s: update statement: %w", op, err)
+	}
+
+	// Nothing was counted, the link is unknown, used up or not limited.
+	var maxClicks int64
+	err = s.db.QueryRow("SELECT max_clicks FROM url WHERE alias = ?", alias).Scan(&maxClicks)
 	if errors.Is(err, sql.ErrNoRows) {
-		return "", storage.ErrURLNotFound
-	} else if err != nil {
-		return "", fmt.Errorf("%s: select statement: %w", op, err)
+		return 0, storage.ErrURLNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: select statement: %w", op, err)
 	}
+	if maxClicks > 0 {
+		return 0, storage.ErrClicksExhausted
+	}
+
+	return 0, nil
+}
 
-	// Checking, is URL changed
-	if resURL == newURL {
-		return "Same url, nothing changed", nil
+func (s *Storage) ListURLs(limit, offset int, ownerID int64) ([]storage.Link, error) {
+	const op = "storage.sqlite.ListURLs"
+
+	rows, err := s.db.Query("SELECT "+linkColumns+" FROM url WHERE "+ownedBy+" ORDER BY id LIMIT ? OFFSET ?",
+		ownerID, ownerID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: select statement: %w", op, err)
 	}
+	defer rows.Close()
 
-	// Updating URL in storage
-	res, err := s.db.Exec("UPDATE url SET url = ? WHERE id = ?", newURL, id)
+	links := make([]storage.Link, 0, limit)
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: iterate rows: %w", op, err)
+	}
+
+	return links, nil
+}
+
+func (s *Storage) CountURLs(ownerID int64) (int, error) {
+	const op = "storage.sqlite.CountURLs"
+
+	var count int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM url WHERE "+ownedBy, ownerID, ownerID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("%s: select statement: %w", op, err)
+	}
+
+	return count, nil
+}
+
+func (s *Storage) DeleteExpiredURLs(before time.Time) (int64, error) {
+	const op = "storage.sqlite.DeleteExpiredURLs"
+
+	res, err := s.db.Exec("DELETE FROM url WHERE expires_at < ?", before.UTC())
 	if err != nil {
-		return "", fmt.Errorf("%s: update statement: %w", op, err)
+		return 0, fmt.Errorf("%s: delete statement: %w", op, err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+
+	return deleted, nil
+}
+
+func (s *Storage) UpdateURL(alias string, newURL string, ownerID int64) error {
+	const op = "storage.sqlite.UpdateURL"
+
+	res, err := s.db.Exec("UPDATE url SET url = ? WHERE alias = ? AND "+ownedBy, newURL, alias, ownerID, ownerID)
+	if err != nil {
+		return fmt.Errorf("%s: update statement: %w", op, err)
 	}
 
 	rowsAffected, err := res.RowsAffected()
 	if err != nil {
-		return "", fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
 	}
 	if rowsAffected == 0 {
-		return "No such ID", storage.ErrURLNotFound
+		return storage.ErrURLNotFound
 	}
 
-	return resURL, nil
+	return nil
+}
+
+func (s *Storage) UpdateLink(alias string, update storage.LinkUpdate, ownerID int64) (storage.Link, error) {
+	const op = "storage.sqlite.UpdateLink"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var id int64
+	err = tx.QueryRow("SELECT id FROM url WHERE alias = ? AND "+ownedBy, alias, ownerID, ownerID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.Link{}, storage.ErrURLNotFound
+	}
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("%s: select statement: %w", op, err)
+	}
+
+	if update.URL != "" {
+		if _, err := tx.Exec("UPDATE url SET url = ? WHERE id = ?", update.URL, id); err != nil {
+			return storage.Link{}, fmt.Errorf("%s: update url: %w", op, err)
+		}
+	}
+
+	if update.Targets != nil {
+		t := *update.Targets
+		_, err := tx.Exec("UPDATE url SET target_ios = ?, target_android = ?, target_desktop = ? WHERE id = ?",
+			t.IOS, t.Android, t.Desktop, id)
+		if err != nil {
+			return storage.Link{}, fmt.Errorf("%s: update targets: %w", op, err)
+		}
+	}
+
+	if update.StickyVariants != nil {
+		if _, err := tx.Exec("UPDATE url SET sticky_variants = ? WHERE id = ?", *update.StickyVariants, id); err != nil {
+			return storage.Link{}, fmt.Errorf("%s: update sticky variants: %w", op, err)
+		}
+	}
+
+	if update.Variants != nil {
+		if err := saveVariants(tx, id, storage.ResetClicks(update.Variants)); err != nil {
+			return storage.Link{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	link, err := scanLink(tx.QueryRow("SELECT "+linkColumns+" FROM url WHERE id = ?", id))
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("%s: select updated link: %w", op, err)
+	}
+
+	if update.StickyVariants != nil && *update.StickyVariants && len(link.Variants) == 0 {
+		return storage.Link{}, storage.ErrNoVariants
+	}
+
+	if err := tx.Commit(); err != nil {
+		return storage.Link{}, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return link, nil
 }
 
-func (s *Storage) DeleteURL(id int) (string, error) {
+func (s *Storage) DeleteURL(alias string, ownerID int64) error {
 	const op = "storage.sqlite.DeleteURL"
 
-	// Getting URL before Deleting
-	var resURL string
-	err := s.db.QueryRow("SELECT url FROM url WHERE id = ?", id).Scan(&resURL)
+	res, err := s.db.Exec("DELETE FROM url WHERE alias = ? AND "+ownedBy, alias, ownerID, ownerID)
+	if err != nil {
+		return fmt.Errorf("%s: delete statement: %w", op, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
+	if rowsAffected == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveClicks(clicks []storage.Click) error {
+	const op = "storage.sqlite.SaveClicks"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.Prepare(`
+	INSERT INTO clicks(url_id, clicked_at, referrer, user_agent, ip_hash, request_id)
+	SELECT id, ?, ?, ?, ?, ? FROM url WHERE alias = ?`)
+	if err != nil {
+		return fmt.Errorf("%s: prepare statement: %w", op, err)
+	}
+	defer stmt.Close()
+
+	variantStmt, err := tx.Prepare(`
+	UPDATE url_variants SET clicks = clicks + 1
+	WHERE url_id = (SELECT id FROM url WHERE alias = ?) AND position = ?`)
+	if err != nil {
+		return fmt.Errorf("%s: prepare statement: %w", op, err)
+	}
+	defer variantStmt.Close()
+
+	for _, click := range clicks {
+		_, err := stmt.Exec(click.ClickedAt.UTC(), click.Referrer, click.UserAgent, click.IPHash, click.RequestID, click.Alias)
+		if err != nil {
+			return fmt.Errorf("%s: insert click: %w", op, err)
+		}
+
+		if click.Variant == 0 {
+			continue
+		}
+		if _, err := variantStmt.Exec(click.Alias, click.Variant); err != nil {
+			return fmt.Errorf("%s: count variant click: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) CountClicks(alias string) (int, error) {
+	const op = "storage.sqlite.CountClicks"
+
+	var n int
+	err := s.db.QueryRow("SELECT (SELECT COUNT(*) FROM clicks WHERE url_id = url.id) FROM url WHERE alias = ?", alias).Scan(&n)
 	if errors.Is(err, sql.ErrNoRows) {
-		return "", storage.ErrURLNotFound
+		return 0, storage.ErrURLNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: select statement: %w", op, err)
+	}
+
+	return n, nil
+}
+
+func (s *Storage) ClickStats(alias string, since time.Time, ownerID int64) (storage.ClickStats, error) {
+	const op = "storage.sqlite.ClickStats"
+
+	var urlID int64
+	err := s.db.QueryRow("SELECT id FROM url WHERE alias = ? AND "+ownedBy, alias, ownerID, ownerID).Scan(&urlID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.ClickStats{}, storage.ErrURLNotFound
 	} else if err != nil {
-		return "", fmt.Errorf("%s: select statement: %w", op, err)
+		return storage.ClickStats{}, fmt.Errorf("%s: select url: %w", op, err)
+	}
+
+	stats := storage.ClickStats{Daily: []storage.DailyClicks{}}
+
+	err = s.db.QueryRow("SELECT COUNT(*), COUNT(DISTINCT ip_hash) FROM clicks WHERE url_id = ?", urlID).
+		Scan(&stats.Total, &stats.UniqueVisitors)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: select totals: %w", op, err)
 	}
 
-	// Deleting URL
-	res, err := s.db.Exec("DELETE FROM url WHERE id = ?", id)
+	rows, err := s.db.Query(`
+	SELECT date(clicked_at) AS day, COUNT(*) FROM clicks
+	WHERE url_id = ? AND clicked_at >= ?
+	GROUP BY day ORDER BY day`, urlID, since.UTC())
 	if err != nil {
-		return "", fmt.Errorf("%s: delete statement: %w", op, err)
+		return storage.ClickStats{}, fmt.Errorf("%s: select daily: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var day storage.DailyClicks
+		if err := rows.Scan(&day.Date, &day.Clicks); err != nil {
+			return storage.ClickStats{}, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+		stats.Daily = append(stats.Daily, day)
+	}
+	if err := rows.Err(); err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: iterate rows: %w", op, err)
+	}
+
+	var variants string
+	if err := s.db.QueryRow("SELECT "+variantsColumn+" FROM url WHERE id = ?", urlID).Scan(&variants); err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: select variants: %w", op, err)
 	}
+	if stats.Variants, err = parseVariants(variants); err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return stats, nil
+}
+
+func (s *Storage) SaveAPIKey(key storage.APIKey, hash string) (int64, error) {
+	const op = "storage.sqlite.SaveAPIKey"
+
+	var id int64
+	err := s.db.QueryRow(`
+	INSERT INTO api_keys(name, prefix, key_hash, scopes, created_at, user_id) VALUES(?, ?, ?, ?, ?, ?)
+	RETURNING id`, key.Name, key.Prefix, hash, strings.Join(key.Scopes, ","), key.CreatedAt.UTC(), nullID(key.UserID)).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetAPIKeyByHash(hash string) (storage.APIKey, error) {
+	const op = "storage.sqlite.GetAPIKeyByHash"
+
+	key, err := scanAPIKey(s.db.QueryRow("SELECT "+apiKeyColumns+" FROM api_keys WHERE key_hash = ?", hash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.APIKey{}, storage.ErrAPIKeyNotFound
+		}
+
+		return storage.APIKey{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return key, nil
+}
+
+func (s *Storage) ListAPIKeys() ([]storage.APIKey, error) {
+	const op = "storage.sqlite.ListAPIKeys"
+
+	rows, err := s.db.Query("SELECT " + apiKeyColumns + " FROM api_keys ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("%s: select statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	keys := []storage.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: iterate rows: %w", op, err)
+	}
+
+	return keys, nil
+}
 
-	rowsAffected, _ := res.RowsAffected()
+func (s *Storage) TouchAPIKey(id int64, usedAt time.Time) error {
+	const op = "storage.sqlite.TouchAPIKey"
+
+	if _, err := s.db.Exec("UPDATE api_keys SET last_used_at = ? WHERE id = ?", usedAt.UTC(), id); err != nil {
+		return fmt.Errorf("%s: update statement: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) RevokeAPIKey(id int64, revokedAt time.Time) error {
+	const op = "storage.sqlite.RevokeAPIKey"
+
+	res, err := s.db.Exec("UPDATE api_keys SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?", revokedAt.UTC(), id)
+	if err != nil {
+		return fmt.Errorf("%s: update statement: %w", op, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
+	}
 	if rowsAffected == 0 {
-		return "", storage.ErrURLNotFound
+		return storage.ErrAPIKeyNotFound
 	}
 
-	return resURL, nil
+	return nil
+}
+
+func (s *Storage) SaveUser(user storage.User) (int64, error) {
+	const op = "storage.sqlite.SaveUser"
+
+	var id int64
+	err := s.db.QueryRow(`
+	INSERT INTO users(name, password_hash, role, created_at) VALUES(?, ?, ?, ?)
+	RETURNING id`, user.Name, user.PasswordHash, user.Role, user.CreatedAt.UTC()).Scan(&id)
+	if err != nil {
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+		}
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetUserByName(name string) (storage.User, error) {
+	const op = "storage.sqlite.GetUserByName"
+
+	var user storage.User
+	err := s.db.QueryRow("SELECT "+userColumns+" FROM users WHERE name = ?", name).
+		Scan(&user.ID, &user.Name, &user.PasswordHash, &user.Role, &user.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.User{}, storage.ErrUserNotFound
+		}
+
+		return storage.User{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return user, nil
+}
+
+func (s *Storage) ListUsers() ([]storage.User, error) {
+	const op = "storage.sqlite.ListUsers"
+
+	rows, err := s.db.Query("SELECT " + userColumns + " FROM users ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("%s: select statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	users := []storage.User{}
+	for rows.Next() {
+		var user storage.User
+		if err := rows.Scan(&user.ID, &user.Name, &user.PasswordHash, &user.Role, &user.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: iterate rows: %w", op, err)
+	}
+
+	return users, nil
+}
+
+// ownedBy restricts a url query to the links of an owner. It takes the
+// owner id twice, storage.AnyOwner matches every link and storage.NoOwner none.
+const ownedBy = "(? = 0 OR owner_id = ?)"
+
+// linkColumns lists the url columns in the order scanLink expects them.
+const linkColumns = "id, alias, url, created_at, active_from, expires_at, redirect_code, owner_id, password_hash, max_clicks, used_clicks, " +
+	"target_ios, target_android, target_desktop, sticky_variants, " + variantsColumn
+
+// variantsColumn selects the variants of a url row as a JSON array in
+// position order.
+const variantsColumn = `(SELECT json_group_array(json_object('url', v.url, 'weight', v.weight, 'clicks', v.clicks))
+	FROM (SELECT * FROM url_variants WHERE url_id = url.id ORDER BY position) v)`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanLink(row scanner) (storage.Link, error) {
+	var link storage.Link
+	var ownerID sql.NullInt64
+	var targets storage.Targets
+	var variants string
+
+	err := row.Scan(
+		&link.ID, &link.Alias, &link.URL, &link.CreatedAt, &link.ActiveFrom, &link.ExpiresAt, &link.RedirectCode, &ownerID, &link.PasswordHash,
+		&link.MaxClicks, &link.UsedClicks, &targets.IOS, &targets.Android, &targets.Desktop, &link.StickyVariants, &variants,
+	)
+	if err != nil {
+		return link, err
+	}
+
+	link.OwnerID = ownerID.Int64
+	if !targets.IsZero() {
+		link.Targets = &targets
+	}
+	if link.Variants, err = parseVariants(variants); err != nil {
+		return link, err
+	}
+
+	return link, err
+}
+
+// apiKeyColumns lists the api_keys columns in the order scanAPIKey expects them.
+const apiKeyColumns = "id, name, prefix, scopes, created_at, last_used_at, revoked_at, user_id"
+
+func scanAPIKey(row scanner) (storage.APIKey, error) {
+	var key storage.APIKey
+	var scopes string
+	var userID sql.NullInt64
+
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, &scopes, &key.CreatedAt, &key.LastUsedAt, &key.RevokedAt, &userID)
+	key.UserID = userID.Int64
+	if scopes != "" {
+		key.Scopes = strings.Split(scopes, ",")
+	}
+
+	return key, err
+}
+
+// userColumns lists the users columns in the order they are scanned.
+const userColumns = "id, name, password_hash, role, created_at"
+
+// nullID stores a zero id as NULL.
+func nullID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
+
+// parseVariants decodes the JSON array selected by variantsColumn, an empty
+// array yields nil.
+func parseVariants(s string) ([]storage.Variant, error) {
+	var variants []storage.Variant
+	if err := json.Unmarshal([]byte(s), &variants); err != nil {
+		return nil, fmt.Errorf("decode variants: %w", err)
+	}
+	if len(variants) == 0 {
+		return nil, nil
+	}
+
+	return variants, nil
+}
+
+// targetValues returns the column values of targets, empty for nil.
+func targetValues(targets *storage.Targets) storage.Targets {
+	if targets == nil {
+		return storage.Targets{}
+	}
+
+	return *targets
+}
+
+// utc converts t to UTC, so stored timestamps compare correctly as text.
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	u := t.UTC()
+
+	return &u
 }
@@ -0,0 +1,697 @@
+package sqlite_test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/sqlite"
+)
+
+func newStorage(t *testing.T) *sqlite.Storage {
+	t.Helper()
+
+	storagePath := filepath.Join(t.TempDir(), "storage.db")
+
+	return newStorageAt(t, storagePath, 0)
+}
+
+func newStorageAt(t *testing.T, storagePath string, aliasMaxLength int) *sqlite.Storage {
+	t.Helper()
+
+	s, err := sqlite.New(storagePath, aliasMaxLength, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+
+	m, err := s.Migrator()
+	require.NoError(t, err)
+	_, err = m.Up()
+	require.NoError(t, err)
+
+	return s
+}
+
+func TestStorage_SaveGeneratedURL(t *testing.T) {
+	s := newStorage(t)
+
+	alias, _, err := s.SaveGeneratedURL("https://google.com", storage.LinkOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "0", alias)
+
+	alias, _, err = s.SaveGeneratedURL("https://go.dev", storage.LinkOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "1", alias)
+
+	got, err := s.GetURL("1")
+	require.NoError(t, err)
+	require.Equal(t, "https://go.dev", got)
+}
+
+func TestStorage_SaveGeneratedURL_Concurrent(t *testing.T) {
+	s := newStorage(t)
+
+	const n = 100
+
+	var wg sync.WaitGroup
+	aliases := make(chan string, n)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			alias, _, err := s.SaveGeneratedURL(fmt.Sprintf("https://example.com/%d", i), storage.LinkOptions{})
+			require.NoError(t, err)
+			aliases <- alias
+		}(i)
+	}
+
+	wg.Wait()
+	close(aliases)
+
+	seen := make(map[string]bool, n)
+	for alias := range aliases {
+		require.False(t, seen[alias], "alias %q handed out twice", alias)
+		seen[alias] = true
+	}
+	require.Len(t, seen, n)
+}
+
+func TestStorage_SaveGeneratedURL_Exhausted(t *testing.T) {
+	s := newStorageAt(t, filepath.Join(t.TempDir(), "storage.db"), 1)
+
+	for i := 0; i < 62; i++ {
+		_, _, err := s.SaveGeneratedURL(fmt.Sprintf("https://example.com/%d", i), storage.LinkOptions{})
+		require.NoError(t, err)
+	}
+
+	_, _, err := s.SaveGeneratedURL("https://example.com/overflow", storage.LinkOptions{})
+	require.ErrorIs(t, err, storage.ErrAliasSpaceExhausted)
+}
+
+func TestStorage_ListURLs(t *testing.T) {
+	s := newStorage(t)
+
+	for i := 0; i < 3; i++ {
+		_, _, err := s.SaveGeneratedURL(fmt.Sprintf("https://example.com/%d", i), storage.LinkOptions{})
+		require.NoError(t, err)
+	}
+
+	links, err := s.ListURLs(2, 1, storage.AnyOwner)
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+	require.Equal(t, "1", links[0].Alias)
+	require.Equal(t, "https://example.com/2", links[1].URL)
+}
+
+func TestStorage_MigrateLegacyAliasValues(t *testing.T) {
+	storagePath := filepath.Join(t.TempDir(), "storage.db")
+
+	// Schema and generator state as left by the ad-hoc CREATE TABLE statements
+	// of the old per-length generator.
+	db, err := sql.Open("sqlite3", storagePath)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+	CREATE TABLE url(id INTEGER PRIMARY KEY, alias TEXT NOT NULL UNIQUE, url TEXT NOT NULL);
+	CREATE TABLE alias_value(id INTEGER PRIMARY KEY, value INT NOT NULL, name TEXT NOT NULL UNIQUE);
+	INSERT INTO alias_value(value, name)
+		VALUES(3, 'AliasLength'), (1, 'PointerOne'), (2, 'PointerTwo'), (3, 'PointerThree'), (0, 'PointerFour');
+	INSERT INTO url(url, alias) VALUES('https://google.com', '122');
+	`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	s := newStorageAt(t, storagePath, 0)
+
+	got, err := s.GetURL("122")
+	require.NoError(t, err)
+	require.Equal(t, "https://google.com", got)
+
+	alias, _, err := s.SaveGeneratedURL("https://go.dev", storage.LinkOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "123", alias)
+}
+
+func TestStorage_CRUD(t *testing.T) {
+	s := newStorage(t)
+
+	_, err := s.SaveURL("https://google.com", "google", storage.LinkOptions{})
+	require.NoError(t, err)
+
+	_, err = s.SaveURL("https://google.com", "google", storage.LinkOptions{})
+	require.ErrorIs(t, err, storage.ErrURLExists)
+
+	require.NoError(t, s.UpdateURL("google", "https://google.de", storage.AnyOwner))
+	require.ErrorIs(t, s.UpdateURL("missing", "https://google.de", storage.AnyOwner), storage.ErrURLNotFound)
+
+	link, err := s.GetLink("google")
+	require.NoError(t, err)
+	require.Equal(t, "https://google.de", link.URL)
+	require.WithinDuration(t, time.Now(), link.CreatedAt, time.Minute)
+
+	count, err := s.CountURLs(storage.AnyOwner)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	require.NoError(t, s.DeleteURL("google", storage.AnyOwner))
+	require.ErrorIs(t, s.DeleteURL("google", storage.AnyOwner), storage.ErrURLNotFound)
+
+	_, err = s.GetLink("google")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestStorage_SaveGeneratedURL_SkipsCustomAliases(t *testing.T) {
+	s := newStorage(t)
+
+	_, err := s.SaveURL("https://google.com", "0", storage.LinkOptions{})
+	require.NoError(t, err)
+
+	alias, _, err := s.SaveGeneratedURL("https://go.dev", storage.LinkOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "1", alias)
+
+	counter, err := s.AliasCounter()
+	require.NoError(t, err)
+	require.EqualValues(t, 2, counter)
+}
+
+func TestStorage_SaveGeneratedURL_SkipsReservedAliases(t *testing.T) {
+	s := newStorage(t)
+
+	// 145496 is the counter value of "api".
+	require.NoError(t, s.RaiseAliasCounter(145496))
+
+	alias, _, err := s.SaveGeneratedURL("https://go.dev", storage.LinkOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "apj", alias)
+}
+
+func TestStorage_SaveURLBatch(t *testing.T) {
+	s := newStorage(t)
+
+	results, err := s.SaveURLBatch([]storage.NewLink{
+		{URL: "https://google.com", Alias: "0"},
+		{URL: "https://go.dev"},
+		{URL: "https://google.de", Alias: "0"},
+		{URL: "https://pkg.go.dev", LinkOptions: storage.LinkOptions{RedirectCode: 301}},
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	require.Equal(t, "0", results[0].Alias)
+	require.NoError(t, results[0].Err)
+	require.Equal(t, "1", results[1].Alias)
+	require.NoError(t, results[1].Err)
+	require.Equal(t, "0", results[2].Alias)
+	require.ErrorIs(t, results[2].Err, storage.ErrURLExists)
+	require.Equal(t, "2", results[3].Alias)
+	require.NoError(t, results[3].Err)
+
+	link, err := s.GetLink("2")
+	require.NoError(t, err)
+	require.Equal(t, "https://pkg.go.dev", link.URL)
+	require.Equal(t, 301, link.RedirectCode)
+	require.Equal(t, results[3].ID, link.ID)
+
+	got, err := s.GetURL("0")
+	require.NoError(t, err)
+	require.Equal(t, "https://google.com", got)
+}
+
+func TestStorage_SaveURLBatch_Exhausted(t *testing.T) {
+	s := newStorageAt(t, filepath.Join(t.TempDir(), "storage.db"), 1)
+
+	for i := 0; i < 61; i++ {
+		_, _, err := s.SaveGeneratedURL(fmt.Sprintf("https://example.com/%d", i), storage.LinkOptions{})
+		require.NoError(t, err)
+	}
+
+	results, err := s.SaveURLBatch([]storage.NewLink{
+		{URL: "https://example.com/last"},
+		{URL: "https://example.com/overflow"},
+		{URL: "https://example.com/custom", Alias: "custom"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, results[0].Err)
+	require.ErrorIs(t, results[1].Err, storage.ErrAliasSpaceExhausted)
+	require.NoError(t, results[2].Err)
+
+	counter, err := s.AliasCounter()
+	require.NoError(t, err)
+	require.EqualValues(t, 62, counter)
+}
+
+func TestStorage_ImportLinks(t *testing.T) {
+	s := newStorage(t)
+
+	_, err := s.SaveURL("https://google.com", "taken", storage.LinkOptions{})
+	require.NoError(t, err)
+
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	links := []storage.Link{
+		{Alias: "taken", URL: "https://google.de", CreatedAt: createdAt, LinkOptions: storage.LinkOptions{PasswordHash: "$2a$10$hash"}},
+		{Alias: "fresh", URL: "https://go.dev", CreatedAt: createdAt, LinkOptions: storage.LinkOptions{RedirectCode: 301, OwnerID: 99}},
+	}
+
+	_, err = s.ImportLinks(append(links, storage.Link{Alias: "other", URL: "https://example.com"}), storage.ConflictFail)
+	require.ErrorIs(t, err, storage.ErrURLExists)
+	_, err = s.GetLink("other")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	stats, err := s.ImportLinks(links, storage.ConflictSkip)
+	require.NoError(t, err)
+	require.Equal(t, storage.ImportStats{Created: 1, Skipped: 1}, stats)
+
+	got, err := s.GetURL("taken")
+	require.NoError(t, err)
+	require.Equal(t, "https://google.com", got)
+
+	link, err := s.GetLink("fresh")
+	require.NoError(t, err)
+	require.Equal(t, "https://go.dev", link.URL)
+	require.True(t, createdAt.Equal(link.CreatedAt))
+	require.Equal(t, 301, link.RedirectCode)
+	require.Zero(t, link.OwnerID, "unknown owners are dropped")
+
+	stats, err = s.ImportLinks(links, storage.ConflictOverwrite)
+	require.NoError(t, err)
+	require.Equal(t, storage.ImportStats{Overwritten: 2}, stats)
+
+	link, err = s.GetLink("taken")
+	require.NoError(t, err)
+	require.Equal(t, "https://google.de", link.URL)
+	require.True(t, createdAt.Equal(link.CreatedAt))
+	require.Equal(t, "$2a$10$hash", link.PasswordHash)
+
+	require.NoError(t, s.RaiseAliasCounter(5))
+	require.NoError(t, s.RaiseAliasCounter(2))
+
+	counter, err := s.AliasCounter()
+	require.NoError(t, err)
+	require.EqualValues(t, 5, counter)
+}
+
+func TestStorage_Clicks(t *testing.T) {
+	s := newStorage(t)
+
+	_, err := s.SaveURL("https://google.com", "google", storage.LinkOptions{})
+	require.NoError(t, err)
+
+	day := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	err = s.SaveClicks([]storage.Click{
+		{Alias: "google", ClickedAt: day.AddDate(0, 0, -10), IPHash: "a"},
+		{Alias: "google", ClickedAt: day, IPHash: "a"},
+		{Alias: "google", ClickedAt: day.Add(time.Hour), IPHash: "b"},
+		{Alias: "google", ClickedAt: day.AddDate(0, 0, 1), IPHash: "c"},
+		{Alias: "missing", ClickedAt: day, IPHash: "d"},
+	})
+	require.NoError(t, err)
+
+	stats, err := s.ClickStats("google", day.AddDate(0, 0, -1), storage.AnyOwner)
+	require.NoError(t, err)
+	require.Equal(t, 4, stats.Total)
+	require.Equal(t, 3, stats.UniqueVisitors)
+	require.Equal(t, []storage.DailyClicks{
+		{Date: "2024-05-01", Clicks: 2},
+		{Date: "2024-05-02", Clicks: 1},
+	}, stats.Daily)
+
+	count, err := s.CountClicks("google")
+	require.NoError(t, err)
+	require.Equal(t, 4, count)
+
+	_, err = s.ClickStats("missing", day, storage.AnyOwner)
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+	_, err = s.CountClicks("missing")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	require.NoError(t, s.DeleteURL("google", storage.AnyOwner))
+	_, err = s.SaveURL("https://google.com", "google", storage.LinkOptions{})
+	require.NoError(t, err)
+
+	stats, err = s.ClickStats("google", day, storage.AnyOwner)
+	require.NoError(t, err)
+	require.Zero(t, stats.Total)
+	require.Empty(t, stats.Daily)
+
+	count, err = s.CountClicks("google")
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
+
+func TestStorage_LinkOptions(t *testing.T) {
+	s := newStorage(t)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	activeFrom := now.Add(time.Hour)
+	expiresAt := now.Add(2 * time.Hour)
+	expiredAt := now.Add(-time.Hour)
+
+	_, err := s.SaveURL("https://google.com", "live", storage.LinkOptions{
+		ActiveFrom:   &activeFrom,
+		ExpiresAt:    &expiresAt,
+		RedirectCode: http.StatusPermanentRedirect,
+		PasswordHash: "$2a$10$hash",
+		MaxClicks:    3,
+	})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://google.com", "expired", storage.LinkOptions{ExpiresAt: &expiredAt})
+	require.NoError(t, err)
+
+	link, err := s.GetLink("live")
+	require.NoError(t, err)
+	require.True(t, activeFrom.Equal(*link.ActiveFrom))
+	require.True(t, expiresAt.Equal(*link.ExpiresAt))
+	require.Equal(t, http.StatusPermanentRedirect, link.RedirectCode)
+	require.Equal(t, "$2a$10$hash", link.PasswordHash)
+	require.True(t, link.Protected())
+	require.EqualValues(t, 3, link.MaxClicks)
+	require.EqualValues(t, 3, link.ClicksLeft())
+	require.True(t, link.Pending(now))
+	require.False(t, link.Expired(now))
+
+	deleted, err := s.DeleteExpiredURLs(now)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, deleted)
+
+	_, err = s.GetLink("expired")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+	_, err = s.GetLink("live")
+	require.NoError(t, err)
+}
+
+func TestStorage_APIKeys(t *testing.T) {
+	s := newStorage(t)
+
+	hash := "secret-hash"
+	createdAt := time.Now().UTC().Truncate(time.Second)
+
+	id, err := s.SaveAPIKey(storage.APIKey{
+		Name:      "billing",
+		Prefix:    "usk_1234",
+		Scopes:    []string{"create", "read"},
+		CreatedAt: createdAt,
+	}, hash)
+	require.NoError(t, err)
+
+	key, err := s.GetAPIKeyByHash(hash)
+	require.NoError(t, err)
+	require.Equal(t, id, key.ID)
+	require.Equal(t, "billing", key.Name)
+	require.Equal(t, []string{"create", "read"}, key.Scopes)
+	require.True(t, createdAt.Equal(key.CreatedAt))
+	require.Nil(t, key.LastUsedAt)
+	require.Nil(t, key.RevokedAt)
+
+	_, err = s.GetAPIKeyByHash("unknown")
+	require.ErrorIs(t, err, storage.ErrAPIKeyNotFound)
+
+	usedAt := createdAt.Add(time.Minute)
+	require.NoError(t, s.TouchAPIKey(id, usedAt))
+
+	revokedAt := createdAt.Add(time.Hour)
+	require.NoError(t, s.RevokeAPIKey(id, revokedAt))
+	require.NoError(t, s.RevokeAPIKey(id, revokedAt.Add(time.Hour)))
+	require.ErrorIs(t, s.RevokeAPIKey(id+1000, revokedAt), storage.ErrAPIKeyNotFound)
+
+	key, err = s.GetAPIKeyByHash(hash)
+	require.NoError(t, err)
+	require.True(t, usedAt.Equal(*key.LastUsedAt))
+	require.True(t, revokedAt.Equal(*key.RevokedAt), "revoking again keeps the first time")
+
+	keys, err := s.ListAPIKeys()
+	require.NoError(t, err)
+	require.NotEmpty(t, keys)
+}
+
+func TestStorage_Ownership(t *testing.T) {
+	s := newStorage(t)
+
+	alice, err := s.SaveUser(storage.User{Name: "alice", PasswordHash: "hash", Role: "user", CreatedAt: time.Now()})
+	require.NoError(t, err)
+	bob, err := s.SaveUser(storage.User{Name: "bob", PasswordHash: "hash", Role: "user", CreatedAt: time.Now()})
+	require.NoError(t, err)
+
+	_, err = s.SaveUser(storage.User{Name: "alice", PasswordHash: "hash", Role: "user", CreatedAt: time.Now()})
+	require.ErrorIs(t, err, storage.ErrUserExists)
+
+	user, err := s.GetUserByName("alice")
+	require.NoError(t, err)
+	require.Equal(t, alice, user.ID)
+	require.Equal(t, "hash", user.PasswordHash)
+
+	_, err = s.GetUserByName("carol")
+	require.ErrorIs(t, err, storage.ErrUserNotFound)
+
+	_, err = s.SaveURL("https://google.com", "google", storage.LinkOptions{OwnerID: alice})
+	require.NoError(t, err)
+
+	link, err := s.GetLink("google")
+	require.NoError(t, err)
+	require.Equal(t, alice, link.OwnerID)
+
+	links, err := s.ListURLs(10, 0, alice)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	links, err = s.ListURLs(10, 0, bob)
+	require.NoError(t, err)
+	require.Empty(t, links)
+
+	count, err := s.CountURLs(bob)
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	_, err = s.ClickStats("google", time.Now(), bob)
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+	require.ErrorIs(t, s.UpdateURL("google", "https://google.de", bob), storage.ErrURLNotFound)
+	require.ErrorIs(t, s.DeleteURL("google", bob), storage.ErrURLNotFound)
+
+	links, err = s.ListURLs(10, 0, storage.NoOwner)
+	require.NoError(t, err)
+	require.Empty(t, links)
+	require.ErrorIs(t, s.UpdateURL("google", "https://google.de", storage.NoOwner), storage.ErrURLNotFound)
+	require.ErrorIs(t, s.DeleteURL("google", storage.NoOwner), storage.ErrURLNotFound)
+
+	require.NoError(t, s.UpdateURL("google", "https://google.de", alice))
+	require.NoError(t, s.DeleteURL("google", storage.AnyOwner))
+
+	keyID, err := s.SaveAPIKey(storage.APIKey{Name: "ci", Prefix: "usk_1", Scopes: []string{"read"}, CreatedAt: time.Now(), UserID: bob}, "key-hash")
+	require.NoError(t, err)
+	key, err := s.GetAPIKeyByHash("key-hash")
+	require.NoError(t, err)
+	require.Equal(t, keyID, key.ID)
+	require.Equal(t, bob, key.UserID)
+
+	users, err := s.ListUsers()
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(users), 2)
+}
+
+func TestStorage_UseClick(t *testing.T) {
+	s := newStorage(t)
+
+	limited, unlimited := "limited", "unlimited"
+
+	_, err := s.SaveURL("https://google.com", limited, storage.LinkOptions{MaxClicks: 5})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://google.com", unlimited, storage.LinkOptions{})
+	require.NoError(t, err)
+
+	left, err := s.UseClick(limited)
+	require.NoError(t, err)
+	require.EqualValues(t, 4, left)
+
+	// More redirects than clicks left race for them.
+	const n = 20
+
+	var wg sync.WaitGroup
+	var used, exhausted atomic.Int64
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := s.UseClick(limited)
+			if errors.Is(err, storage.ErrClicksExhausted) {
+				exhausted.Add(1)
+				return
+			}
+			require.NoError(t, err)
+			used.Add(1)
+		}()
+	}
+
+	wg.Wait()
+	require.EqualValues(t, 4, used.Load())
+	require.EqualValues(t, n-4, exhausted.Load())
+
+	link, err := s.GetLink(limited)
+	require.NoError(t, err)
+	require.EqualValues(t, 5, link.UsedClicks)
+	require.True(t, link.UsedUp())
+	require.Zero(t, link.ClicksLeft())
+
+	left, err = s.UseClick(unlimited)
+	require.NoError(t, err)
+	require.Zero(t, left)
+
+	link, err = s.GetLink(unlimited)
+	require.NoError(t, err)
+	require.Zero(t, link.UsedClicks)
+	require.False(t, link.UsedUp())
+
+	_, err = s.UseClick("missing")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestStorage_UpdateLink(t *testing.T) {
+	s := newStorage(t)
+
+	owner, err := s.SaveUser(storage.User{Name: "alice", PasswordHash: "hash", Role: "user", CreatedAt: time.Now()})
+	require.NoError(t, err)
+
+	alias, plain := "targeted", "plain"
+	targets := &storage.Targets{
+		IOS:     "https://apps.apple.com/app/id1",
+		Android: "https://play.google.com/store/apps/details?id=app",
+	}
+
+	_, err = s.SaveURL("https://google.com", alias, storage.LinkOptions{OwnerID: owner, Targets: targets})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://google.com", plain, storage.LinkOptions{})
+	require.NoError(t, err)
+
+	link, err := s.GetLink(alias)
+	require.NoError(t, err)
+	require.Equal(t, targets, link.Targets)
+
+	link, err = s.GetLink(plain)
+	require.NoError(t, err)
+	require.Nil(t, link.Targets)
+
+	desktop := &storage.Targets{Desktop: "https://example.com/download"}
+	updated, err := s.UpdateLink(alias, storage.LinkUpdate{Targets: desktop}, owner)
+	require.NoError(t, err)
+	require.Equal(t, desktop, updated.Targets)
+	require.Equal(t, "https://google.com", updated.URL)
+
+	link, err = s.GetLink(alias)
+	require.NoError(t, err)
+	require.Equal(t, updated, link)
+
+	// Only links with variants can be sticky, a rejected update changes nothing.
+	sticky := true
+	_, err = s.UpdateLink(alias, storage.LinkUpdate{URL: "https://google.de", StickyVariants: &sticky}, owner)
+	require.ErrorIs(t, err, storage.ErrNoVariants)
+
+	link, err = s.GetLink(alias)
+	require.NoError(t, err)
+	require.Equal(t, "https://google.com", link.URL)
+	require.False(t, link.StickyVariants)
+
+	// Fields left out of the update stay as they are.
+	updated, err = s.UpdateLink(alias, storage.LinkUpdate{URL: "https://google.de"}, owner)
+	require.NoError(t, err)
+	require.Equal(t, "https://google.de", updated.URL)
+	require.Equal(t, desktop, updated.Targets)
+
+	_, err = s.UpdateLink(alias, storage.LinkUpdate{URL: "https://evil.com"}, owner+1)
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+	_, err = s.UpdateLink("missing", storage.LinkUpdate{URL: "https://evil.com"}, storage.AnyOwner)
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	_, err = s.UpdateLink(alias, storage.LinkUpdate{Targets: &storage.Targets{}}, storage.AnyOwner)
+	require.NoError(t, err)
+
+	link, err = s.GetLink(alias)
+	require.NoError(t, err)
+	require.Nil(t, link.Targets)
+}
+
+func TestStorage_Variants(t *testing.T) {
+	s := newStorage(t)
+
+	owner, err := s.SaveUser(storage.User{Name: "alice", PasswordHash: "hash", Role: "user", CreatedAt: time.Now()})
+	require.NoError(t, err)
+
+	alias, copied := "rotating", "copied"
+	variants := []storage.Variant{
+		{URL: "https://example.com/a", Weight: 3},
+		{URL: "https://example.com/b", Weight: 1},
+	}
+
+	_, err = s.SaveURL("https://example.com/a", alias, storage.LinkOptions{OwnerID: owner, Variants: variants, StickyVariants: true})
+	require.NoError(t, err)
+
+	link, err := s.GetLink(alias)
+	require.NoError(t, err)
+	require.Equal(t, variants, link.Variants)
+	require.True(t, link.StickyVariants)
+
+	now := time.Now()
+	require.NoError(t, s.SaveClicks([]storage.Click{
+		{Alias: alias, ClickedAt: now, Variant: 2},
+		{Alias: alias, ClickedAt: now, Variant: 2},
+		{Alias: alias, ClickedAt: now, Variant: 1},
+		{Alias: alias, ClickedAt: now},
+	}))
+
+	stats, err := s.ClickStats(alias, now.Add(-time.Hour), owner)
+	require.NoError(t, err)
+	require.Equal(t, 4, stats.Total)
+	require.Equal(t, []storage.Variant{
+		{URL: "https://example.com/a", Weight: 3, Clicks: 1},
+		{URL: "https://example.com/b", Weight: 1, Clicks: 2},
+	}, stats.Variants)
+
+	link, err = s.GetLink(alias)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, link.Variants[1].Clicks)
+
+	// Imports keep the counts.
+	link.Alias = copied
+	_, err = s.ImportLinks([]storage.Link{link}, storage.ConflictFail)
+	require.NoError(t, err)
+	imported, err := s.GetLink(copied)
+	require.NoError(t, err)
+	require.Equal(t, link.Variants, imported.Variants)
+
+	_, err = s.UpdateLink(alias, storage.LinkUpdate{Variants: []storage.Variant{}}, owner+1)
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	replaced := []storage.Variant{{URL: "https://example.com/c", Weight: 1, Clicks: 7}}
+	sticky := false
+	_, err = s.UpdateLink(alias, storage.LinkUpdate{Variants: replaced, StickyVariants: &sticky}, owner)
+	require.NoError(t, err)
+
+	link, err = s.GetLink(alias)
+	require.NoError(t, err)
+	require.Equal(t, []storage.Variant{{URL: "https://example.com/c", Weight: 1}}, link.Variants)
+	require.False(t, link.StickyVariants)
+
+	// Variants cannot be removed from a link that is made sticky.
+	sticky = true
+	_, err = s.UpdateLink(alias, storage.LinkUpdate{Variants: []storage.Variant{}, StickyVariants: &sticky}, owner)
+	require.ErrorIs(t, err, storage.ErrNoVariants)
+
+	_, err = s.UpdateLink(alias, storage.LinkUpdate{Variants: []storage.Variant{}}, storage.AnyOwner)
+	require.NoError(t, err)
+
+	link, err = s.GetLink(alias)
+	require.NoError(t, err)
+	require.Nil(t, link.Variants)
+
+	stats, err = s.ClickStats(alias, now.Add(-time.Hour), owner)
+	require.NoError(t, err)
+	require.Nil(t, stats.Variants)
+}
@@ -1,8 +1,294 @@
 package storage
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
-	ErrURLNotFound = errors.New("url not found")
-	ErrURLExists   = errors.New("url exists")
+	ErrURLNotFound         = errors.New("url not found")
+	ErrURLExists           = errors.New("url exists")
+	ErrAliasSpaceExhausted = errors.New("alias space exhausted")
+	ErrAPIKeyNotFound      = errors.New("api key not found")
+	ErrUserNotFound        = errors.New("user not found")
+	ErrUserExists          = errors.New("user exists")
+	ErrClicksExhausted     = errors.New("clicks exhausted")
+	ErrNoVariants          = errors.New("link has no variants")
+)
+
+// AnyOwner passed as ownerID disables the ownership check, it is used for admins.
+const AnyOwner int64 = 0
+
+// NoOwner passed as ownerID matches no link, it is used for credentials that
+// are neither admins nor tied to a user.
+const NoOwner int64 = -1
+
+// LinkOptions are the optional settings chosen when a link is saved.
+type LinkOptions struct {
+	// ActiveFrom is when the link starts redirecting, nil means right away.
+	ActiveFrom *time.Time `json:"active_from,omitempty"`
+	// ExpiresAt is when the link stops redirecting, nil means never.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// RedirectCode is the HTTP status used to redirect, 0 means the server default.
+	RedirectCode int `json:"redirect_code,omitempty"`
+	// OwnerID is the user who created the link, zero means no owner.
+	OwnerID int64 `json:"owner_id,omitempty"`
+	// PasswordHash is the bcrypt hash of the password guarding the link,
+	// empty means the link is not protected.
+	PasswordHash string `json:"-"`
+	// MaxClicks is the number of redirects the link serves, 0 means no limit.
+	MaxClicks int64 `json:"max_clicks,omitempty"`
+	// Targets are alternate destinations per platform, nil means none.
+	Targets *Targets `json:"targets,omitempty"`
+	// Variants are the destinations the link rotates between by weight,
+	// none means it always redirects to its URL.
+	Variants []Variant `json:"variants,omitempty"`
+	// StickyVariants keeps a visitor on the variant they were sent to first.
+	StickyVariants bool `json:"sticky_variants,omitempty"`
+}
+
+// Variant is one of the destinations of a rotating link. Variants are
+// numbered from 1 in the order they were saved.
+type Variant struct {
+	URL string `json:"url"`
+	// Weight is the share of redirects the variant gets relative to the
+	// weights of the others.
+	Weight int `json:"weight"`
+	// Clicks counts the recorded redirects to the variant.
+	Clicks int64 `json:"clicks"`
+}
+
+// ResetClicks returns a copy of variants with no clicks counted, nil if
+// there are none.
+func ResetClicks(variants []Variant) []Variant {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	reset := make([]Variant, len(variants))
+	for i, v := range variants {
+		reset[i] = Variant{URL: v.URL, Weight: v.Weight}
+	}
+
+	return reset
+}
+
+// LinkUpdate lists the changes made by UpdateLink, zero fields leave the
+// link as it is.
+type LinkUpdate struct {
+	// URL replaces the destination.
+	URL string
+	// Targets replace the platform targets, zero Targets remove them.
+	Targets *Targets
+	// Variants replace the variants, an empty non-nil slice removes them.
+	// The click counts of the new variants start at zero.
+	Variants       []Variant
+	StickyVariants *bool
+}
+
+// Targets are the destinations of a link for clients on one platform.
+// Platforms without a target get the link URL.
+type Targets struct {
+	IOS     string `json:"ios,omitempty"`
+	Android string `json:"android,omitempty"`
+	Desktop string `json:"desktop,omitempty"`
+}
+
+// IsZero reports whether no platform has a target.
+func (t *Targets) IsZero() bool {
+	return t == nil || *t == Targets{}
+}
+
+// Link is a saved URL together with its alias.
+type Link struct {
+	ID        int64     `json:"-"`
+	Alias     string    `json:"alias"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+	LinkOptions
+	// UsedClicks counts the redirects served of a link with MaxClicks.
+	UsedClicks int64 `json:"used_clicks,omitempty"`
+}
+
+// Pending reports whether the link is not active yet at t.
+func (l Link) Pending(t time.Time) bool {
+	return l.ActiveFrom != nil && t.Before(*l.ActiveFrom)
+}
+
+// Expired reports whether the link has expired at t.
+func (l Link) Expired(t time.Time) bool {
+	return l.ExpiresAt != nil && !t.Before(*l.ExpiresAt)
+}
+
+// Protected reports whether the link asks for a password before redirecting.
+func (l Link) Protected() bool {
+	return l.PasswordHash != ""
+}
+
+// UsedUp reports whether the link has served all of its MaxClicks redirects.
+func (l Link) UsedUp() bool {
+	return l.MaxClicks > 0 && l.UsedClicks >= l.MaxClicks
+}
+
+// ClicksLeft returns how many redirects the link still serves, it is only
+// meaningful for links with MaxClicks.
+func (l Link) ClicksLeft() int64 {
+	return max(l.MaxClicks-l.UsedClicks, 0)
+}
+
+// OwnedBy reports whether the link belongs to ownerID, every link belongs to
+// AnyOwner and none to NoOwner.
+func (l Link) OwnedBy(ownerID int64) bool {
+	return ownerID == AnyOwner || l.OwnerID == ownerID
+}
+
+// NewLink is one entry of a batch save.
+type NewLink struct {
+	URL string
+	// Alias is the custom alias, empty means one is generated.
+	Alias string
+	LinkOptions
+}
+
+// BatchResult is the outcome of saving one NewLink. Err is ErrURLExists or
+// ErrAliasSpaceExhausted when that link was not saved.
+type BatchResult struct {
+	Alias string
+	ID    int64
+	Err   error
+}
+
+// ConflictPolicy decides what ImportLinks does with a link whose alias is taken.
+type ConflictPolicy string
+
+const (
+	ConflictSkip      ConflictPolicy = "skip"
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	ConflictFail      ConflictPolicy = "fail"
 )
+
+// ImportStats counts what an import did with its links.
+type ImportStats struct {
+	Created     int `json:"created"`
+	Overwritten int `json:"overwritten"`
+	Skipped     int `json:"skipped"`
+}
+
+// Click is one redirect served for an alias.
+type Click struct {
+	Alias     string
+	ClickedAt time.Time
+	Referrer  string
+	UserAgent string
+	// IPHash is a salted hash of the client IP, the raw address is never stored.
+	IPHash    string
+	RequestID string
+	// Variant is the number of the variant the click was sent to, 0 for
+	// links without variants.
+	Variant int
+}
+
+// ClickStats summarizes the clicks of one alias.
+type ClickStats struct {
+	Total          int           `json:"total"`
+	UniqueVisitors int           `json:"unique_visitors"`
+	Daily          []DailyClicks `json:"daily"`
+	// Variants are the variants of a rotating link with their click counts.
+	Variants []Variant `json:"variants,omitempty"`
+}
+
+// DailyClicks is the number of clicks on one UTC day.
+type DailyClicks struct {
+	Date   string `json:"date"`
+	Clicks int    `json:"clicks"`
+}
+
+// APIKey is a credential for the API. The key itself is only stored as a hash,
+// Prefix is kept to tell keys apart.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	// UserID is the user the key acts for, zero means the key is not tied to a user.
+	UserID int64 `json:"user_id,omitempty"`
+}
+
+// User is an account that owns links. Only the hash of the password is kept.
+type User struct {
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	Role         string    `json:"role"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Store is implemented by every storage backend.
+type Store interface {
+	// SaveURL saves urlToSave under the given alias.
+	SaveURL(urlToSave string, alias string, opts LinkOptions) (int64, error)
+	// SaveGeneratedURL reserves the next free alias and saves urlToSave under it.
+	SaveGeneratedURL(urlToSave string, opts LinkOptions) (string, int64, error)
+	// SaveURLBatch saves links in one transaction and returns their results
+	// in the same order. A link that cannot be saved is reported in its
+	// result and does not stop the others.
+	SaveURLBatch(links []NewLink) ([]BatchResult, error)
+	// ImportLinks saves links in one transaction, keeping their aliases and
+	// creation times. Links whose alias is taken are handled by onConflict,
+	// ConflictFail rolls back all of them with ErrURLExists. Owners that do
+	// not exist are dropped.
+	ImportLinks(links []Link, onConflict ConflictPolicy) (ImportStats, error)
+	// RaiseAliasCounter sets the alias counter to counter unless it is
+	// already higher.
+	RaiseAliasCounter(counter int64) error
+	// AliasCounter returns how many generated aliases have been used up,
+	// including the ones skipped because a custom link had taken them.
+	AliasCounter() (int64, error)
+	GetURL(alias string) (string, error)
+	GetLink(alias string) (Link, error)
+	// UseClick counts one redirect of a link with MaxClicks and returns how
+	// many are left. Once none are left it fails with ErrClicksExhausted,
+	// concurrent calls never count more than MaxClicks. Links without a
+	// limit are not counted and report zero left.
+	UseClick(alias string) (int64, error)
+	// UpdateURL and DeleteURL only touch the link if it belongs to ownerID,
+	// a link of another owner is reported as ErrURLNotFound.
+	UpdateURL(alias string, newURL string, ownerID int64) error
+	DeleteURL(alias string, ownerID int64) error
+	// UpdateLink applies all changes of update to a link the way UpdateURL
+	// replaces its URL, in one transaction, and returns the updated link.
+	// Making a link sticky that is left without variants fails with
+	// ErrNoVariants and changes nothing.
+	UpdateLink(alias string, update LinkUpdate, ownerID int64) (Link, error)
+	// ListURLs returns at most limit links of ownerID in creation order,
+	// skipping the first offset.
+	ListURLs(limit, offset int, ownerID int64) ([]Link, error)
+	CountURLs(ownerID int64) (int, error)
+	// DeleteExpiredURLs deletes the links that expired before the given time.
+	DeleteExpiredURLs(before time.Time) (int64, error)
+	// SaveClicks stores clicks in one batch, clicks on unknown aliases are dropped.
+	SaveClicks(clicks []Click) error
+	// ClickStats returns all-time totals and the daily counts since the given
+	// time for a link of ownerID.
+	ClickStats(alias string, since time.Time, ownerID int64) (ClickStats, error)
+	// CountClicks returns the all-time number of clicks on a link.
+	CountClicks(alias string) (int, error)
+	// SaveAPIKey saves key together with the hash of its secret.
+	SaveAPIKey(key APIKey, hash string) (int64, error)
+	GetAPIKeyByHash(hash string) (APIKey, error)
+	ListAPIKeys() ([]APIKey, error)
+	// TouchAPIKey records that the key was used at usedAt.
+	TouchAPIKey(id int64, usedAt time.Time) error
+	// RevokeAPIKey revokes the key, revoking it again keeps the first time.
+	RevokeAPIKey(id int64, revokedAt time.Time) error
+	// SaveUser saves a new user, a taken name is reported as ErrUserExists.
+	SaveUser(user User) (int64, error)
+	GetUserByName(name string) (User, error)
+	ListUsers() ([]User, error)
+	// Ping checks that the storage is reachable.
+	Ping() error
+	Close() error
+}
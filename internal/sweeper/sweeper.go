@@ -0,0 +1,80 @@
+package sweeper
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"url-shortener/internal/lib/logger/sl"
+)
+
+// ExpiredDeleter is an interface for purging expired links.
+type ExpiredDeleter interface {
+	DeleteExpiredURLs(before time.Time) (int64, error)
+}
+
+// Sweeper periodically deletes links that expired longer than the retention
+// period ago. Until then expired links keep answering 410 Gone.
+type Sweeper struct {
+	log       *slog.Logger
+	deleter   ExpiredDeleter
+	interval  time.Duration
+	retention time.Duration
+
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// New starts a sweeper that runs every interval.
+func New(log *slog.Logger, deleter ExpiredDeleter, interval, retention time.Duration) *Sweeper {
+	s := &Sweeper{
+		log:       log.With(slog.String("op", "sweeper.Sweeper")),
+		deleter:   deleter,
+		interval:  interval,
+		retention: retention,
+		done:      make(chan struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.run()
+
+	return s
+}
+
+// Close stops the sweeper and waits for a running sweep to finish.
+func (s *Sweeper) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+	})
+}
+
+func (s *Sweeper) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.sweep()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *Sweeper) sweep() {
+	deleted, err := s.deleter.DeleteExpiredURLs(time.Now().Add(-s.retention))
+	if err != nil {
+		s.log.Error("failed to delete expired urls", sl.Err(err))
+		return
+	}
+
+	if deleted > 0 {
+		s.log.Info("deleted expired urls", slog.Int64("count", deleted))
+	}
+}
@@ -0,0 +1,51 @@
+package sweeper_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/sweeper"
+)
+
+type deleterStub struct {
+	mu     sync.Mutex
+	before []time.Time
+}
+
+func (d *deleterStub) DeleteExpiredURLs(before time.Time) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.before = append(d.before, before)
+
+	return 1, nil
+}
+
+func TestSweeper(t *testing.T) {
+	deleter := &deleterStub{}
+	s := sweeper.New(slogdiscard.NewDiscardLogger(), deleter, 10*time.Millisecond, time.Hour)
+
+	require.Eventually(t, func() bool {
+		deleter.mu.Lock()
+		defer deleter.mu.Unlock()
+
+		return len(deleter.before) >= 2
+	}, time.Second, 5*time.Millisecond)
+
+	s.Close()
+
+	deleter.mu.Lock()
+	calls := len(deleter.before)
+	require.WithinDuration(t, time.Now().Add(-time.Hour), deleter.before[0], time.Second)
+	deleter.mu.Unlock()
+
+	time.Sleep(30 * time.Millisecond)
+
+	deleter.mu.Lock()
+	defer deleter.mu.Unlock()
+	require.Equal(t, calls, len(deleter.before), "no sweeps after Close")
+}